@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"path"
@@ -50,8 +51,8 @@ func main() {
 		}
 	}
 
-	// 静态资源注册
-	e.Static(uriAccessPrefix, storageDirectory)
+	// 静态资源注册(不暴露临时文件, 拒绝路径穿越)
+	e.GET(uriAccessPrefix+"/*", echo.WrapHandler(http.StripPrefix(uriAccessPrefix, http.FileServer(s.FileSystem()))))
 
 	v1 := e.Group(
 		"/v1",
@@ -147,6 +148,9 @@ func main() {
 		if err := e.Shutdown(c1); err != nil {
 			fmt.Println("shutdown http server:", err.Error())
 		}
+		if err := s.Close(c1); err != nil {
+			fmt.Println("close storage:", err.Error())
+		}
 	}
 
 }
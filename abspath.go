@@ -0,0 +1,85 @@
+package fileupload
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// AbsolutePath 根据存储结果重新计算文件的绝对路径, 适用于返回给客户端前清空了 PathAbs/PathRlt 的存储结果
+// 依次使用 PathAbs, PathRlt, PathUri(去除资源访问前缀); 仅适用于使用 Storage 默认存储目录及资源访问前缀存储的文件
+// 存储结果可能来自客户端, 因此 PathAbs 不在存储目录下时返回错误
+func (s *Storage) AbsolutePath(result *FileStorageResult) (string, error) {
+	if result == nil {
+		return "", fmt.Errorf("absolute path: empty result")
+	}
+	if result.PathAbs != "" {
+		return s.containedPath(result.PathAbs)
+	}
+	storageDirectory := s.storageDirectory
+	if result.PathRlt != "" {
+		return joinAbsolute(storageDirectory, result.PathRlt)
+	}
+	if result.PathUri != "" {
+		name, err := s.trimURIPrefix(result.PathUri)
+		if err != nil {
+			return "", err
+		}
+		return joinAbsolute(storageDirectory, name)
+	}
+	return "", fmt.Errorf("absolute path: empty path")
+}
+
+// containedPath 检查路径 name 位于存储目录下(不能为存储目录本身), 返回其绝对路径
+func (s *Storage) containedPath(name string) (string, error) {
+	storageDirectory := s.storageDirectory
+	if storageDirectory == "" {
+		storageDirectory = "."
+	}
+	root, err := filepath.Abs(storageDirectory)
+	if err != nil {
+		return "", err
+	}
+	if name, err = filepath.Abs(name); err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, name)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("absolute path: %q is outside the storage directory", name)
+	}
+	return name, nil
+}
+
+// joinAbsolute 拼接目录及相对路径(统一使用 / 分隔)并返回绝对路径, 相对路径不能超出目录
+func joinAbsolute(directory string, name string) (string, error) {
+	clean := path.Clean("/" + name)
+	if clean == "/" {
+		return "", fmt.Errorf("absolute path: invalid path %q", name)
+	}
+	return filepath.Abs(filepath.Join(directory, filepath.FromSlash(clean[1:])))
+}
+
+// trimURIPrefix 去除资源访问路径的访问前缀, 返回相对于存储目录的路径
+func (s *Storage) trimURIPrefix(uri string) (string, error) {
+	prefix := s.uriAccessPrefix
+	if p, err := url.Parse(prefix); err == nil && p.Scheme != "" && p.Host != "" {
+		u, err := url.Parse(uri)
+		if err != nil {
+			return "", err
+		}
+		if u.Scheme != p.Scheme || u.Host != p.Host {
+			return "", fmt.Errorf("absolute path: uri %q does not match prefix %q", uri, prefix)
+		}
+		prefix, uri = p.Path, u.Path
+	}
+	prefix, uri = strings.Trim(prefix, "/"), strings.TrimPrefix(uri, "/")
+	if prefix == "" {
+		return uri, nil
+	}
+	if !strings.HasPrefix(uri, prefix+"/") {
+		return "", fmt.Errorf("absolute path: uri %q does not match prefix %q", uri, s.uriAccessPrefix)
+	}
+	return strings.TrimPrefix(uri, prefix+"/"), nil
+}
@@ -0,0 +1,70 @@
+package fileupload
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAbsolutePath(t *testing.T) {
+	for _, prefix := range []string{"", "/static", "https://cdn.example.com/static"} {
+		s, dir := newTestStorage(t, WithUriAccessPrefix(prefix))
+		result, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: "docs/2024"}, openBytes([]byte("x")), "a.txt", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		original := result.PathAbs
+		result.PathAbs = ""
+		if got, err := s.AbsolutePath(result); err != nil || got != original {
+			t.Errorf("prefix %q from PathRlt = %s, %v, want %s", prefix, got, err, original)
+		}
+		result.PathRlt = ""
+		if got, err := s.AbsolutePath(result); err != nil || got != original {
+			t.Errorf("prefix %q from PathUri = %s, %v, want %s", prefix, got, err, original)
+		}
+
+		for _, forged := range []*FileStorageResult{
+			{PathAbs: filepath.Join(dir, "..", "secret.txt")},
+			{PathAbs: dir},
+		} {
+			if got, err := s.AbsolutePath(forged); err == nil {
+				t.Errorf("forged %s resolved to %s", forged.PathAbs, got)
+			}
+		}
+		if got, err := s.AbsolutePath(&FileStorageResult{PathRlt: "../../secret.txt"}); err == nil && filepath.Dir(got) != dir {
+			t.Errorf("PathRlt traversal resolved to %s outside %s", got, dir)
+		}
+	}
+}
+
+func TestPathRltAbsoluteAndRelativeDirectory(t *testing.T) {
+	base := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir(base); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	for _, directory := range []string{
+		filepath.Join(base, "uploads"),
+		filepath.Join(base, "uploads") + string(filepath.Separator),
+		"uploads",
+		"./uploads/",
+		"other/../uploads",
+	} {
+		s := NewStorage(WithStorageDirectory(directory))
+		result, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: "/docs/2024/"}, openBytes([]byte("x")), "a.txt", 1)
+		if err != nil {
+			t.Fatalf("directory %q: %v", directory, err)
+		}
+		if want := "docs/2024/" + result.Name; result.PathRlt != want {
+			t.Errorf("directory %q: PathRlt = %s, want %s", directory, result.PathRlt, want)
+		}
+		if want := filepath.Join(base, "uploads", "docs", "2024", result.Name); result.PathAbs != want {
+			t.Errorf("directory %q: PathAbs = %s, want %s", directory, result.PathAbs, want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package fileupload
+
+import "fmt"
+
+// WithAtomicBatch 批量存储(MultipartCopy, Base64Copy, HTTP, Echo)中任一文件失败时删除本次已新写入的文件, 并返回空的存储结果
+// 未开启时返回出错前已存储成功的结果, 已写入的文件保留
+// 开启时存储成功的通知(WithOnStored, Observer, Logger)在批量存储全部成功后发出; 回滚时改为以 ErrBatchRolledBack 通知失败
+func WithAtomicBatch(atomic bool) Opts {
+	return func(s *Storage) { s.atomicBatch = atomic }
+}
+
+// rollback 批量存储失败时删除本次新写入的文件, 复用已存储相同内容的结果不做处理; b 为批量存储的状态, 记录等待通知的存储结果
+func (s *Storage) rollback(b *batch, succeeded []*FileStorageResult, err error) []*FileStorageResult {
+	if err == nil || !s.atomicBatch {
+		for _, v := range b.takePending() {
+			s.notify(v, nil)
+		}
+		return succeeded
+	}
+	rolledBack := fmt.Errorf("%w: %w", ErrBatchRolledBack, err)
+	for _, v := range b.takePending() {
+		s.notify(v, rolledBack)
+	}
+	for _, v := range succeeded {
+		for _, link := range v.Links {
+			s.undo(link)
+		}
+		s.undo(v)
+	}
+	// 文件全部删除后由后至前删除本次新建的目录, 非空目录删除失败时保留
+	fs := s.filesystem()
+	for i := len(succeeded) - 1; i >= 0; i-- {
+		for _, dir := range succeeded[i].dirs {
+			_ = fs.Remove(dir)
+		}
+	}
+	return nil
+}
+
+// undo 删除存储结果本次新写入的文件, 复用已存储相同内容(或已存在的链接)的结果仅归还引用数
+func (s *Storage) undo(result *FileStorageResult) {
+	if result.written != nil {
+		_ = s.deleteFile(result.written.pathAbs, result.written.hash)
+	} else if result.referenced != "" {
+		_, _ = s.refCounter.Decr(result.referenced)
+	}
+}
+
+// writtenFile 存储过程中新写入的文件
+type writtenFile struct {
+	pathAbs string // 文件存储绝对路径
+	hash    string // 文件哈希值
+}
@@ -0,0 +1,66 @@
+package fileupload
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAtomicBatch(t *testing.T) {
+	files := []testFile{
+		{field: "files", filename: "a.txt", content: "a"},
+		{field: "files", filename: "b.txt", content: "b"},
+		{field: "files", filename: "c.txt", content: "too large"},
+		{field: "files", filename: "d.txt", content: "d"},
+	}
+	observer := &countingObserver{}
+	var storedEvents int
+	var errs []error
+	s, dir := newTestStorage(t, WithMaxFileSize(1), WithAtomicBatch(true), WithObserver(observer),
+		WithOnStored(func(*FileStorageResult) { storedEvents++ }),
+		WithOnError(func(err error, origin string) { errs = append(errs, err) }))
+	results, err := s.MultipartCopy(&FileStorage{StorageSubDirectory: "batch"}, formFileHeaders(t, files...)...)
+	if !errors.Is(err, ErrFileTooLarge) {
+		t.Fatalf("err = %v, want ErrFileTooLarge", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("results = %v, want none", results)
+	}
+	if names := listTree(t, dir); len(names) != 0 {
+		t.Errorf("storage tree = %v, want zero files", names)
+	}
+	// 回滚的文件不通知存储成功, 改为通知失败
+	if storedEvents != 0 || observer.stored != 0 || observer.bytes != 0 {
+		t.Errorf("stored events = %d, observer stored %d bytes %d after rollback", storedEvents, observer.stored, observer.bytes)
+	}
+	if observer.failed != observer.started || len(errs) != observer.started {
+		t.Errorf("started %d, failed %d, errors %v", observer.started, observer.failed, errs)
+	}
+	rolledBack := 0
+	for _, e := range errs {
+		if errors.Is(e, ErrBatchRolledBack) {
+			rolledBack++
+		}
+	}
+	if rolledBack != 2 {
+		t.Errorf("errors = %v, want a.txt and b.txt rolled back", errs)
+	}
+
+	// 全部成功时批量存储完成后通知
+	storedEvents, errs = 0, nil
+	results, err = s.MultipartCopy(&FileStorage{}, formFileHeaders(t, files[0], files[1])...)
+	if err != nil || len(results) != 2 {
+		t.Fatalf("atomic success = %d results, %v", len(results), err)
+	}
+	if storedEvents != 2 || observer.stored != 2 || len(errs) != 0 {
+		t.Errorf("stored events = %d, observer stored %d, errors %v, want 2 stored", storedEvents, observer.stored, errs)
+	}
+
+	s, _ = newTestStorage(t, WithMaxFileSize(1))
+	results, err = s.MultipartCopy(&FileStorage{}, formFileHeaders(t, files...)...)
+	if err == nil || len(results) != 2 {
+		t.Fatalf("non-atomic = %d results, %v, want the two stored before the failure", len(results), err)
+	}
+	for _, v := range results {
+		readFile(t, v.PathAbs)
+	}
+}
@@ -0,0 +1,94 @@
+package fileupload
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"regexp"
+)
+
+// base64Source 流式解码的base64内容, 仅支持回到起始位置, 不在内存中保存解码后的完整内容
+type base64Source struct {
+	encoding *base64.Encoding // 编码方式
+	encoded  []byte           // base64编码的内容(不含填充)
+	limit    int64            // 解码后的大小上限
+	r        io.Reader        // 当前解码位置
+}
+
+// newBase64Source 兼容标准及URL安全(- _)的base64编码, 填充可省略
+func newBase64Source(encoded []byte, limit int64) *base64Source {
+	encoded = bytes.TrimRight(bytes.TrimSpace(encoded), "=")
+	encoding := base64.RawStdEncoding
+	if bytes.ContainsAny(encoded, "-_") {
+		encoding = base64.RawURLEncoding
+	}
+	return &base64Source{encoding: encoding, encoded: encoded, limit: limit}
+}
+
+func (b *base64Source) Read(p []byte) (int, error) {
+	if b.r == nil {
+		b.r = &sizeLimitReader{
+			r:     base64.NewDecoder(b.encoding, bytes.NewReader(b.encoded)),
+			limit: b.limit,
+		}
+	}
+	return b.r.Read(p)
+}
+
+func (b *base64Source) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekStart {
+		return 0, errors.New("base64 source: only seeking to the start is supported")
+	}
+	b.r = nil
+	return 0, nil
+}
+
+// Base64Item 带原始文件名的base64内容, 如 {"filename": "a.pdf", "data": "data:application/pdf;base64,..."}
+type Base64Item struct {
+	Filename string `json:"filename"` // 原始文件名
+	Data     string `json:"data"`     // base64(data URI)内容
+}
+
+// Base64CopyNamed 带原始文件名的base64存储, 存储结果的 OriginName 为原始文件名, 文件后缀取自原始文件名(没有后缀时根据声明的类型确定)
+// 不限于图片类型; 原始文件名为空时与 Base64Copy 一致仅允许图片类型, 配置 WithConcurrency 时并发处理且结果保持原有顺序
+func (s *Storage) Base64CopyNamed(param *FileStorage, items []Base64Item) (succeeded []*FileStorageResult, err error) {
+	defer func() { succeeded = flattenLinks(succeeded) }()
+	b := s.pendingBatch()
+	defer func() { succeeded = s.rollback(b, succeeded, err) }()
+	files, names := make([][]byte, len(items)), make([]string, len(items))
+	for i, item := range items {
+		if item.Data != "" {
+			files[i], names[i] = []byte(item.Data), item.Filename
+		}
+	}
+	return s.base64CopyAll(param, b, files, names)
+}
+
+// Base64Parser 解析base64(data URI)内容的头部, 返回声明的内容类型(如 "image/jpeg")及头部的长度(即编码内容的起始位置), 无法解析时 ok 为 false
+type Base64Parser func(content []byte) (mediaType string, n int, ok bool)
+
+// WithBase64Parser 自定义base64(data URI)头部解析, 用于非标准格式的头部; 默认使用 DefaultBase64Parser
+func WithBase64Parser(parser Base64Parser) Opts {
+	return func(s *Storage) { s.base64Parser = parser }
+}
+
+// regexpBase64 base64(data URI)头部, 类型与 ;base64 之间可包含参数(如 ;charset=utf-8), 仅匹配头部以避免扫描整个编码内容
+var regexpBase64 = regexp.MustCompile(`^data:\s*([\w.+-]+/[\w.+-]+)(?:\s*;\s*[\w.+-]+=(?:"[^"]*"|[^;,"]*))*\s*;\s*(?i:base64),`)
+
+// DefaultBase64Parser 解析如 data:image/jpeg;base64, 及 data:image/jpeg;charset=utf-8;base64, 的头部
+func DefaultBase64Parser(content []byte) (mediaType string, n int, ok bool) {
+	matched := regexpBase64.FindSubmatch(content)
+	if len(matched) < 2 {
+		return "", 0, false
+	}
+	return string(matched[1]), len(matched[0]), true
+}
+
+// parseBase64 解析base64(data URI)内容的头部
+func (s *Storage) parseBase64(content []byte) (string, int, bool) {
+	if s.base64Parser != nil {
+		return s.base64Parser(content)
+	}
+	return DefaultBase64Parser(content)
+}
@@ -0,0 +1,145 @@
+package fileupload
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestBase64OversizedFailsFast(t *testing.T) {
+	fs := &writeRecordingFS{FileSystem: osFileSystem{}}
+	s, _ := newTestStorage(t, WithMaxFileSize(1024), WithFileSystem(fs))
+	content := dataURI("image/png", bytes.Repeat([]byte{0xAB}, 1<<20))
+	_, err := s.Base64Copy(&FileStorage{}, [][]byte{content})
+	if !errors.Is(err, ErrFileTooLarge) {
+		t.Fatalf("err = %v, want ErrFileTooLarge", err)
+	}
+	if len(fs.writes) != 0 {
+		t.Errorf("oversized payload touched the file system: %v", fs.writes)
+	}
+}
+
+func TestBase64SizeLimitWhileDecoding(t *testing.T) {
+	src := newBase64Source(dataURI("image/png", bytes.Repeat([]byte{0xAB}, 4096))[len("data:image/png;base64,"):], 1024)
+	n, err := io.Copy(io.Discard, src)
+	if n >= 4096 {
+		t.Errorf("decoded %d bytes, want to stop near the 1024 byte limit", n)
+	}
+	if !errors.Is(err, ErrFileTooLarge) {
+		t.Fatalf("err = %v, want ErrFileTooLarge", err)
+	}
+}
+
+func TestBase64SmallImage(t *testing.T) {
+	s, _ := newTestStorage(t, WithMaxFileSize(1<<20))
+	content := pngBytes(t, 4, 4)
+	results, err := s.Base64Copy(&FileStorage{}, [][]byte{dataURI("image/png", content)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].FileExt != ".png" || readFile(t, results[0].PathAbs) != string(content) {
+		t.Errorf("results = %+v, want the decoded png", results)
+	}
+}
+
+func TestBase64URLSafeAndUnpadded(t *testing.T) {
+	s, _ := newTestStorage(t)
+	// 0xFB 0xFF 在标准编码中为 "+/", 在URL安全编码中为 "-_"
+	content := append(pngBytes(t, 4, 4), 0xFB, 0xFF, 0xFB)
+	if len(content)%3 == 0 {
+		content = append(content, 0xFF)
+	}
+	for name, encoded := range map[string]string{
+		"url-safe":          base64.URLEncoding.EncodeToString(content),
+		"url-safe unpadded": base64.RawURLEncoding.EncodeToString(content),
+		"unpadded":          base64.RawStdEncoding.EncodeToString(content),
+	} {
+		if !strings.ContainsAny(encoded, "-_") && strings.HasPrefix(name, "url") {
+			t.Fatalf("%s: %q has no url-safe characters", name, encoded)
+		}
+		results, err := s.Base64Copy(&FileStorage{}, [][]byte{[]byte("data:image/png;base64," + encoded)})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got := readFile(t, results[0].PathAbs); got != string(content) {
+			t.Errorf("%s: decoded %d bytes, want %d", name, len(got), len(content))
+		}
+	}
+}
+
+func TestBase64CopyNamed(t *testing.T) {
+	s, _ := newTestStorage(t)
+	results, err := s.Base64CopyNamed(&FileStorage{}, []Base64Item{
+		{Filename: "Quarterly Report.pdf", Data: string(dataURI("application/pdf", []byte("%PDF-1.4 report")))},
+		{Filename: "avatar", Data: string(dataURI("image/png", pngBytes(t, 2, 2)))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if results[0].OriginName != "Quarterly Report.pdf" || results[0].FileExt != ".pdf" {
+		t.Errorf("result 0 = %s %s, want the origin name and .pdf", results[0].OriginName, results[0].FileExt)
+	}
+	// 原始文件名没有后缀时根据声明的类型确定
+	if results[1].OriginName != "avatar" || results[1].FileExt != ".png" {
+		t.Errorf("result 1 = %s %s, want avatar and .png", results[1].OriginName, results[1].FileExt)
+	}
+	if got := readFile(t, results[0].PathAbs); got != "%PDF-1.4 report" {
+		t.Errorf("content = %q", got)
+	}
+}
+
+func TestDefaultBase64Parser(t *testing.T) {
+	for _, tc := range []struct {
+		header    string
+		mediaType string
+	}{
+		{"data:image/jpeg;base64,", "image/jpeg"},
+		{"data:image/jpeg;charset=utf-8;base64,", "image/jpeg"},
+		{`data:image/svg+xml; charset="utf-8" ; name=logo.svg;BASE64,`, "image/svg+xml"},
+	} {
+		mediaType, n, ok := DefaultBase64Parser([]byte(tc.header + "QUJD"))
+		if !ok || mediaType != tc.mediaType || n != len(tc.header) {
+			t.Errorf("%q = %s %d %v, want %s %d", tc.header, mediaType, n, ok, tc.mediaType, len(tc.header))
+		}
+	}
+	for _, header := range []string{"image/jpeg;base64,", "data:image/jpeg,", "data:;base64,"} {
+		if _, _, ok := DefaultBase64Parser([]byte(header + "QUJD")); ok {
+			t.Errorf("%q parsed", header)
+		}
+	}
+
+	s, _ := newTestStorage(t)
+	content := pngBytes(t, 2, 2)
+	results, err := s.Base64Copy(&FileStorage{}, [][]byte{[]byte("data:image/png;charset=utf-8;base64," + base64.StdEncoding.EncodeToString(content))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if readFile(t, results[0].PathAbs) != string(content) {
+		t.Error("decoded content differs")
+	}
+}
+
+func TestBase64Parser(t *testing.T) {
+	// 非标准格式: "png:" 后为编码内容
+	parser := func(content []byte) (string, int, bool) {
+		if !bytes.HasPrefix(content, []byte("png:")) {
+			return "", 0, false
+		}
+		return "image/png", len("png:"), true
+	}
+	s, _ := newTestStorage(t, WithBase64Parser(parser))
+	content := pngBytes(t, 2, 2)
+	results, err := s.Base64Copy(&FileStorage{}, [][]byte{[]byte("png:" + base64.StdEncoding.EncodeToString(content))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].FileExt != ".png" || readFile(t, results[0].PathAbs) != string(content) {
+		t.Errorf("result = %s, want the decoded png", results[0].Name)
+	}
+}
@@ -0,0 +1,127 @@
+package fileupload
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// RequestDedup 单次请求内重复内容(如同一文件同时出现在单文件及多文件字段)的处理方式, 重复内容均只写入一次
+type RequestDedup int
+
+const (
+	RequestDedupShare RequestDedup = iota // 保留每个上传文件的存储结果, 重复内容的结果指向同一文件
+	RequestDedupMerge                     // 重复内容只保留第一个存储结果
+)
+
+// WithRequestDedup 单次请求内重复内容的处理方式, 默认 RequestDedupShare
+func WithRequestDedup(dedup RequestDedup) Opts {
+	return func(s *Storage) { s.requestDedup = dedup }
+}
+
+// batch 单次请求内的存储状态
+type batch struct {
+	ctx        context.Context // 请求上下文
+	mutex      sync.Mutex
+	stored     map[string]batchEntry           // 文件存储绝对路径 -> 首个存储结果, 为nil时不复用本次请求已写入的文件
+	duplicates map[*FileStorageResult]struct{} // 重复内容的存储结果
+	pending    []*FileStorageResult            // 开启 WithAtomicBatch 时等待批量存储完成后再通知的存储结果
+}
+
+func newBatch(ctx context.Context) *batch {
+	return &batch{
+		ctx:        ctx,
+		stored:     make(map[string]batchEntry),
+		duplicates: make(map[*FileStorageResult]struct{}),
+	}
+}
+
+// batchEntry 本次请求已写入的文件
+type batchEntry struct {
+	result *FileStorageResult // 首个存储结果
+	hash   string             // 文件哈希值(存储结果的哈希值可能被 WithResultFields 清空)
+}
+
+// reuse 本次请求已写入相同路径的文件时复用其存储结果
+// 相同路径已写入不同内容(文件名不由哈希值决定时)返回 ErrNameCollision, 避免后写入的内容覆盖先写入的内容
+func (b *batch) reuse(result *FileStorageResult, pathAbs string) (bool, error) {
+	if b == nil || b.stored == nil {
+		return false, nil
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	first, ok := b.stored[pathAbs]
+	if !ok {
+		return false, nil
+	}
+	if first.hash != result.Hash {
+		return false, fmt.Errorf("%w: %s", ErrNameCollision, result.Name)
+	}
+	copyStored(result, first.result)
+	b.duplicates[result] = struct{}{}
+	return true, nil
+}
+
+// store 记录本次请求已写入的文件
+func (b *batch) store(result *FileStorageResult, pathAbs string) {
+	if b == nil || b.stored == nil {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if _, ok := b.stored[pathAbs]; !ok {
+		b.stored[pathAbs] = batchEntry{result: result, hash: result.Hash}
+	}
+}
+
+// duplicated 是否为重复内容的存储结果
+func (b *batch) duplicated(result *FileStorageResult) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	_, ok := b.duplicates[result]
+	return ok
+}
+
+// merge 移除重复内容的存储结果
+func (b *batch) merge(results []*FileStorageResult) []*FileStorageResult {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	merged := results[:0]
+	for _, v := range results {
+		if _, ok := b.duplicates[v]; !ok {
+			merged = append(merged, v)
+		}
+	}
+	return merged
+}
+
+// pendingBatch 不在请求内复用已写入文件的批量存储(如 MultipartCopy, Base64Copy)使用的状态, 仅在开启 WithAtomicBatch 时用于延迟通知
+func (s *Storage) pendingBatch() *batch {
+	if !s.atomicBatch {
+		return nil
+	}
+	return &batch{}
+}
+
+// postpone 记录存储成功的结果, 批量存储完成后再通知; 返回是否已延迟
+func (b *batch) postpone(result *FileStorageResult) bool {
+	if b == nil {
+		return false
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.pending = append(b.pending, result)
+	return true
+}
+
+// takePending 取出全部等待通知的存储结果
+func (b *batch) takePending() []*FileStorageResult {
+	if b == nil {
+		return nil
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	pending := b.pending
+	b.pending = nil
+	return pending
+}
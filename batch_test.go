@@ -0,0 +1,53 @@
+package fileupload
+
+import (
+	"sync"
+	"testing"
+)
+
+// countingFS 记录文件写入次数的本地磁盘文件系统
+type countingFS struct {
+	osFileSystem
+	mutex   sync.Mutex
+	renames int
+}
+
+func (fs *countingFS) Rename(oldPath string, newPath string) error {
+	fs.mutex.Lock()
+	fs.renames++
+	fs.mutex.Unlock()
+	return fs.osFileSystem.Rename(oldPath, newPath)
+}
+
+func TestRequestDedup(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		dedup   RequestDedup
+		results int
+	}{
+		{"share", RequestDedupShare, 2},
+		{"merge", RequestDedupMerge, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := &countingFS{}
+			s, _ := newTestStorage(t, WithFileSystem(fs), WithRequestDedup(tc.dedup))
+			r := multipartRequest(t, []testFile{
+				{field: "avatar", filename: "me.txt", content: "same content"},
+				{field: "files", filename: "copy.txt", content: "same content"},
+			})
+			results, err := s.HTTP(r, &FileStorage{}, &MultipartFileName{Single: "avatar", Multiple: "files"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fs.renames != 1 {
+				t.Errorf("%d physical writes, want 1", fs.renames)
+			}
+			if len(results) != tc.results {
+				t.Fatalf("got %d results, want %d", len(results), tc.results)
+			}
+			if tc.results == 2 && (results[0].PathAbs != results[1].PathAbs || results[0].Field != "avatar" || results[1].Field != "files") {
+				t.Errorf("results = %+v, %+v, want the same file for both fields", results[0], results[1])
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package fileupload
+
+import (
+	"strings"
+)
+
+// 资源分类
+const (
+	CategoryImage    = "image"
+	CategoryVideo    = "video"
+	CategoryAudio    = "audio"
+	CategoryDocument = "document"
+	CategoryArchive  = "archive"
+	CategoryOther    = "other"
+)
+
+// Categorizer 根据文件后缀(如 ".png")及内容类型(如 "image/png")确定资源分类
+type Categorizer func(ext string, mime string) string
+
+// WithCategorizer 自定义资源分类, 默认使用 DefaultCategorizer
+func WithCategorizer(categorizer Categorizer) Opts {
+	return func(s *Storage) { s.categorizer = categorizer }
+}
+
+// WithSeparateByCategory 以资源分类(如 image, archive)作为存储子目录的第一级目录, 如 image/{StorageSubDirectory}/...
+func WithSeparateByCategory(separate bool) Opts {
+	return func(s *Storage) { s.separateByCategory = separate }
+}
+
+var categoryExtensions = map[string]string{
+	".jpg": CategoryImage, ".jpeg": CategoryImage, ".png": CategoryImage, ".gif": CategoryImage, ".bmp": CategoryImage,
+	".webp": CategoryImage, ".svg": CategoryImage, ".ico": CategoryImage, ".tif": CategoryImage, ".tiff": CategoryImage,
+	".heic": CategoryImage, ".heif": CategoryImage, ".avif": CategoryImage,
+	".mp4": CategoryVideo, ".mov": CategoryVideo, ".avi": CategoryVideo, ".mkv": CategoryVideo, ".webm": CategoryVideo,
+	".flv": CategoryVideo, ".wmv": CategoryVideo, ".m4v": CategoryVideo,
+	".mp3": CategoryAudio, ".wav": CategoryAudio, ".flac": CategoryAudio, ".aac": CategoryAudio, ".ogg": CategoryAudio,
+	".m4a": CategoryAudio, ".wma": CategoryAudio,
+	".pdf": CategoryDocument, ".doc": CategoryDocument, ".docx": CategoryDocument, ".xls": CategoryDocument,
+	".xlsx": CategoryDocument, ".ppt": CategoryDocument, ".pptx": CategoryDocument, ".txt": CategoryDocument,
+	".csv": CategoryDocument, ".md": CategoryDocument, ".rtf": CategoryDocument, ".odt": CategoryDocument,
+	".ods": CategoryDocument, ".odp": CategoryDocument, ".json": CategoryDocument,
+	".zip": CategoryArchive, ".rar": CategoryArchive, ".7z": CategoryArchive, ".tar": CategoryArchive,
+	".gz": CategoryArchive, ".tgz": CategoryArchive, ".bz2": CategoryArchive, ".xz": CategoryArchive,
+}
+
+var categoryMimes = map[string]string{
+	"application/pdf":              CategoryDocument,
+	"application/msword":           CategoryDocument,
+	"application/rtf":              CategoryDocument,
+	"application/zip":              CategoryArchive,
+	"application/x-gzip":           CategoryArchive,
+	"application/gzip":             CategoryArchive,
+	"application/x-rar-compressed": CategoryArchive,
+	"application/x-7z-compressed":  CategoryArchive,
+	"application/x-tar":            CategoryArchive,
+}
+
+// DefaultCategorizer 默认资源分类, 优先根据文件后缀判断, 其次根据内容类型判断
+func DefaultCategorizer(ext string, mime string) string {
+	if category, ok := categoryExtensions[strings.ToLower(ext)]; ok {
+		return category
+	}
+	mime = strings.ToLower(mime)
+	if i := strings.IndexByte(mime, ';'); i >= 0 {
+		mime = mime[:i]
+	}
+	mime = strings.TrimSpace(mime)
+	if category, ok := categoryMimes[mime]; ok {
+		return category
+	}
+	switch {
+	case strings.HasPrefix(mime, "image/"):
+		return CategoryImage
+	case strings.HasPrefix(mime, "video/"):
+		return CategoryVideo
+	case strings.HasPrefix(mime, "audio/"):
+		return CategoryAudio
+	case strings.HasPrefix(mime, "text/"):
+		return CategoryDocument
+	}
+	return CategoryOther
+}
+
+// categorize 确定资源分类
+func (s *Storage) categorize(ext string, mime string) string {
+	if s.categorizer != nil {
+		return s.categorizer(ext, mime)
+	}
+	return DefaultCategorizer(ext, mime)
+}
@@ -0,0 +1,57 @@
+package fileupload
+
+import (
+	"path"
+	"testing"
+)
+
+func TestDefaultCategorizer(t *testing.T) {
+	for _, tc := range []struct {
+		ext, mime, want string
+	}{
+		{".PNG", "", CategoryImage},
+		{".mp4", "", CategoryVideo},
+		{".flac", "", CategoryAudio},
+		{".pdf", "", CategoryDocument},
+		{".zip", "", CategoryArchive},
+		{"", "image/x-custom", CategoryImage},
+		{"", "application/pdf; charset=binary", CategoryDocument},
+		{"", "text/plain; charset=utf-8", CategoryDocument},
+		{".bin", "application/octet-stream", CategoryOther},
+	} {
+		if got := DefaultCategorizer(tc.ext, tc.mime); got != tc.want {
+			t.Errorf("DefaultCategorizer(%q, %q) = %q, want %q", tc.ext, tc.mime, got, tc.want)
+		}
+	}
+}
+
+func TestCategoryResult(t *testing.T) {
+	s, _ := newTestStorage(t, WithCategorizer(func(ext string, mime string) string { return "custom" + ext }))
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("hello")), "a.txt", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Category != "custom.txt" {
+		t.Errorf("Category = %q, want custom.txt", result.Category)
+	}
+}
+
+func TestSeparateByCategory(t *testing.T) {
+	s, _ := newTestStorage(t, WithSeparateByCategory(true))
+	image := pngBytes(t, 2, 2)
+	photo, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: "uploads"}, openBytes(image), "photo.png", int64(len(image)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := zipBytes(t, testFile{filename: "a.txt", content: "a"})
+	bundle, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: "uploads"}, openBytes([]byte(archive)), "bundle.zip", int64(len(archive)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := path.Dir(photo.PathRlt); got != "image/uploads" {
+		t.Errorf("image stored in %s, want image/uploads", got)
+	}
+	if got := path.Dir(bundle.PathRlt); got != "archive/uploads" {
+		t.Errorf("zip stored in %s, want archive/uploads", got)
+	}
+}
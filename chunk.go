@@ -0,0 +1,238 @@
+package fileupload
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// chunkSuffix 分片文件后缀
+const chunkSuffix = ".chunk"
+
+const (
+	defaultMaxChunkSize = 64 << 20 // 单个分片的默认大小上限
+	defaultMaxChunks    = 10000    // 单次分片上传的默认分片数上限
+)
+
+// WithChunkDirectory 分片上传的分片暂存目录(位于存储的文件系统, 见 WithFileSystem), 默认为系统临时目录下的 fileupload-chunks
+func WithChunkDirectory(directory string) Opts {
+	return func(s *Storage) { s.chunkDirectory = directory }
+}
+
+// WithChunkLimits 单个分片的大小上限(字节)及单次分片上传的分片数上限(分片序号需小于该值), 超出时返回 ErrChunkLimitExceeded
+// 小于等于0时使用默认值: 64MB, 10000个; 拼接后的文件大小由 WithMaxFileSize 限制
+func WithChunkLimits(maxChunkSize int64, maxChunks int) Opts {
+	return func(s *Storage) {
+		s.maxChunkSize = maxChunkSize
+		s.maxChunks = maxChunks
+	}
+}
+
+// chunkLimits 单个分片的大小上限及分片数上限
+func (s *Storage) chunkLimits() (maxChunkSize int64, maxChunks int) {
+	maxChunkSize, maxChunks = s.maxChunkSize, s.maxChunks
+	if maxChunkSize <= 0 {
+		maxChunkSize = defaultMaxChunkSize
+	}
+	if maxChunks <= 0 {
+		maxChunks = defaultMaxChunks
+	}
+	return
+}
+
+// chunkPath 分片上传的暂存目录
+func (s *Storage) chunkPath(uploadID string) (string, error) {
+	if uploadID == "" || uploadID == "." || uploadID == ".." || strings.ContainsAny(uploadID, `/\`) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidUploadID, uploadID)
+	}
+	directory := s.chunkDirectory
+	if directory == "" {
+		directory = filepath.Join(os.TempDir(), "fileupload-chunks")
+	}
+	return filepath.Join(directory, uploadID), nil
+}
+
+// PutChunk 写入分片上传的第 index 个分片(从0开始), 分片可乱序到达, 重复到达的分片覆盖之前的内容
+// 分片大小或序号超出上限(见 WithChunkLimits)时返回 ErrChunkLimitExceeded
+func (s *Storage) PutChunk(uploadID string, index int, r io.Reader) (err error) {
+	maxChunkSize, maxChunks := s.chunkLimits()
+	if index < 0 {
+		err = fmt.Errorf("invalid chunk index %d", index)
+		return
+	}
+	if index >= maxChunks {
+		err = fmt.Errorf("%w: chunk index %d, limit %d chunks", ErrChunkLimitExceeded, index, maxChunks)
+		return
+	}
+	directory, err := s.chunkPath(uploadID)
+	if err != nil {
+		return
+	}
+	// 与 CompleteUpload, AbortUpload 互斥, 避免拼接或删除过程中写入分片
+	unlock := s.locker.lock("upload:" + directory)
+	defer unlock()
+
+	u := &upload{fs: s.filesystem(), buffers: &s.buffers, sync: s.sync}
+	defer func() {
+		if err != nil {
+			u.cleanup()
+		}
+	}()
+	if err = u.mkdirAll(directory); err != nil {
+		return
+	}
+	name := filepath.Join(directory, strconv.Itoa(index)+chunkSuffix)
+	// 多读取1字节以判断是否超出大小上限
+	tmp, size, err := u.writeTemp(name, func(w io.Writer) error {
+		_, err := u.buffers.copy(w, io.LimitReader(r, maxChunkSize+1))
+		return err
+	})
+	if err != nil {
+		return
+	}
+	if size > maxChunkSize {
+		err = fmt.Errorf("%w: chunk %d larger than %d bytes", ErrChunkLimitExceeded, index, maxChunkSize)
+		return
+	}
+	// 重复到达的分片覆盖之前的内容, 因此不记录为新建文件
+	err = u.commitTemp(tmp, name, true)
+	return
+}
+
+// CompleteUpload 按顺序拼接全部分片并通过常规流程存储, filename 为原始文件名; 成功后删除已暂存的分片
+// 分片序号不连续时返回 ErrChunkMissing, 已暂存的分片保留以便补传
+func (s *Storage) CompleteUpload(uploadID string, param *FileStorage, filename string) (result *FileStorageResult, err error) {
+	directory, err := s.chunkPath(uploadID)
+	if err != nil {
+		return
+	}
+	unlock := s.locker.lock("upload:" + directory)
+	defer unlock()
+
+	fs := s.filesystem()
+	indexes, err := chunkIndexes(fs, directory)
+	if err != nil {
+		return
+	}
+	if len(indexes) == 0 {
+		err = fmt.Errorf("%w: no chunks for upload %q", ErrChunkMissing, uploadID)
+		return
+	}
+	for i, index := range indexes {
+		if i != index {
+			err = fmt.Errorf("%w: chunk %d of upload %q", ErrChunkMissing, i, uploadID)
+			return
+		}
+	}
+
+	assembled, err := createTemp(fs, directory, "assembled.", tempFileSuffix, 0600)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = assembled.Close()
+		_ = fs.Remove(assembled.Name())
+	}()
+	for _, index := range indexes {
+		if err = s.appendFile(fs, assembled, filepath.Join(directory, strconv.Itoa(index)+chunkSuffix)); err != nil {
+			return
+		}
+	}
+
+	result = &FileStorageResult{
+		OriginName:    s.sanitizeFilename(filename),
+		RawOriginName: filename,
+	}
+	result.FileExt = path.Ext(result.OriginName)
+	s.started(param)
+	if err = s.store(&upload{
+		param:  param,
+		result: result,
+		src:    assembled,
+	}); err != nil {
+		return
+	}
+	_ = assembled.Close()
+	err = removeChunks(fs, directory)
+	return
+}
+
+// AbortUpload 放弃分片上传, 删除已暂存的分片
+func (s *Storage) AbortUpload(uploadID string) error {
+	directory, err := s.chunkPath(uploadID)
+	if err != nil {
+		return err
+	}
+	unlock := s.locker.lock("upload:" + directory)
+	defer unlock()
+	return removeChunks(s.filesystem(), directory)
+}
+
+// removeChunks 删除分片暂存目录及其中的文件, 目录不存在时不返回错误
+func removeChunks(fs FileSystem, directory string) error {
+	reader, ok := fs.(dirReader)
+	if !ok {
+		return fmt.Errorf("chunk: file system does not support reading directories")
+	}
+	entries, err := reader.ReadDir(directory)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, entry := range entries {
+		if err = fs.Remove(filepath.Join(directory, entry.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err = fs.Remove(directory); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// chunkIndexes 已暂存分片的序号(升序)
+func chunkIndexes(fs FileSystem, directory string) ([]int, error) {
+	reader, ok := fs.(dirReader)
+	if !ok {
+		return nil, fmt.Errorf("chunk: file system does not support reading directories")
+	}
+	entries, err := reader.ReadDir(directory)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	indexes := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, chunkSuffix) {
+			continue
+		}
+		index, err := strconv.Atoi(strings.TrimSuffix(name, chunkSuffix))
+		if err != nil || index < 0 {
+			continue
+		}
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	return indexes, nil
+}
+
+// appendFile 将文件 name 的内容追加写入 dst
+func (s *Storage) appendFile(fs FileSystem, dst io.Writer, name string) error {
+	src, err := fs.Open(name)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = src.Close() }()
+	_, err = s.buffers.copy(dst, src)
+	return err
+}
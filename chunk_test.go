@@ -0,0 +1,77 @@
+package fileupload
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChunkedUpload(t *testing.T) {
+	chunks := t.TempDir()
+	s, _ := newTestStorage(t, WithChunkDirectory(chunks))
+	put := func(index int, content string) {
+		t.Helper()
+		if err := s.PutChunk("video-1", index, strings.NewReader(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 乱序及重复到达, 重复的分片以后到达的内容为准
+	put(2, "third")
+	put(0, "stale")
+	put(0, "first-")
+	if _, err := s.CompleteUpload("video-1", &FileStorage{}, "movie.mp4"); !errors.Is(err, ErrChunkMissing) {
+		t.Fatalf("complete with a gap: err = %v, want ErrChunkMissing", err)
+	}
+	put(1, "second-")
+	result, err := s.CompleteUpload("video-1", &FileStorage{}, "movie.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, result.PathAbs); got != "first-second-third" {
+		t.Errorf("assembled content = %q", got)
+	}
+	hash, _, _ := s.HashReader(strings.NewReader("first-second-third"))
+	if result.Hash != hash || result.OriginName != "movie.mp4" || result.FileExt != ".mp4" {
+		t.Errorf("result = %+v, want the hash of the assembled content", result)
+	}
+	if _, err = os.Stat(filepath.Join(chunks, "video-1")); !os.IsNotExist(err) {
+		t.Errorf("chunks were not removed: %v", err)
+	}
+}
+
+func TestAbortUpload(t *testing.T) {
+	chunks := t.TempDir()
+	s, _ := newTestStorage(t, WithChunkDirectory(chunks))
+	if err := s.PutChunk("abort", 0, strings.NewReader("data")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AbortUpload("abort"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(chunks, "abort")); !os.IsNotExist(err) {
+		t.Errorf("chunks were not removed: %v", err)
+	}
+	if _, err := s.CompleteUpload("abort", &FileStorage{}, "a.txt"); !errors.Is(err, ErrChunkMissing) {
+		t.Errorf("complete after abort: err = %v, want ErrChunkMissing", err)
+	}
+}
+
+func TestChunkLimits(t *testing.T) {
+	s, _ := newTestStorage(t, WithChunkDirectory(t.TempDir()), WithChunkLimits(4, 2))
+	if err := s.PutChunk("limits", 0, strings.NewReader("12345")); !errors.Is(err, ErrChunkLimitExceeded) {
+		t.Errorf("oversized chunk: err = %v, want ErrChunkLimitExceeded", err)
+	}
+	if err := s.PutChunk("limits", 2, strings.NewReader("1")); !errors.Is(err, ErrChunkLimitExceeded) {
+		t.Errorf("chunk index over the limit: err = %v, want ErrChunkLimitExceeded", err)
+	}
+	if err := s.PutChunk("limits", 1, strings.NewReader("1234")); err != nil {
+		t.Errorf("chunk at the limit: %v", err)
+	}
+	for _, id := range []string{"", "..", "a/b", `a\b`} {
+		if err := s.PutChunk(id, 0, strings.NewReader("1")); !errors.Is(err, ErrInvalidUploadID) {
+			t.Errorf("upload id %q: err = %v, want ErrInvalidUploadID", id, err)
+		}
+	}
+}
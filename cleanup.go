@@ -0,0 +1,243 @@
+package fileupload
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// tempFileSuffix 临时文件后缀
+const tempFileSuffix = ".tmp"
+
+// mkdirer 支持创建单级目录的文件系统
+type mkdirer interface {
+	Mkdir(name string, perm os.FileMode) error
+}
+
+func (osFileSystem) Mkdir(name string, perm os.FileMode) error {
+	return os.Mkdir(name, perm)
+}
+
+// mkdirAll 创建目录并记录本次新建的各级目录, 目录已存在(如被并发创建)时不视为错误
+// 文件系统不支持创建单级目录时仅调用 MkdirAll, 不记录新建的目录
+func (u *upload) mkdirAll(directory string) (err error) {
+	fs, ok := u.fs.(mkdirer)
+	if !ok {
+		return u.fs.MkdirAll(directory, 0755)
+	}
+	var created []string
+	var mkdir func(dir string) error
+	mkdir = func(dir string) error {
+		err := fs.Mkdir(dir, 0755)
+		if os.IsNotExist(err) && filepath.Dir(dir) != dir {
+			if err = mkdir(filepath.Dir(dir)); err != nil {
+				return err
+			}
+			err = fs.Mkdir(dir, 0755)
+		}
+		if err == nil {
+			created = append(created, dir)
+			return nil
+		}
+		if os.IsExist(err) {
+			return nil
+		}
+		return err
+	}
+	err = mkdir(directory)
+	// 由深至浅记录, 与清理顺序一致
+	for i := len(created) - 1; i >= 0; i-- {
+		u.dirs = append(u.dirs, created[i])
+	}
+	return
+}
+
+// writeFile 将内容写入临时文件后重命名至目标位置, existed 表示目标位置写入前已存在文件
+func (u *upload) writeFile(name string, content io.Reader, existed bool) (err error) {
+	tmp, _, err := u.writeTemp(name, func(w io.Writer) error {
+		_, err := u.buffers.copy(w, content)
+		return err
+	})
+	if err != nil {
+		return
+	}
+	err = u.commitTemp(tmp, name, existed)
+	return
+}
+
+// writeTemp 在临时目录(未设置时为目标位置所在目录)创建临时文件并通过 write 写入内容, 返回临时文件路径及写入的字节数
+func (u *upload) writeTemp(name string, write func(w io.Writer) error) (tmpName string, size int64, err error) {
+	directory := u.tempDirectory
+	if directory == "" {
+		directory = filepath.Dir(name)
+	} else if err = u.fs.MkdirAll(directory, 0755); err != nil {
+		return
+	}
+	tmp, err := createTemp(u.fs, directory, tempPrefix(name), tempFileSuffix, storedFileMode)
+	if os.IsNotExist(err) {
+		// 目录可能已被并发失败的存储过程清理, 重新创建后重试
+		if err = u.fs.MkdirAll(directory, 0755); err != nil {
+			return
+		}
+		tmp, err = createTemp(u.fs, directory, tempPrefix(name), tempFileSuffix, storedFileMode)
+	}
+	if err != nil {
+		return
+	}
+	tmpName = tmp.Name()
+	u.created = append(u.created, tmpName)
+	counter := &countWriter{w: tmp}
+	if err = write(counter); err != nil {
+		_ = tmp.Close()
+		return
+	}
+	if err = u.syncFile(tmp); err != nil {
+		_ = tmp.Close()
+		return
+	}
+	if err = tmp.Close(); err != nil {
+		return
+	}
+	if err = u.chmodTemp(tmpName); err != nil {
+		return
+	}
+	size = counter.n
+	return
+}
+
+// commitTemp 将临时文件重命名至目标位置, existed 表示目标位置写入前已存在文件
+func (u *upload) commitTemp(tmpName string, name string, existed bool) (err error) {
+	err = u.fs.Rename(tmpName, name)
+	if os.IsNotExist(err) && u.tempDirectory != "" {
+		// 目标目录可能已被并发失败的存储过程清理, 重新创建后重试
+		if err = u.fs.MkdirAll(filepath.Dir(name), 0755); err == nil {
+			err = u.fs.Rename(tmpName, name)
+		}
+	}
+	if err != nil {
+		// 临时目录与目标位置不在同一设备时无法重命名, 复制至目标位置所在目录后再重命名
+		if u.tempDirectory == "" {
+			return
+		}
+		if err = u.moveTemp(tmpName, name); err != nil {
+			return
+		}
+	}
+	u.forget(tmpName)
+	if !existed {
+		u.created = append(u.created, name)
+	}
+	err = u.commitFile(name)
+	return
+}
+
+// moveTemp 将临时文件复制至目标位置所在目录的临时文件, 重命名至目标位置后删除原临时文件
+func (u *upload) moveTemp(tmpName string, name string) (err error) {
+	src, err := u.fs.Open(tmpName)
+	if err != nil {
+		return
+	}
+	defer func() { _ = src.Close() }()
+	dst, err := createTemp(u.fs, filepath.Dir(name), tempPrefix(name), tempFileSuffix, storedFileMode)
+	if err != nil {
+		return
+	}
+	u.created = append(u.created, dst.Name())
+	if _, err = u.buffers.copy(dst, src); err != nil {
+		_ = dst.Close()
+		return
+	}
+	if err = u.syncFile(dst); err != nil {
+		_ = dst.Close()
+		return
+	}
+	if err = dst.Close(); err != nil {
+		return
+	}
+	if err = u.chmodTemp(dst.Name()); err != nil {
+		return
+	}
+	if err = u.fs.Rename(dst.Name(), name); err != nil {
+		return
+	}
+	u.forget(dst.Name())
+	_ = src.Close()
+	_ = u.fs.Remove(tmpName)
+	return
+}
+
+// tempPrefix 目标位置 name 对应的临时文件名前缀, 保证临时文件名不超出文件名的最大长度
+func tempPrefix(name string) string {
+	return truncateFilename(filepath.Base(name), maxFilenameLength-len(".0123456789abcdef"+tempFileSuffix)) + "."
+}
+
+// forget 不再跟踪已移走的文件
+func (u *upload) forget(name string) {
+	for i := len(u.created) - 1; i >= 0; i-- {
+		if u.created[i] == name {
+			u.created = append(u.created[:i], u.created[i+1:]...)
+			break
+		}
+	}
+}
+
+// countWriter 统计写入的字节数
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// cleanup 删除本次存储过程中创建的文件及(已为空的)目录
+func (u *upload) cleanup() {
+	for i := len(u.created) - 1; i >= 0; i-- {
+		_ = u.fs.Remove(u.created[i])
+	}
+	u.created = nil
+	// 由深至浅删除目录, 非空目录删除失败时保留
+	for _, dir := range u.dirs {
+		_ = u.fs.Remove(dir)
+	}
+	u.dirs = nil
+}
+
+// PruneEmptyDirs 删除 root 下所有空目录(不包括 root 本身)
+func PruneEmptyDirs(root string) error {
+	var dirs []string
+	err := filepath.WalkDir(root, func(name string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && name != root {
+			dirs = append(dirs, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// 由深至浅处理, 子目录删除后父目录可能随之变为空目录
+	sort.Slice(dirs, func(i, j int) bool {
+		return strings.Count(dirs[i], string(filepath.Separator)) > strings.Count(dirs[j], string(filepath.Separator))
+	})
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			continue
+		}
+		if err = os.Remove(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package fileupload
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// listTree 返回目录下的全部文件及目录(相对路径)
+func listTree(t *testing.T, root string) []string {
+	t.Helper()
+	var names []string
+	err := filepath.WalkDir(root, func(name string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name != root {
+			rel, _ := filepath.Rel(root, name)
+			names = append(names, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestFailedUploadLeavesTreeUnchanged(t *testing.T) {
+	errRejected := errors.New("rejected")
+	s, dir := newTestStorage(t, WithScanner(func(ctx context.Context, r io.Reader) error { return errRejected }))
+	param := &FileStorage{StorageSubDirectory: "a/b/c"}
+	if _, err := s.CopyMultipartFile(param, openBytes([]byte("hello")), "a.txt", 5); !errors.Is(err, errRejected) {
+		t.Fatalf("err = %v, want %v", err, errRejected)
+	}
+	results, err := s.Base64Copy(param, [][]byte{dataURI("image/png", pngBytes(t, 2, 2))})
+	if !errors.Is(err, errRejected) || len(results) != 0 {
+		t.Fatalf("base64 = %v, %v, want %v", results, err, errRejected)
+	}
+	if names := listTree(t, dir); len(names) != 0 {
+		t.Errorf("storage tree after failed uploads = %v, want empty", names)
+	}
+}
+
+func TestPruneEmptyDirs(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"a/b/c", "d/e", "f"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "d", "keep.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := PruneEmptyDirs(root); err != nil {
+		t.Fatal(err)
+	}
+	names := listTree(t, root)
+	want := []string{"d", filepath.Join("d", "keep.txt")}
+	if len(names) != len(want) || names[0] != want[0] || names[1] != want[1] {
+		t.Errorf("tree = %v, want %v", names, want)
+	}
+}
+
+func TestStoredFileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix permissions")
+	}
+	s, dir := newTestStorage(t)
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("hello")), "a.txt", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := os.Create(filepath.Join(dir, "created"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = created.Close()
+	want, err := os.Stat(created.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.Stat(result.PathAbs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Mode().Perm() != want.Mode().Perm() {
+		t.Errorf("default mode = %v, want %v like os.Create", got.Mode().Perm(), want.Mode().Perm())
+	}
+
+	s, _ = newTestStorage(t, WithFileMode(0640))
+	if result, err = s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("hello")), "a.txt", 5); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = os.Stat(result.PathAbs); err != nil {
+		t.Fatal(err)
+	}
+	if got.Mode().Perm() != 0640 {
+		t.Errorf("mode = %v, want 0640", got.Mode().Perm())
+	}
+}
@@ -0,0 +1,88 @@
+package fileupload
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// encodeContent 将内容写入 w, 按存储结果依次进行压缩及加密
+func (s *Storage) encodeContent(w io.Writer, content io.Reader, result *FileStorageResult) (err error) {
+	var closers []io.Closer
+	if result.Encrypted {
+		var ew io.WriteCloser
+		if ew, err = s.encryption.newWriter(w); err != nil {
+			return
+		}
+		w = ew
+		closers = append(closers, ew)
+	}
+	if result.Compressed {
+		level := s.compression.Level
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		var gw *gzip.Writer
+		if gw, err = gzip.NewWriterLevel(w, level); err != nil {
+			return
+		}
+		w = gw
+		closers = append(closers, gw)
+	}
+	_, err = s.buffers.copy(w, content)
+	// 由外至内关闭, 保证压缩数据完整写入加密层
+	for i := len(closers) - 1; i >= 0; i-- {
+		if e := closers[i].Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+// Open 打开已存储的文件, 压缩或加密存储的文件读取时自动解压及解密
+func (s *Storage) Open(result *FileStorageResult) (io.ReadCloser, error) {
+	return s.openFile(result.PathAbs, result)
+}
+
+// openFile 打开文件 name, 按存储结果解密及解压
+func (s *Storage) openFile(name string, result *FileStorageResult) (io.ReadCloser, error) {
+	file, err := s.filesystem().Open(name)
+	if err != nil {
+		return nil, err
+	}
+	rc := &readCloser{Reader: file, closers: []io.Closer{file}}
+	if result.Encrypted {
+		if s.encryption == nil {
+			_ = rc.Close()
+			return nil, ErrDecryption
+		}
+		if rc.Reader, err = s.encryption.newReader(rc.Reader); err != nil {
+			_ = rc.Close()
+			return nil, err
+		}
+	}
+	if result.Compressed {
+		gr, err := gzip.NewReader(rc.Reader)
+		if err != nil {
+			_ = rc.Close()
+			return nil, err
+		}
+		rc.Reader = gr
+		rc.closers = append([]io.Closer{gr}, rc.closers...)
+	}
+	return rc, nil
+}
+
+// readCloser 关闭时依次关闭全部 closers
+type readCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r *readCloser) Close() (err error) {
+	for _, c := range r.closers {
+		if e := c.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
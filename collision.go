@@ -0,0 +1,118 @@
+package fileupload
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// maxCollisionSuffix ExistingRename 尝试的最大序号
+const maxCollisionSuffix = 100
+
+// WithVerifyExisting 目标位置已存在大小一致的同名文件时, 再比较(解压解密后的)文件内容, 内容不一致同样视为哈希冲突
+// 适用于 ExistingError 及 ExistingRename
+func WithVerifyExisting(verify bool) Opts {
+	return func(s *Storage) { s.verifyExisting = verify }
+}
+
+// sameExisting 判断已存在的文件与本次写入的临时文件是否为相同内容
+func (s *Storage) sameExisting(u *upload, existing os.FileInfo, tmp string, size int64) (same bool, err error) {
+	if existing.Size() != size {
+		// 压缩或加密存储时文件大小随压缩级别及密钥id(记录于文件头)变化, 比较解码后内容的哈希值
+		if u.result.Compressed || u.result.Encrypted {
+			return s.sameExistingHash(u.result)
+		}
+		return
+	}
+	if !s.verifyExisting {
+		return true, nil
+	}
+	a, err := s.openFile(u.result.PathAbs, u.result)
+	if err != nil {
+		return
+	}
+	defer func() { _ = a.Close() }()
+	b, err := s.openFile(tmp, u.result)
+	if err != nil {
+		return
+	}
+	defer func() { _ = b.Close() }()
+	return equalReader(a, b)
+}
+
+// sameExistingHash 判断已存在的文件解码后内容的哈希值是否与存储结果一致
+func (s *Storage) sameExistingHash(result *FileStorageResult) (bool, error) {
+	rc, err := s.openFile(result.PathAbs, result)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = rc.Close() }()
+	hash, _, err := s.sha256Reader(rc)
+	if err != nil {
+		return false, err
+	}
+	return hash == result.Hash, nil
+}
+
+// equalReader 比较两个读取器的内容是否一致
+func equalReader(a io.Reader, b io.Reader) (bool, error) {
+	bufA, bufB := make([]byte, 32<<10), make([]byte, 32<<10)
+	for {
+		na, ea := io.ReadFull(a, bufA)
+		nb, eb := io.ReadFull(b, bufB)
+		if ea != nil && ea != io.EOF && ea != io.ErrUnexpectedEOF {
+			return false, ea
+		}
+		if eb != nil && eb != io.EOF && eb != io.ErrUnexpectedEOF {
+			return false, eb
+		}
+		if !bytes.Equal(bufA[:na], bufB[:nb]) {
+			return false, nil
+		}
+		if ea != nil || eb != nil {
+			return ea != nil && eb != nil, nil
+		}
+	}
+}
+
+// collisionName 在文件名的第一个 . 之前(没有时在末尾)插入序号, 如 hash.png -> hash-1.png
+func collisionName(name string, n int) string {
+	suffix := "-" + strconv.Itoa(n)
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		return name[:i] + suffix + name[i:]
+	}
+	return name + suffix
+}
+
+// renameCollision 哈希冲突时依次尝试带序号的文件名, 直至目标位置不存在文件或已存在相同内容
+// 返回已存在的相同内容文件的信息(目标位置不存在文件时为 nil)
+func (s *Storage) renameCollision(u *upload, tmp string, size int64) (existing os.FileInfo, err error) {
+	result := u.result
+	name := result.Name
+	for n := 1; n <= maxCollisionSuffix; n++ {
+		result.Name = collisionName(name, n)
+		if err = s.resolvePath(u.param, result); err != nil {
+			return
+		}
+		u.unlocks = append(u.unlocks, s.locker.lock(result.PathAbs))
+		stat, ser := u.fs.Stat(result.PathAbs)
+		if ser != nil {
+			if !os.IsNotExist(ser) {
+				err = ser
+			}
+			return
+		}
+		if stat.IsDir() {
+			continue
+		}
+		same := false
+		if same, err = s.sameExisting(u, stat, tmp, size); err != nil || same {
+			return stat, err
+		}
+	}
+	err = fmt.Errorf("%w: %s", ErrHashCollision, name)
+	return
+}
@@ -0,0 +1,131 @@
+package fileupload
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// samePhotoName 同一请求中同名但内容不同的两个文件
+var samePhotoName = []testFile{
+	{field: "photos", filename: "photo.jpg", content: "first photo"},
+	{field: "photos", filename: "photo.jpg", content: "second, larger photo"},
+}
+
+func TestDuplicateNamesHashNaming(t *testing.T) {
+	s, _ := newTestStorage(t)
+	results, err := s.MultipartCopy(&FileStorage{}, formFileHeaders(t, samePhotoName...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].PathAbs == results[1].PathAbs {
+		t.Fatalf("results = %+v, want two distinct files", results)
+	}
+	for i, result := range results {
+		if got := readFile(t, result.PathAbs); got != samePhotoName[i].content {
+			t.Errorf("file %d = %q, want %q", i, got, samePhotoName[i].content)
+		}
+	}
+}
+
+func TestDuplicateNamesOriginNaming(t *testing.T) {
+	s, _ := newTestStorage(t,
+		WithNameFunc(func(result *FileStorageResult) string { return result.OriginName }),
+		WithExistingPolicy(ExistingRename),
+	)
+	results, err := s.MultipartCopy(&FileStorage{}, formFileHeaders(t, samePhotoName...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if results[0].Name != "photo.jpg" || results[1].Name != "photo-1.jpg" {
+		t.Errorf("names = %s, %s, want photo.jpg, photo-1.jpg", results[0].Name, results[1].Name)
+	}
+	for i, result := range results {
+		if got := readFile(t, result.PathAbs); got != samePhotoName[i].content {
+			t.Errorf("file %d = %q, want %q (last write must not win)", i, got, samePhotoName[i].content)
+		}
+	}
+}
+
+func TestExistingSameSize(t *testing.T) {
+	const content, planted = "hello", "HELLO"
+	for _, tc := range []struct {
+		name      string
+		opts      []Opts
+		collision bool
+		want      string
+	}{
+		{"error", nil, false, planted},
+		{"error verified", []Opts{WithVerifyExisting(true)}, true, planted},
+		{"overwrite", []Opts{WithExistingPolicy(ExistingOverwrite)}, false, content},
+		{"keep", []Opts{WithExistingPolicy(ExistingKeep)}, false, planted},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, _ := newTestStorage(t, tc.opts...)
+			dry, err := s.CopyMultipartFile(&FileStorage{DryRun: true}, openBytes([]byte(content)), "a.txt", 5)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = os.MkdirAll(filepath.Dir(dry.PathAbs), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err = os.WriteFile(dry.PathAbs, []byte(planted), 0644); err != nil {
+				t.Fatal(err)
+			}
+			result, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte(content)), "a.txt", 5)
+			if tc.collision != errors.Is(err, ErrHashCollision) {
+				t.Fatalf("err = %v, want collision %v", err, tc.collision)
+			}
+			if err == nil && result.PathAbs != dry.PathAbs {
+				t.Errorf("PathAbs = %s, want %s", result.PathAbs, dry.PathAbs)
+			}
+			if got := readFile(t, dry.PathAbs); got != tc.want {
+				t.Errorf("existing file = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestVerifyExistingContent(t *testing.T) {
+	const content = "hello"
+	for _, tc := range []struct {
+		name    string
+		planted string
+		renamed bool
+	}{
+		{"identical", content, false},
+		{"mismatched", "HELLO", true},
+	} {
+		s, _ := newTestStorage(t, WithVerifyExisting(true), WithExistingPolicy(ExistingRename))
+		dry, err := s.CopyMultipartFile(&FileStorage{DryRun: true}, openBytes([]byte(content)), "a.txt", 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = os.MkdirAll(filepath.Dir(dry.PathAbs), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(dry.PathAbs, []byte(tc.planted), 0644); err != nil {
+			t.Fatal(err)
+		}
+		result, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte(content)), "a.txt", 5)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if renamed := result.PathAbs != dry.PathAbs; renamed != tc.renamed {
+			t.Errorf("%s: stored at %s, renamed %v, want %v", tc.name, result.Name, renamed, tc.renamed)
+		}
+		if tc.renamed && result.Name != collisionName(dry.Name, 1) {
+			t.Errorf("%s: Name = %s, want %s", tc.name, result.Name, collisionName(dry.Name, 1))
+		}
+		if got := readFile(t, result.PathAbs); got != content {
+			t.Errorf("%s: result content = %q", tc.name, got)
+		}
+		if got := readFile(t, dry.PathAbs); got != tc.planted {
+			t.Errorf("%s: planted file changed to %q", tc.name, got)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package fileupload
+
+import (
+	"strings"
+)
+
+// compressedSuffix 压缩存储的文件名后缀
+const compressedSuffix = ".gz"
+
+// CompressionPolicy 压缩存储策略
+type CompressionPolicy struct {
+	Types      []string // 需要压缩的内容类型(前缀匹配), 如 "text/", "application/json"
+	Extensions []string // 需要压缩的文件后缀, 如 ".json", ".csv"
+	Level      int      // gzip压缩级别, 0 表示 gzip.DefaultCompression
+	MinSize    int64    // 小于该大小的文件不压缩
+}
+
+// DefaultCompressionPolicy 默认压缩存储策略, 压缩文本类内容(日志, JSON, CSV等)
+func DefaultCompressionPolicy() *CompressionPolicy {
+	return &CompressionPolicy{
+		Types:      []string{"text/", "application/json", "application/xml", "application/javascript"},
+		Extensions: []string{".txt", ".log", ".json", ".csv", ".tsv", ".xml", ".html", ".md", ".svg"},
+		MinSize:    1 << 10,
+	}
+}
+
+// WithCompression 对可压缩的内容以gzip压缩存储, 文件名追加 .gz 后缀
+// 哈希值基于压缩前的内容计算, 相同内容的去重不受压缩影响; 通过 Open 读取时自动解压
+func WithCompression(policy *CompressionPolicy) Opts {
+	return func(s *Storage) { s.compression = policy }
+}
+
+// shouldCompress 是否压缩存储
+func (s *Storage) shouldCompress(result *FileStorageResult) bool {
+	policy := s.compression
+	if policy == nil || result.Size < policy.MinSize {
+		return false
+	}
+	ext := strings.ToLower(result.FileExt)
+	for _, v := range policy.Extensions {
+		if strings.EqualFold(v, ext) {
+			return true
+		}
+	}
+	contentType := strings.ToLower(result.FinalContentType)
+	for _, v := range policy.Types {
+		if strings.HasPrefix(contentType, strings.ToLower(v)) {
+			return true
+		}
+	}
+	return false
+}
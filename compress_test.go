@@ -0,0 +1,68 @@
+package fileupload
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCompressionRoundTrip(t *testing.T) {
+	s, _ := newTestStorage(t, WithCompression(DefaultCompressionPolicy()))
+	original := strings.Repeat(`{"level":"info","msg":"compressible log line"}`+"\n", 100)
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte(original)), "app.json", int64(len(original)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Compressed || !strings.HasSuffix(result.PathAbs, compressedSuffix) {
+		t.Fatalf("result = %+v, want a compressed .gz file", result)
+	}
+	if result.OriginalSize != int64(len(original)) {
+		t.Errorf("OriginalSize = %d, want %d", result.OriginalSize, len(original))
+	}
+	stat, err := os.Stat(result.PathAbs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Size() >= int64(len(original)) {
+		t.Errorf("stored size %d is not smaller than %d", stat.Size(), len(original))
+	}
+	zr, err := gzip.NewReader(bytes.NewReader([]byte(readFile(t, result.PathAbs))))
+	if err != nil {
+		t.Fatalf("stored file is not gzip: %v", err)
+	}
+	if b, _ := io.ReadAll(zr); string(b) != original {
+		t.Error("gunzipped file differs from the original")
+	}
+	rc, err := s.Open(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = rc.Close() }()
+	if b, _ := io.ReadAll(rc); string(b) != original {
+		t.Error("Open did not return the original content")
+	}
+	hash, _, _ := s.HashReader(strings.NewReader(original))
+	if result.Hash != hash {
+		t.Errorf("Hash = %s, want the hash of the original %s", result.Hash, hash)
+	}
+}
+
+func TestCompressionSkipsSmallAndBinary(t *testing.T) {
+	s, _ := newTestStorage(t, WithCompression(DefaultCompressionPolicy()))
+	for _, name := range []string{"small.json", "image.png"} {
+		content := "{}"
+		if name == "image.png" {
+			content = string(pngBytes(t, 64, 64))
+		}
+		result, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte(content)), name, int64(len(content)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Compressed {
+			t.Errorf("%s was compressed", name)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package fileupload
+
+import (
+	"sync"
+)
+
+// WithConcurrency 批量存储时的最大并发数, 小于等于1时顺序处理
+func WithConcurrency(n int) Opts {
+	return func(s *Storage) { s.concurrency = n }
+}
+
+// parallel 以有限并发数执行 n 个任务并返回首个错误, 出现错误后不再启动剩余任务
+func (s *Storage) parallel(n int, fn func(i int) error) (err error) {
+	if s.concurrency <= 1 || n <= 1 {
+		for i := 0; i < n; i++ {
+			if err = fn(i); err != nil {
+				return
+			}
+		}
+		return
+	}
+
+	var (
+		wg   sync.WaitGroup
+		once sync.Once
+		done = make(chan struct{})
+		sem  = make(chan struct{}, s.concurrency)
+	)
+	fail := func(e error) {
+		once.Do(func() {
+			err = e
+			close(done)
+		})
+	}
+
+loop:
+	for i := 0; i < n; i++ {
+		select {
+		case <-done:
+			break loop
+		case sem <- struct{}{}:
+		}
+		// 获取信号量期间可能已有任务失败
+		select {
+		case <-done:
+			<-sem
+			break loop
+		default:
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if e := fn(i); e != nil {
+				fail(e)
+			}
+		}(i)
+	}
+	wg.Wait()
+	return
+}
+
+// pathLocker 按文件路径加锁, 保证相同内容(相同路径)的并发写入互斥
+type pathLocker struct {
+	mutex sync.Mutex
+	locks map[string]*pathLock
+}
+
+type pathLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lock 锁定指定路径, 返回解锁函数
+func (l *pathLocker) lock(key string) (unlock func()) {
+	l.mutex.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*pathLock)
+	}
+	lock, ok := l.locks[key]
+	if !ok {
+		lock = &pathLock{}
+		l.locks[key] = lock
+	}
+	lock.refs++
+	l.mutex.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		l.mutex.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(l.locks, key)
+		}
+		l.mutex.Unlock()
+	}
+}
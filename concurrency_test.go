@@ -0,0 +1,116 @@
+package fileupload
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentMultipartCopy(t *testing.T) {
+	s, _ := newTestStorage(t, WithConcurrency(4))
+	files := make([]testFile, 0, 32)
+	for i := 0; i < cap(files); i++ {
+		files = append(files, testFile{field: "files", filename: fmt.Sprintf("%d.txt", i), content: fmt.Sprintf("file content %d", i)})
+	}
+	results, err := s.MultipartCopy(&FileStorage{}, formFileHeaders(t, files...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(files) {
+		t.Fatalf("got %d results, want %d", len(results), len(files))
+	}
+	for i, result := range results {
+		hash, _, err := s.HashReader(bytes.NewReader([]byte(files[i].content)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Hash != hash || result.OriginName != files[i].filename {
+			t.Errorf("result %d = %s %s, want %s %s", i, result.OriginName, result.Hash, files[i].filename, hash)
+		}
+		if got := readFile(t, result.PathAbs); got != files[i].content {
+			t.Errorf("result %d content = %q", i, got)
+		}
+	}
+}
+
+func TestConcurrentBase64Copy(t *testing.T) {
+	s, _ := newTestStorage(t, WithConcurrency(4))
+	images := make([][]byte, 20)
+	files := make([][]byte, len(images))
+	for i := range images {
+		images[i] = pngBytes(t, i+1, 3)
+		files[i] = dataURI("image/png", images[i])
+	}
+	results, err := s.Base64Copy(&FileStorage{}, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(images) {
+		t.Fatalf("got %d results, want %d", len(results), len(images))
+	}
+	for i, result := range results {
+		hash, size, err := s.HashReader(bytes.NewReader(images[i]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Hash != hash || result.Size != size {
+			t.Errorf("result %d = %s/%d, want %s/%d", i, result.Hash, result.Size, hash, size)
+		}
+		if got := readFile(t, result.PathAbs); got != string(images[i]) {
+			t.Errorf("result %d content differs", i)
+		}
+	}
+}
+
+func TestConcurrentFreshSubdirectory(t *testing.T) {
+	s, _ := newTestStorage(t)
+	const n = 16
+	errs := make([]error, n)
+	images := make([][]byte, n)
+	for i := range images {
+		images[i] = dataURI("image/png", pngBytes(t, i+1, 1))
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// 同时创建尚不存在的多级目录
+			param := &FileStorage{StorageSubDirectory: "fresh/nested"}
+			if i%2 == 0 {
+				_, errs[i] = s.Base64Copy(param, [][]byte{images[i]})
+				return
+			}
+			content := []byte(fmt.Sprintf("file %d", i))
+			_, errs[i] = s.CopyMultipartFile(param, openBytes(content), "a.txt", int64(len(content)))
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("store %d: %v", i, err)
+		}
+	}
+	results, err := s.List("fresh/nested", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != n {
+		t.Errorf("List = %d files, want %d", len(results), n)
+	}
+}
+
+func TestParallelStopsOnError(t *testing.T) {
+	s := NewStorage(WithConcurrency(2))
+	errFailed := fmt.Errorf("failed")
+	err := s.parallel(10, func(i int) error {
+		if i == 3 {
+			return errFailed
+		}
+		return nil
+	})
+	if err != errFailed {
+		t.Fatalf("err = %v, want %v", err, errFailed)
+	}
+}
@@ -0,0 +1,103 @@
+package fileupload
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// sniffLength 内容类型检测读取的最大字节数
+const sniffLength = 512
+
+// sniffContentType 读取内容头部检测内容类型
+func sniffContentType(r io.Reader) (string, error) {
+	buf := make([]byte, sniffLength)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	return detectContentType(buf[:n]), nil
+}
+
+// detectContentType 检测内容类型, 在 http.DetectContentType 的基础上识别 WebP 及 AVIF/HEIF
+func detectContentType(data []byte) string {
+	// RIFF....WEBP
+	if len(data) >= 12 && bytes.Equal(data[:4], []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WEBP")) {
+		return "image/webp"
+	}
+	if contentType := isoImageType(data); contentType != "" {
+		return contentType
+	}
+	return http.DetectContentType(data)
+}
+
+// isoImageType 根据 ISO BMFF 文件头部的 ftyp 盒子识别 AVIF/HEIF 图片, 无法识别时返回空
+func isoImageType(data []byte) string {
+	if len(data) < 16 || !bytes.Equal(data[4:8], []byte("ftyp")) {
+		return ""
+	}
+	size := int(binary.BigEndian.Uint32(data[:4]))
+	if size < 16 || size > len(data) {
+		size = len(data)
+	}
+	// 主品牌及兼容品牌, 跳过4字节的次版本号
+	brands := [][]byte{data[8:12]}
+	for i := 16; i+4 <= size; i += 4 {
+		brands = append(brands, data[i:i+4])
+	}
+	heif := false
+	for _, brand := range brands {
+		switch string(brand) {
+		case "avif", "avis":
+			return "image/avif"
+		case "heic", "heix", "heim", "heis", "hevc", "hevx":
+			return "image/heic"
+		case "mif1", "msf1":
+			heif = true
+		}
+	}
+	if heif {
+		return "image/heif"
+	}
+	return ""
+}
+
+// headBuffer 记录写入内容的头部, 用于检测内容类型
+type headBuffer struct {
+	buf []byte
+}
+
+func (h *headBuffer) Write(p []byte) (int, error) {
+	if remain := sniffLength - len(h.buf); remain > 0 {
+		if len(p) < remain {
+			remain = len(p)
+		}
+		h.buf = append(h.buf, p[:remain]...)
+	}
+	return len(p), nil
+}
+
+// ContentType 检测到的内容类型
+func (h *headBuffer) ContentType() string {
+	return detectContentType(h.buf)
+}
+
+// genericContentType 内容检测无法识别时的类型
+const genericContentType = "application/octet-stream"
+
+// resolveContentType 确定返回给客户端的内容类型, 依次使用: 内容检测结果, 客户端声明的类型(如base64 data URI), 文件后缀对应的类型
+func resolveContentType(detected string, declared string, ext string) string {
+	if detected != "" && detected != genericContentType {
+		return detected
+	}
+	if declared != "" {
+		return declared
+	}
+	if byExt := mime.TypeByExtension(ext); byExt != "" {
+		return byExt
+	}
+	return genericContentType
+}
@@ -0,0 +1,153 @@
+package fileupload
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/jpeg"
+	"io"
+	"testing"
+)
+
+func TestFinalContentType(t *testing.T) {
+	content := pngBytes(t, 4, 4)
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	if _, err := zw.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	s, _ := newTestStorage(t, WithDecompressOnUpload(true))
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes(buf.Bytes()), "image.png.gz", int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.DetectedContentType != "application/x-gzip" {
+		t.Errorf("DetectedContentType = %q, want application/x-gzip", result.DetectedContentType)
+	}
+	if result.FinalContentType != "image/png" {
+		t.Errorf("FinalContentType = %q, want image/png", result.FinalContentType)
+	}
+}
+
+func TestFinalContentTypeConverted(t *testing.T) {
+	// 测试用的转换: 将 HEIC 内容替换为 JPEG 图片
+	converted := &bytes.Buffer{}
+	if err := jpeg.Encode(converted, image.NewGray(image.Rect(0, 0, 4, 4)), nil); err != nil {
+		t.Fatal(err)
+	}
+	var calls []string
+	s, _ := newTestStorage(t, WithConverter(func(contentType string, content io.Reader) (io.Reader, error) {
+		calls = append(calls, contentType)
+		if contentType != "image/heic" {
+			return nil, nil
+		}
+		if _, err := io.Copy(io.Discard, content); err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(converted.Bytes()), nil
+	}))
+	heic := append([]byte{0, 0, 0, 24}, []byte("ftypheic\x00\x00\x00\x00mif1heic")...)
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes(heic), "IMG_0001.HEIC", int64(len(heic)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.DetectedContentType != "image/heic" {
+		t.Errorf("DetectedContentType = %q, want image/heic", result.DetectedContentType)
+	}
+	if result.FinalContentType != "image/jpeg" || result.ContentType != "image/jpeg" {
+		t.Errorf("FinalContentType = %q, ContentType = %q, want image/jpeg", result.FinalContentType, result.ContentType)
+	}
+	if result.FileExt != ".jpg" || readFile(t, result.PathAbs) != converted.String() {
+		t.Errorf("stored %s, want the converted jpeg", result.Name)
+	}
+	if len(calls) == 0 || calls[0] != "image/heic" {
+		t.Errorf("converter calls = %v", calls)
+	}
+
+	// 不需要转换的内容原样存储
+	content := pngBytes(t, 2, 2)
+	if result, err = s.CopyMultipartFile(&FileStorage{}, openBytes(content), "a.png", int64(len(content))); err != nil {
+		t.Fatal(err)
+	}
+	if result.FinalContentType != "image/png" || result.FileExt != ".png" || readFile(t, result.PathAbs) != string(content) {
+		t.Errorf("unconverted = %s %s", result.FinalContentType, result.Name)
+	}
+}
+
+func TestResultContentType(t *testing.T) {
+	s, _ := newTestStorage(t)
+	content := pngBytes(t, 4, 4)
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes(content), "upload.bin", int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ContentType != "image/png" {
+		t.Errorf("ContentType = %q, want image/png", result.ContentType)
+	}
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(encoded, []byte(`"content_type":"image/png"`)) {
+		t.Errorf("json = %s, want a content_type field", encoded)
+	}
+
+	binary := []byte{0x00, 0x01, 0x02, 0x03}
+	results, err := s.Base64Copy(&FileStorage{}, [][]byte{dataURI("image/x-custom", binary)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].ContentType != "image/x-custom" {
+		t.Errorf("base64 ContentType = %q, want the declared image/x-custom", results[0].ContentType)
+	}
+	result, err = s.CopyMultipartFile(&FileStorage{}, openBytes(binary), "doc.pdf", int64(len(binary)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ContentType != "application/pdf" {
+		t.Errorf("extension ContentType = %q, want application/pdf", result.ContentType)
+	}
+}
+
+func TestDetectHEIC(t *testing.T) {
+	heic := append([]byte{0, 0, 0, 24}, []byte("ftypheic\x00\x00\x00\x00mif1heic")...)
+	if got := detectContentType(heic); got != "image/heic" {
+		t.Errorf("detectContentType = %q, want image/heic", got)
+	}
+}
+
+// minimalWebP 1x1 的无损WebP图片
+const minimalWebP = "UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA=="
+
+func TestDetectWebPAndAVIF(t *testing.T) {
+	webp, err := base64.StdEncoding.DecodeString(minimalWebP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	avif := append([]byte{0, 0, 0, 28}, []byte("ftypavif\x00\x00\x00\x00avifmif1miaf")...)
+	s, _ := newTestStorage(t, WithExtensionFromContentType(true))
+	for _, tc := range []struct {
+		content     []byte
+		contentType string
+		ext         string
+	}{
+		{webp, "image/webp", ".webp"},
+		{avif, "image/avif", ".avif"},
+	} {
+		if got := detectContentType(tc.content); got != tc.contentType {
+			t.Errorf("detectContentType = %q, want %s", got, tc.contentType)
+		}
+		result, err := s.CopyMultipartFile(&FileStorage{}, openBytes(tc.content), "upload", int64(len(tc.content)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.ContentType != tc.contentType || result.Category != CategoryImage || result.FileExt != tc.ext {
+			t.Errorf("result = %s %s %s, want %s image %s", result.ContentType, result.Category, result.FileExt, tc.contentType, tc.ext)
+		}
+	}
+}
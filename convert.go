@@ -0,0 +1,39 @@
+package fileupload
+
+import (
+	"bufio"
+	"io"
+	"mime"
+)
+
+// Converter 转换上传内容的格式(如 HEIC 转 JPEG), contentType 为上传内容(解压后)检测的类型, 返回 nil 表示不转换
+// 每次读取上传内容(计算哈希值, 写入文件等)时调用, 相同的输入需返回相同的输出
+type Converter func(contentType string, content io.Reader) (io.Reader, error)
+
+// WithConverter 存储前转换上传内容的格式, 哈希值基于转换后的内容计算; 转换后文件后缀及 FinalContentType 为转换后的类型
+func WithConverter(converter Converter) Opts {
+	return func(s *Storage) { s.converter = converter }
+}
+
+// convertReader 检测内容类型并按 Converter 转换, 不需要转换时返回原内容
+func (s *Storage) convertReader(u *upload, content io.Reader) (io.Reader, error) {
+	br := bufio.NewReaderSize(content, sniffLength)
+	head, _ := br.Peek(sniffLength)
+	converted, err := s.converter(detectContentType(head), br)
+	if err != nil || converted == nil {
+		return br, err
+	}
+	u.converted = true
+	return converted, nil
+}
+
+// convertedExtension 转换格式后的文件后缀, 使用转换后内容类型对应的后缀
+func (s *Storage) convertedExtension(result *FileStorageResult) {
+	mediaType, _, err := mime.ParseMediaType(result.FinalContentType)
+	if err != nil {
+		return
+	}
+	if ext := s.mimeExtension(mediaType); ext != "" {
+		result.FileExt = ext
+	}
+}
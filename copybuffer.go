@@ -0,0 +1,39 @@
+package fileupload
+
+import (
+	"io"
+	"sync"
+)
+
+// defaultCopyBufferSize 复制内容的默认缓冲区大小(与 io.Copy 一致)
+const defaultCopyBufferSize = 32 << 10
+
+// WithCopyBufferSize 复制内容(计算哈希值, 写入文件)使用的缓冲区大小, 大文件使用更大的缓冲区可减少系统调用; 默认32KB
+// 缓冲区通过 sync.Pool 复用, 目标实现了 io.ReaderFrom (或源实现了 io.WriterTo)时由其自行处理, 不使用缓冲区
+func WithCopyBufferSize(n int) Opts {
+	return func(s *Storage) { s.buffers.size = n }
+}
+
+// copyBuffers 复制内容使用的缓冲区池
+type copyBuffers struct {
+	size int
+	pool sync.Pool
+}
+
+// copy 使用池中的缓冲区复制内容
+func (b *copyBuffers) copy(dst io.Writer, src io.Reader) (int64, error) {
+	if b == nil {
+		return io.Copy(dst, src)
+	}
+	size := b.size
+	if size <= 0 {
+		size = defaultCopyBufferSize
+	}
+	buf, ok := b.pool.Get().(*[]byte)
+	if !ok || len(*buf) != size {
+		tmp := make([]byte, size)
+		buf = &tmp
+	}
+	defer b.pool.Put(buf)
+	return io.CopyBuffer(dst, src, *buf)
+}
@@ -0,0 +1,81 @@
+package fileupload
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// plainReader 隐藏 io.WriterTo, 使复制使用缓冲区
+type plainReader struct {
+	r io.Reader
+}
+
+func (r plainReader) Read(p []byte) (int, error) { return r.r.Read(p) }
+
+// countingWriter 统计写入次数(对应文件写入的系统调用数), 不实现 io.ReaderFrom
+type countingWriter struct {
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return len(p), nil
+}
+
+func TestCopyBufferSize(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 1<<20)
+	for _, tc := range []struct {
+		size   int
+		writes int
+	}{
+		{0, 32},
+		{256 << 10, 4},
+	} {
+		b := &copyBuffers{size: tc.size}
+		w := &countingWriter{}
+		n, err := b.copy(w, plainReader{r: bytes.NewReader(content)})
+		if err != nil || n != int64(len(content)) {
+			t.Fatalf("size %d: copied %d, %v", tc.size, n, err)
+		}
+		if w.writes != tc.writes {
+			t.Errorf("size %d: %d writes, want %d", tc.size, w.writes, tc.writes)
+		}
+	}
+	if raceEnabled {
+		return
+	}
+	b := &copyBuffers{}
+	src := bytes.NewReader(content)
+	var r io.Reader = plainReader{r: src}
+	w := &countingWriter{}
+	allocs := testing.AllocsPerRun(10, func() {
+		src.Reset(content)
+		_, _ = b.copy(w, r)
+	})
+	if allocs >= 1 {
+		t.Errorf("copy allocates %v times per run, want the pooled buffer reused", allocs)
+	}
+}
+
+func BenchmarkCopyBufferSize(b *testing.B) {
+	content := bytes.Repeat([]byte("x"), 8<<20)
+	for _, size := range []int{32 << 10, 128 << 10, 1 << 20} {
+		b.Run(fmt.Sprintf("%dKB", size>>10), func(b *testing.B) {
+			buffers := &copyBuffers{size: size}
+			src := bytes.NewReader(content)
+			w := &countingWriter{}
+			b.SetBytes(int64(len(content)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				src.Reset(content)
+				if _, err := buffers.copy(w, plainReader{r: src}); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(w.writes)/float64(b.N), "writes/op")
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package fileupload
+
+import (
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"strings"
+)
+
+// WithAllowedDeclaredTypes 按表单文件声明的 Content-Type 预先过滤上传文件, 在读取文件内容之前拒绝不允许的类型
+// 支持 image/* 形式的通配; 未声明类型或声明为 application/octet-stream 时视为未知类型, 不做拦截
+// 声明的类型由客户端提供, 仅作为快速预检, 不能替代基于内容的类型检测
+func WithAllowedDeclaredTypes(types ...string) Opts {
+	return func(s *Storage) {
+		s.declaredTypes = nil
+		for _, v := range types {
+			if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+				s.declaredTypes = append(s.declaredTypes, v)
+			}
+		}
+	}
+}
+
+// checkDeclaredType 检查表单文件声明的类型是否允许上传
+func (s *Storage) checkDeclaredType(file *multipart.FileHeader) error {
+	if len(s.declaredTypes) == 0 {
+		return nil
+	}
+	declared := file.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(declared)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(declared))
+	}
+	if mediaType == "" || mediaType == "application/octet-stream" {
+		return nil
+	}
+	for _, v := range s.declaredTypes {
+		if v == mediaType || (strings.HasSuffix(v, "/*") && strings.HasPrefix(mediaType, v[:len(v)-1])) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrDeclaredTypeNotAllowed, declared)
+}
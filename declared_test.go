@@ -0,0 +1,30 @@
+package fileupload
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestDeclaredTypes(t *testing.T) {
+	s, _ := newTestStorage(t, WithAllowedDeclaredTypes("image/*", "application/pdf"))
+	files := formFileHeaders(t,
+		testFile{field: "f", filename: "setup.exe", content: "MZ", contentType: "application/x-msdownload"},
+		testFile{field: "f", filename: "a.png", content: string(pngBytes(t, 1, 1)), contentType: "image/png"},
+		testFile{field: "f", filename: "b.bin", content: "unknown", contentType: "application/octet-stream"},
+		testFile{field: "f", filename: "c.pdf", content: "%PDF-", contentType: "application/pdf; charset=binary"},
+	)
+	// 拒绝时不读取文件内容
+	_, err := s.fileHeaderCopy(&FileStorage{}, files[0], func() (io.ReadSeekCloser, error) {
+		t.Error("disallowed part was opened")
+		return files[0].Open()
+	}, nil)
+	if !errors.Is(err, ErrDeclaredTypeNotAllowed) {
+		t.Fatalf("err = %v, want ErrDeclaredTypeNotAllowed", err)
+	}
+	for _, file := range files[1:] {
+		if _, err = s.MultipartCopy(&FileStorage{}, file); err != nil {
+			t.Errorf("%s declared %s: %v", file.Filename, file.Header.Get("Content-Type"), err)
+		}
+	}
+}
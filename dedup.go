@@ -0,0 +1,95 @@
+package fileupload
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"sync"
+)
+
+// DedupScope 相同内容的复用范围
+type DedupScope int
+
+const (
+	DedupDefault      DedupScope = iota // 设置 WithContentIndex 时为 DedupGlobal, 否则为 DedupSubDirectory
+	DedupSubDirectory                   // 仅复用同一子目录(相同存储路径)下的相同内容
+	DedupGlobal                         // 复用整个存储目录下的相同内容, 返回已存储文件的位置而不论请求的子目录
+	DedupNone                           // 不复用相同内容, 文件名为 哈希值-Uid+后缀, 每次存储均写入新文件(如每次提交需独立留存)
+)
+
+// WithDedupScope 相同内容的复用范围, 默认 DedupDefault
+// DedupGlobal 未设置 WithContentIndex 时, 首次存储前扫描存储目录(按文件名中的哈希值)建立内存索引, 之后随存储及删除更新
+// 使用 WithNameFunc 自定义文件名的文件不参与跨子目录复用; DedupNone 时不使用内容索引及引用计数
+func WithDedupScope(scope DedupScope) Opts {
+	return func(s *Storage) { s.dedupScope = scope }
+}
+
+// scannedIndex 扫描存储目录建立的内存索引
+type scannedIndex struct {
+	once  sync.Once
+	index *MemoryIndex
+	err   error
+}
+
+// global 是否跨子目录复用相同内容
+func (s *Storage) global() bool {
+	if s.dedupScope == DedupDefault {
+		return s.contentIndex != nil
+	}
+	return s.dedupScope == DedupGlobal
+}
+
+// dedupIndex 查询相同内容使用的内容索引, 不跨子目录复用时返回 nil
+func (s *Storage) dedupIndex() (ContentIndex, error) {
+	if !s.global() {
+		return nil, nil
+	}
+	if s.contentIndex != nil {
+		return s.contentIndex, nil
+	}
+	s.scanned.once.Do(func() {
+		s.scanned.index, s.scanned.err = s.scanIndex()
+	})
+	if s.scanned.err != nil {
+		return nil, s.scanned.err
+	}
+	return s.scanned.index, nil
+}
+
+// recordIndex 记录已存储文件使用的内容索引, 没有时返回 nil
+func (s *Storage) recordIndex() (ContentIndex, error) {
+	if s.contentIndex != nil {
+		return s.contentIndex, nil
+	}
+	return s.dedupIndex()
+}
+
+// scanIndex 扫描存储目录, 以文件名中的哈希值建立内存索引
+func (s *Storage) scanIndex() (*MemoryIndex, error) {
+	index := NewMemoryIndex()
+	results, err := s.List("", &ListOptions{Recursive: true})
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+		return index, err
+	}
+	for _, result := range results {
+		hash := result.Name
+		if result.Encrypted {
+			hash = strings.TrimSuffix(hash, encryptedSuffix)
+		}
+		if result.Compressed {
+			hash = strings.TrimSuffix(hash, compressedSuffix)
+		}
+		hash = strings.TrimSuffix(hash, result.FileExt)
+		if hash == "" {
+			continue
+		}
+		result.Hash = hash
+		if err = index.Store(hash, result); err != nil {
+			return nil, err
+		}
+	}
+	return index, nil
+}
@@ -0,0 +1,104 @@
+package fileupload
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// storeTwice 将相同内容分别存储至两个子目录
+func storeTwice(t *testing.T, s *Storage, first string, second string) (*FileStorageResult, *FileStorageResult) {
+	t.Helper()
+	a, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: first}, openBytes([]byte("shared")), "logo.png", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: second}, openBytes([]byte("shared")), "logo.png", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, b
+}
+
+func TestDedupScope(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		scope DedupScope
+		files int
+	}{
+		{"default", DedupDefault, 2},
+		{"sub directory", DedupSubDirectory, 2},
+		{"global", DedupGlobal, 1},
+	} {
+		s, dir := newTestStorage(t, WithDedupScope(tc.scope))
+		a, b := storeTwice(t, s, "tenant-a", "tenant-b")
+		if tc.files == 1 && (b.PathAbs != a.PathAbs || b.Created) {
+			t.Errorf("%s: second upload = %s (created %v), want the existing %s", tc.name, b.PathAbs, b.Created, a.PathAbs)
+		}
+		if tc.files == 2 && (b.PathAbs == a.PathAbs || !b.Created) {
+			t.Errorf("%s: second upload reused %s", tc.name, a.PathAbs)
+		}
+		if got := countFiles(t, dir); got != tc.files {
+			t.Errorf("%s: %d physical files, want %d", tc.name, got, tc.files)
+		}
+	}
+}
+
+func TestDedupGlobalScansExistingFiles(t *testing.T) {
+	s, dir := newTestStorage(t)
+	a, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: "old"}, openBytes([]byte("shared")), "logo.png", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 新的实例首次存储前扫描存储目录
+	s = NewStorage(WithStorageDirectory(dir), WithDedupScope(DedupGlobal))
+	b, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: "new"}, openBytes([]byte("shared")), "logo.png", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.PathAbs != a.PathAbs || b.Created {
+		t.Errorf("upload = %s (created %v), want the scanned %s", b.PathAbs, b.Created, a.PathAbs)
+	}
+}
+
+func TestDedupNone(t *testing.T) {
+	s, dir := newTestStorage(t, WithDedupScope(DedupNone))
+	a, b := storeTwice(t, s, "same", "same")
+	if a.PathAbs == b.PathAbs || !b.Created {
+		t.Errorf("second upload reused %s", a.PathAbs)
+	}
+	if got := countFiles(t, dir); got != 2 {
+		t.Errorf("%d physical files, want 2", got)
+	}
+}
+
+func TestDedupNoneDistinctSubmissions(t *testing.T) {
+	// 同时设置内容索引及引用计数时仍不复用相同内容
+	s, dir := newTestStorage(t, WithDedupScope(DedupNone), WithContentIndex(NewMemoryIndex()), WithRefCounter(NewMemoryRefCounter()))
+	a, b := storeTwice(t, s, "", "")
+	if a.Name == b.Name || a.Hash != b.Hash {
+		t.Fatalf("names = %s, %s, want distinct names for the same hash", a.Name, b.Name)
+	}
+	for _, result := range []*FileStorageResult{a, b} {
+		if !strings.HasPrefix(result.Name, result.Hash+"-"+strconv.FormatInt(result.Uid, 10)) || readFile(t, result.PathAbs) != "shared" {
+			t.Errorf("result = %s, want hash-uid naming and the content", result.Name)
+		}
+	}
+	// 删除一次提交不影响另一次提交的文件
+	if err := s.Delete(a); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, b.PathAbs); got != "shared" || countFiles(t, dir) != 1 {
+		t.Errorf("after delete: content %q, %d files", got, countFiles(t, dir))
+	}
+}
+
+// countFiles 统计目录下(不含以 . 开头的目录)的文件数
+func countFiles(t *testing.T, dir string) int {
+	t.Helper()
+	results, err := NewStorage(WithStorageDirectory(dir)).List("", &ListOptions{Recursive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(results)
+}
@@ -0,0 +1,65 @@
+package fileupload
+
+import (
+	"fmt"
+	"os"
+)
+
+// WithDedupVerify 内容索引命中时, 在文件锁内再次确认已存储文件仍然存在, 若已被并发删除则重新写入
+func WithDedupVerify(verify bool) Opts {
+	return func(s *Storage) { s.dedupVerify = verify }
+}
+
+// Delete 删除已存储的文件(及其缩略图, 元数据文件), 并移除内容索引中的记录; 文件不存在时不返回错误
+// 文件路径见 AbsolutePath, 仅能删除存储目录下的文件; 设置 WithRefCounter 时先减少引用数, 仍有其它引用时保留文件
+func (s *Storage) Delete(result *FileStorageResult) (err error) {
+	if result == nil || (result.PathAbs == "" && result.PathRlt == "" && result.PathUri == "") {
+		err = fmt.Errorf("delete: empty file path")
+		return
+	}
+	name, err := s.AbsolutePath(result)
+	if err != nil {
+		return
+	}
+	return s.deleteFile(name, result.Hash)
+}
+
+// deleteFile 删除存储过程中写入的文件 name(绝对路径), hash 为文件哈希值(未知时为空)
+func (s *Storage) deleteFile(name string, hash string) (err error) {
+	index, err := s.recordIndex()
+	if err != nil {
+		return
+	}
+	if (index != nil || s.counting()) && hash != "" {
+		unlockHash := s.locker.lock("hash:" + hash)
+		defer unlockHash()
+	}
+	unlock := s.locker.lock(name)
+	defer unlock()
+
+	if s.counting() {
+		var refs int64
+		if refs, err = s.refCounter.Decr(s.refKey(name)); err != nil || refs > 0 {
+			return
+		}
+	}
+
+	fs := s.filesystem()
+	stat, ser := fs.Stat(name)
+	if err = fs.Remove(name); err != nil && !os.IsNotExist(err) {
+		return
+	}
+	if ser == nil && err == nil {
+		s.releaseQuota(name, stat.Size())
+	}
+	if err = fs.Remove(thumbnailPath(name)); err != nil && !os.IsNotExist(err) {
+		return
+	}
+	if err = s.removeSidecar(name); err != nil {
+		return
+	}
+	if index != nil && hash != "" {
+		err = index.Delete(hash)
+	}
+	return
+}
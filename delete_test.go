@@ -0,0 +1,100 @@
+package fileupload
+
+import (
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestDelete(t *testing.T) {
+	s, _ := newTestStorage(t, WithContentIndex(NewMemoryIndex()))
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("hello")), "a.txt", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Delete(result); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(result.PathAbs); !os.IsNotExist(err) {
+		t.Errorf("file still exists: %v", err)
+	}
+	if err = s.Delete(result); err != nil {
+		t.Errorf("deleting a missing file: %v", err)
+	}
+	again, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: "other"}, openBytes([]byte("hello")), "a.txt", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !again.Created || readFile(t, again.PathAbs) != "hello" {
+		t.Errorf("store after delete = %+v, want a new file", again)
+	}
+}
+
+func TestDedupVerifyRace(t *testing.T) {
+	s, _ := newTestStorage(t, WithContentIndex(NewMemoryIndex()), WithDedupVerify(true))
+	store := func(sub string) (*FileStorageResult, error) {
+		return s.CopyMultipartFile(&FileStorage{StorageSubDirectory: sub}, openBytes([]byte("shared")), "a.txt", 6)
+	}
+	prev, err := store("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 50; i++ {
+		var wg sync.WaitGroup
+		var serr, derr error
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, serr = store("b")
+		}()
+		go func(prev *FileStorageResult) {
+			defer wg.Done()
+			derr = s.Delete(prev)
+		}(prev)
+		wg.Wait()
+		if serr != nil || derr != nil {
+			t.Fatalf("store: %v, delete: %v", serr, derr)
+		}
+		// 存储结束后重新存储, 返回的文件必须存在且内容完整
+		if prev, err = store("a"); err != nil {
+			t.Fatal(err)
+		}
+		if got := readFile(t, prev.PathAbs); got != "shared" {
+			t.Fatalf("iteration %d: stored file content = %q", i, got)
+		}
+	}
+}
+
+func TestDeleteRefCounted(t *testing.T) {
+	counter := NewMemoryRefCounter()
+	s, _ := newTestStorage(t, WithRefCounter(counter))
+	first, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("shared")), "a.txt", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("shared")), "b.txt", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.PathAbs != first.PathAbs {
+		t.Fatalf("second reference stored at %s, want %s", second.PathAbs, first.PathAbs)
+	}
+	if n, _ := counter.Count(first.PathRlt); n != 2 {
+		t.Errorf("count = %d, want 2", n)
+	}
+	if err = s.Delete(first); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, second.PathAbs); got != "shared" {
+		t.Errorf("file after deleting one of two references = %q", got)
+	}
+	if err = s.Delete(second); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(second.PathAbs); !os.IsNotExist(err) {
+		t.Errorf("file still exists after the last reference: %v", err)
+	}
+	if n, _ := counter.Count(first.PathRlt); n != 0 {
+		t.Errorf("count = %d, want 0", n)
+	}
+}
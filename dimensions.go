@@ -0,0 +1,54 @@
+package fileupload
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"strings"
+)
+
+// WithImageDimensionLimits 图片宽高限制, 超出范围的图片返回 ErrImageDimensions 且不写入文件; 小于等于0表示该项不限制
+// 仅解析图片头部信息(image.DecodeConfig), 不完整解码图片; 非图片及无法识别格式的图片不做检查
+func WithImageDimensionLimits(minWidth int, minHeight int, maxWidth int, maxHeight int) Opts {
+	return func(s *Storage) {
+		s.dimensionLimits = &dimensionLimits{
+			minWidth:  minWidth,
+			minHeight: minHeight,
+			maxWidth:  maxWidth,
+			maxHeight: maxHeight,
+		}
+	}
+}
+
+// dimensionLimits 图片宽高限制
+type dimensionLimits struct {
+	minWidth  int
+	minHeight int
+	maxWidth  int
+	maxHeight int
+}
+
+// checkDimensions 写入之前检查图片宽高
+func (s *Storage) checkDimensions(u *upload) (err error) {
+	limits := s.dimensionLimits
+	if limits == nil || !strings.HasPrefix(u.result.FinalContentType, "image/") {
+		return
+	}
+	content, err := s.openContent(u)
+	if err != nil {
+		return
+	}
+	config, _, err := image.DecodeConfig(content)
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			err = nil
+		}
+		return
+	}
+	width, height := config.Width, config.Height
+	if (limits.minWidth > 0 && width < limits.minWidth) || (limits.minHeight > 0 && height < limits.minHeight) ||
+		(limits.maxWidth > 0 && width > limits.maxWidth) || (limits.maxHeight > 0 && height > limits.maxHeight) {
+		return fmt.Errorf("%w: %dx%d", ErrImageDimensions, width, height)
+	}
+	return
+}
@@ -0,0 +1,28 @@
+package fileupload
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestImageDimensionLimits(t *testing.T) {
+	s, dir := newTestStorage(t, WithImageDimensionLimits(64, 64, 4096, 4096))
+	for _, tc := range []struct {
+		name    string
+		content []byte
+		err     error
+	}{
+		{"tiny.png", pngBytes(t, 16, 16), ErrImageDimensions},
+		{"oversized.png", pngBytes(t, 4097, 64), ErrImageDimensions},
+		{"avatar.png", pngBytes(t, 128, 128), nil},
+		{"notes.txt", []byte("not an image"), nil},
+	} {
+		_, err := s.CopyMultipartFile(&FileStorage{}, openBytes(tc.content), tc.name, int64(len(tc.content)))
+		if !errors.Is(err, tc.err) {
+			t.Errorf("%s: err = %v, want %v", tc.name, err, tc.err)
+		}
+	}
+	if got := countFiles(t, dir); got != 2 {
+		t.Errorf("%d files stored, want only the accepted two", got)
+	}
+}
@@ -0,0 +1,41 @@
+package fileupload
+
+import (
+	"fmt"
+)
+
+// WithMinFreeSpace 写入前检查目标文件系统的可用空间, 可用空间需不小于文件大小加 bytes(预留空间), 否则返回 ErrInsufficientSpace
+// 不支持查询可用空间的平台跳过检查
+func WithMinFreeSpace(bytes int64) Opts {
+	return func(s *Storage) {
+		s.minFreeSpace = bytes
+		s.checkFreeSpace = true
+	}
+}
+
+// diskFreeSpace 查询目录所在文件系统的可用空间, 平台不支持时 ok 为 false
+var diskFreeSpace = platformDiskFreeSpace
+
+// preflightSpace 写入前检查可用空间
+func (s *Storage) preflightSpace(directory string, size int64) error {
+	if !s.checkFreeSpace {
+		return nil
+	}
+	free, ok, err := diskFreeSpace(directory)
+	if err != nil || !ok {
+		return err
+	}
+	return checkSpace(free, size, s.minFreeSpace)
+}
+
+// checkSpace 可用空间 free 是否足够写入 size 字节并保留 headroom 字节
+func checkSpace(free uint64, size int64, headroom int64) error {
+	need := size + headroom
+	if need < 0 {
+		need = 0
+	}
+	if free < uint64(need) {
+		return fmt.Errorf("%w: need %d bytes, available %d bytes", ErrInsufficientSpace, need, free)
+	}
+	return nil
+}
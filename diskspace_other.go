@@ -0,0 +1,7 @@
+//go:build !(linux || darwin || freebsd || dragonfly || windows)
+
+package fileupload
+
+func platformDiskFreeSpace(string) (uint64, bool, error) {
+	return 0, false, nil
+}
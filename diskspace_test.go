@@ -0,0 +1,50 @@
+package fileupload
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckSpace(t *testing.T) {
+	for _, tc := range []struct {
+		free     uint64
+		size     int64
+		headroom int64
+		ok       bool
+	}{
+		{100, 50, 50, true},
+		{100, 51, 50, false},
+		{100, 100, 0, true},
+		{0, 0, 0, true},
+		{10, 1, 10, false},
+	} {
+		err := checkSpace(tc.free, tc.size, tc.headroom)
+		if ok := err == nil; ok != tc.ok {
+			t.Errorf("checkSpace(%d, %d, %d) = %v, want ok %v", tc.free, tc.size, tc.headroom, err, tc.ok)
+		}
+		if err != nil && !errors.Is(err, ErrInsufficientSpace) {
+			t.Errorf("err = %v, want ErrInsufficientSpace", err)
+		}
+	}
+}
+
+func TestPreflightSpace(t *testing.T) {
+	defer func(fn func(string) (uint64, bool, error)) { diskFreeSpace = fn }(diskFreeSpace)
+	diskFreeSpace = func(string) (uint64, bool, error) { return 8, true, nil }
+
+	s, dir := newTestStorage(t, WithMinFreeSpace(4))
+	if _, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("hello")), "a.txt", 5); !errors.Is(err, ErrInsufficientSpace) {
+		t.Fatalf("err = %v, want ErrInsufficientSpace", err)
+	}
+	if names := listTree(t, dir); len(names) != 0 {
+		t.Errorf("storage tree = %v, want empty", names)
+	}
+	if _, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("hi")), "a.txt", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	diskFreeSpace = func(string) (uint64, bool, error) { return 0, false, nil }
+	if _, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("hello")), "a.txt", 5); err != nil {
+		t.Errorf("unsupported platform should skip the check: %v", err)
+	}
+}
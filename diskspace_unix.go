@@ -0,0 +1,15 @@
+//go:build linux || darwin || freebsd || dragonfly
+
+package fileupload
+
+import (
+	"syscall"
+)
+
+func platformDiskFreeSpace(directory string) (free uint64, ok bool, err error) {
+	var stat syscall.Statfs_t
+	if err = syscall.Statfs(directory, &stat); err != nil {
+		return
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), true, nil
+}
@@ -0,0 +1,23 @@
+//go:build windows
+
+package fileupload
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var procGetDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+func platformDiskFreeSpace(directory string) (free uint64, ok bool, err error) {
+	name, err := syscall.UTF16PtrFromString(directory)
+	if err != nil {
+		return
+	}
+	r, _, e := procGetDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(&free)), 0, 0)
+	if r == 0 {
+		err = e
+		return
+	}
+	return free, true, nil
+}
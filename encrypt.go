@@ -0,0 +1,221 @@
+package fileupload
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// encryptedSuffix 加密存储的文件名后缀
+const encryptedSuffix = ".enc"
+
+// 加密文件格式:
+// magic(4) | keyID长度(1) | keyID | nonce前缀(8) | 分块大小(4) | 分块...
+// 每个分块以 nonce前缀+分块序号(4) 作为nonce独立加密, 附加数据标记是否为最后一个分块以防止截断
+const (
+	encryptionMagic     = "FUE1"
+	encryptionChunkSize = 64 << 10
+	encryptionPrefixLen = 8
+)
+
+// encryption 加密存储配置
+type encryption struct {
+	keyID string            // 写入时使用的密钥id
+	keys  map[string][]byte // 全部密钥(读取时按文件头中的密钥id选择)
+}
+
+// WithEncryption 以AES-GCM分块加密存储文件内容, key 长度为16, 24或32字节, 文件名追加 .enc 后缀
+// 哈希值基于明文计算, 相同内容的去重不受加密影响; 通过 Open 读取时自动解密
+func WithEncryption(key []byte) Opts {
+	return WithEncryptionKeys("default", map[string][]byte{"default": key})
+}
+
+// WithEncryptionKeys 加密存储并支持密钥轮换, 新文件使用 keyID 对应的密钥加密, 文件头记录密钥id, 读取时按密钥id选择密钥
+func WithEncryptionKeys(keyID string, keys map[string][]byte) Opts {
+	return func(s *Storage) {
+		s.encryption = &encryption{
+			keyID: keyID,
+			keys:  keys,
+		}
+	}
+}
+
+func (e *encryption) aead(keyID string) (cipher.AEAD, error) {
+	key, ok := e.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key id %q", ErrDecryption, keyID)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// newWriter 创建加密写入器, 关闭时写入最后一个分块
+func (e *encryption) newWriter(w io.Writer) (io.WriteCloser, error) {
+	if len(e.keyID) > 255 {
+		return nil, fmt.Errorf("encryption key id too long")
+	}
+	aead, err := e.aead(e.keyID)
+	if err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, encryptionPrefixLen)
+	if _, err = rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	header := &bytes.Buffer{}
+	header.WriteString(encryptionMagic)
+	header.WriteByte(byte(len(e.keyID)))
+	header.WriteString(e.keyID)
+	header.Write(prefix)
+	_ = binary.Write(header, binary.BigEndian, uint32(encryptionChunkSize))
+	if _, err = w.Write(header.Bytes()); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{
+		w:      w,
+		aead:   aead,
+		prefix: prefix,
+		buf:    make([]byte, 0, encryptionChunkSize),
+	}, nil
+}
+
+type encryptWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	buf     []byte
+}
+
+func (e *encryptWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		// 缓冲区已满且仍有数据时, 当前分块不是最后一个分块
+		if len(e.buf) == encryptionChunkSize {
+			if err = e.seal(false); err != nil {
+				return
+			}
+		}
+		m := copy(e.buf[len(e.buf):encryptionChunkSize], p)
+		e.buf = e.buf[:len(e.buf)+m]
+		p = p[m:]
+		n += m
+	}
+	return
+}
+
+func (e *encryptWriter) Close() error {
+	return e.seal(true)
+}
+
+func (e *encryptWriter) seal(final bool) error {
+	nonce := chunkNonce(e.prefix, e.counter)
+	sealed := e.aead.Seal(nil, nonce, e.buf, chunkAdditionalData(final))
+	e.counter++
+	e.buf = e.buf[:0]
+	_, err := e.w.Write(sealed)
+	return err
+}
+
+// newReader 创建解密读取器
+func (e *encryption) newReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(encryptionMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != encryptionMagic {
+		return nil, fmt.Errorf("%w: invalid header", ErrDecryption)
+	}
+	idLen, err := br.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid header", ErrDecryption)
+	}
+	rest := make([]byte, int(idLen)+encryptionPrefixLen+4)
+	if _, err = io.ReadFull(br, rest); err != nil {
+		return nil, fmt.Errorf("%w: invalid header", ErrDecryption)
+	}
+	aead, err := e.aead(string(rest[:idLen]))
+	if err != nil {
+		return nil, err
+	}
+	chunkSize := binary.BigEndian.Uint32(rest[int(idLen)+encryptionPrefixLen:])
+	if chunkSize == 0 || chunkSize > 16<<20 {
+		return nil, fmt.Errorf("%w: invalid chunk size", ErrDecryption)
+	}
+	return &decryptReader{
+		r:      br,
+		aead:   aead,
+		prefix: rest[idLen : int(idLen)+encryptionPrefixLen],
+		sealed: make([]byte, int(chunkSize)+aead.Overhead()),
+	}, nil
+}
+
+type decryptReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	sealed  []byte
+	plain   []byte
+	done    bool
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+func (d *decryptReader) open() error {
+	n, err := io.ReadFull(d.r, d.sealed)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("%w: truncated", ErrDecryption)
+		}
+		return err
+	}
+	// 之后没有数据时为最后一个分块
+	final := false
+	if _, err = d.r.Peek(1); err != nil {
+		if !errors.Is(err, io.EOF) {
+			return err
+		}
+		final = true
+	}
+	plain, err := d.aead.Open(d.sealed[:0:0], chunkNonce(d.prefix, d.counter), d.sealed[:n], chunkAdditionalData(final))
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrDecryption, err.Error())
+	}
+	d.counter++
+	d.plain = plain
+	d.done = final
+	return nil
+}
+
+func chunkNonce(prefix []byte, counter uint32) []byte {
+	nonce := make([]byte, encryptionPrefixLen+4)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[encryptionPrefixLen:], counter)
+	return nonce
+}
+
+func chunkAdditionalData(final bool) []byte {
+	if final {
+		return []byte{1}
+	}
+	return []byte{0}
+}
@@ -0,0 +1,118 @@
+package fileupload
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// openAll 通过 Storage.Open 读取存储文件的全部内容
+func openAll(t *testing.T, s *Storage, result *FileStorageResult) string {
+	t.Helper()
+	rc, err := s.Open(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = rc.Close() }()
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestEncryptionRoundTrip(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	s, _ := newTestStorage(t, WithEncryption(key))
+	// 超过一个分块, 覆盖多分块的加解密
+	plaintext := strings.Repeat("confidential document ", 5000)
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte(plaintext)), "doc.txt", int64(len(plaintext)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Encrypted || !strings.HasSuffix(result.PathAbs, encryptedSuffix) {
+		t.Fatalf("result = %+v, want an encrypted .enc file", result)
+	}
+	stored := readFile(t, result.PathAbs)
+	if strings.Contains(stored, "confidential") {
+		t.Error("on-disk bytes contain the plaintext")
+	}
+	if got := openAll(t, s, result); got != plaintext {
+		t.Error("decrypted content differs from the plaintext")
+	}
+	hash, _, _ := s.HashReader(strings.NewReader(plaintext))
+	if result.Hash != hash {
+		t.Errorf("Hash = %s, want the plaintext hash %s", result.Hash, hash)
+	}
+	again, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte(plaintext)), "copy.txt", int64(len(plaintext)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.PathAbs != result.PathAbs || again.Created {
+		t.Errorf("same plaintext stored again at %s (created %v)", again.PathAbs, again.Created)
+	}
+}
+
+func TestEncryptionKeyRotation(t *testing.T) {
+	oldKey, newKey := bytes.Repeat([]byte{1}, 16), bytes.Repeat([]byte{2}, 16)
+	s, dir := newTestStorage(t, WithEncryptionKeys("old", map[string][]byte{"old": oldKey}))
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("secret")), "a.txt", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated := NewStorage(WithStorageDirectory(dir), WithEncryptionKeys("new", map[string][]byte{"old": oldKey, "new": newKey}))
+	if got := openAll(t, rotated, result); got != "secret" {
+		t.Errorf("content after rotation = %q", got)
+	}
+	missing := NewStorage(WithStorageDirectory(dir), WithEncryptionKeys("new", map[string][]byte{"new": newKey}))
+	if rc, err := missing.Open(result); err == nil {
+		_, err = io.ReadAll(rc)
+		_ = rc.Close()
+		if err == nil {
+			t.Error("decrypted without the original key")
+		}
+	}
+}
+
+func TestEncryptionKeyRotationReupload(t *testing.T) {
+	oldKey, newKey := bytes.Repeat([]byte{1}, 16), bytes.Repeat([]byte{2}, 16)
+	s, dir := newTestStorage(t, WithEncryptionKeys("k1", map[string][]byte{"k1": oldKey}))
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("secret")), "a.txt", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 密钥id长度不同, 加密后的文件大小随之不同
+	for _, opts := range [][]Opts{
+		{WithEncryptionKeys("rotated-key", map[string][]byte{"k1": oldKey, "rotated-key": newKey})},
+		{WithEncryptionKeys("rotated-key", map[string][]byte{"k1": oldKey, "rotated-key": newKey}), WithVerifyExisting(true)},
+	} {
+		rotated := NewStorage(append([]Opts{WithStorageDirectory(dir)}, opts...)...)
+		again, err := rotated.CopyMultipartFile(&FileStorage{}, openBytes([]byte("secret")), "b.txt", 6)
+		if err != nil {
+			t.Fatalf("re-upload after rotation: %v", err)
+		}
+		if again.PathAbs != result.PathAbs || again.Created {
+			t.Errorf("re-upload stored at %s (created %v), want the existing %s", again.PathAbs, again.Created, result.PathAbs)
+		}
+		if got := openAll(t, rotated, again); got != "secret" {
+			t.Errorf("content = %q", got)
+		}
+	}
+
+	// 已存在的文件解密后内容不同时仍为哈希冲突
+	other, _ := newTestStorage(t, WithEncryptionKeys("k1", map[string][]byte{"k1": oldKey}))
+	tampered, err := other.CopyMultipartFile(&FileStorage{}, openBytes([]byte("tampered")), "c.txt", 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Rename(tampered.PathAbs, result.PathAbs); err != nil {
+		t.Fatal(err)
+	}
+	rotated := NewStorage(WithStorageDirectory(dir), WithEncryptionKeys("rotated-key", map[string][]byte{"k1": oldKey, "rotated-key": newKey}))
+	if _, err = rotated.CopyMultipartFile(&FileStorage{}, openBytes([]byte("secret")), "b.txt", 6); !errors.Is(err, ErrHashCollision) {
+		t.Errorf("err = %v, want ErrHashCollision", err)
+	}
+}
@@ -0,0 +1,85 @@
+package fileupload
+
+import (
+	"errors"
+)
+
+var (
+	// ErrHashCollision 同名(相同哈希值)文件已存在但大小(或内容, 见 WithVerifyExisting)不一致
+	ErrHashCollision = errors.New("file with the same hash already exists but differs in size or content")
+
+	// ErrInsufficientSpace 存储目录所在文件系统可用空间不足
+	ErrInsufficientSpace = errors.New("insufficient disk space")
+
+	// ErrStorageClosed 存储已关闭
+	ErrStorageClosed = errors.New("storage closed")
+
+	// ErrDecryption 加密存储的文件解密失败
+	ErrDecryption = errors.New("decryption failed")
+
+	// ErrScanRejected 内容扫描拒绝存储
+	ErrScanRejected = errors.New("rejected by content scanner")
+
+	// ErrInvalidUploadID 分片上传id不合法
+	ErrInvalidUploadID = errors.New("invalid upload id")
+
+	// ErrChunkMissing 分片上传缺少分片
+	ErrChunkMissing = errors.New("missing upload chunk")
+
+	// ErrChunkLimitExceeded 分片上传的分片大小或分片数超出上限
+	ErrChunkLimitExceeded = errors.New("upload chunk limit exceeded")
+
+	// ErrQuotaExceeded 子目录已存储文件的总大小超出配额
+	ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+	// ErrDeclaredTypeNotAllowed 表单文件声明的类型不允许上传
+	ErrDeclaredTypeNotAllowed = errors.New("declared content type not allowed")
+
+	// ErrTotalSizeExceeded 单次请求内全部上传文件的总大小超出上限
+	ErrTotalSizeExceeded = errors.New("total upload size exceeded")
+
+	// ErrIncompleteUpload 读取的上传内容与声明的大小不一致(如客户端中途中止上传)
+	ErrIncompleteUpload = errors.New("incomplete upload")
+
+	// ErrRequestTooLarge 请求体大小超出上限
+	ErrRequestTooLarge = errors.New("request body too large")
+
+	// ErrFileTooLarge 单个文件的大小超出上限
+	ErrFileTooLarge = errors.New("file too large")
+
+	// ErrDecompressedTooLarge gzip压缩的上传内容解压后的大小超出上限(见 WithMaxDecompressedSize)
+	ErrDecompressedTooLarge = errors.New("decompressed content too large")
+
+	// ErrMissingExtension 文件没有后缀
+	ErrMissingExtension = errors.New("file has no extension")
+
+	// ErrNameCollision 同一请求内不同内容的文件使用了相同的存储路径
+	ErrNameCollision = errors.New("different files with the same name in one request")
+
+	// ErrFilenameTooLong 存储文件名超出最大长度(见 WithFilenameLengthStrict)
+	ErrFilenameTooLong = errors.New("file name too long")
+
+	// ErrInvalidFileName 自定义存储文件名不合法
+	ErrInvalidFileName = errors.New("invalid file name")
+
+	// ErrIntegrityMismatch 文件哈希值或大小与客户端声明的值不一致
+	ErrIntegrityMismatch = errors.New("integrity mismatch")
+
+	// ErrStripEXIFUnsupported 开启 WithStripEXIF 时上传了无法去除EXIF元数据的图片格式(如TIFF)
+	ErrStripEXIFUnsupported = errors.New("exif stripping not supported for this image format")
+
+	// ErrImageDimensions 图片宽高超出限制
+	ErrImageDimensions = errors.New("image dimensions out of range")
+
+	// ErrBatchRolledBack 开启 WithAtomicBatch 时批量存储中其它文件失败, 已存储的文件被回滚
+	ErrBatchRolledBack = errors.New("batch rolled back")
+
+	// ErrMirrorFailed 文件写入镜像文件系统失败(存储本身已成功)
+	ErrMirrorFailed = errors.New("mirror failed")
+
+	// ErrUnsafeZipEntry zip文件包含路径穿越或绝对路径的条目
+	ErrUnsafeZipEntry = errors.New("unsafe zip entry")
+
+	// ErrZipLimitExceeded zip文件的文件数或解压后大小超出上限
+	ErrZipLimitExceeded = errors.New("zip limit exceeded")
+)
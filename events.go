@@ -0,0 +1,37 @@
+package fileupload
+
+// WithOnStored 每个文件存储成功(已写入最终位置或复用已存储的相同内容)后调用, 批量存储时每完成一个文件调用一次
+func WithOnStored(fn func(result *FileStorageResult)) Opts {
+	return func(s *Storage) { s.onStored = fn }
+}
+
+// WithOnError 每个文件存储失败时调用, origin 为原始文件名
+func WithOnError(fn func(err error, origin string)) Opts {
+	return func(s *Storage) { s.onError = fn }
+}
+
+// notify 触发存储结果回调
+func (s *Storage) notify(result *FileStorageResult, err error) {
+	s.logResult(result, err)
+	observer := s.observe()
+	if err != nil {
+		observer.Failed(err)
+		if s.onError != nil {
+			origin := ""
+			if result != nil {
+				origin = result.OriginName
+			}
+			s.onError(err, origin)
+		}
+		return
+	}
+	if result.Created {
+		observer.BytesWritten(result.Size)
+	} else {
+		observer.Deduplicated(result)
+	}
+	observer.Stored(result)
+	if s.onStored != nil {
+		s.onStored(result)
+	}
+}
@@ -0,0 +1,48 @@
+package fileupload
+
+import (
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestEventCallbacks(t *testing.T) {
+	var (
+		mutex  sync.Mutex
+		stored []string
+		failed []string
+	)
+	s, _ := newTestStorage(t,
+		WithMaxFileSize(8),
+		WithOnStored(func(result *FileStorageResult) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			// 回调时文件已位于最终位置
+			if _, err := os.Stat(result.PathAbs); err != nil {
+				t.Errorf("OnStored before the file is on disk: %v", err)
+			}
+			stored = append(stored, result.OriginName)
+		}),
+		WithOnError(func(err error, origin string) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			failed = append(failed, origin)
+		}),
+	)
+	files := formFileHeaders(t,
+		testFile{field: "files", filename: "a.txt", content: "a"},
+		testFile{field: "files", filename: "b.txt", content: "b"},
+	)
+	if _, err := s.MultipartCopy(&FileStorage{}, files...); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("too large file")), "c.txt", 14); err == nil {
+		t.Fatal("expected a size error")
+	}
+	if len(stored) != 2 || stored[0] != "a.txt" || stored[1] != "b.txt" {
+		t.Errorf("OnStored calls = %v, want [a.txt b.txt]", stored)
+	}
+	if len(failed) != 1 || failed[0] != "c.txt" {
+		t.Errorf("OnError calls = %v, want [c.txt]", failed)
+	}
+}
@@ -0,0 +1,127 @@
+package fileupload
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// WithStripEXIF 去除JPEG图片中的EXIF(及XMP)元数据, 文件哈希值基于去除后的内容计算
+// TIFF图片的元数据位于图像目录(IFD)中无法可靠去除, 开启时拒绝存储并返回 ErrStripEXIFUnsupported(不受 WithStripEXIFStrict 影响)
+func WithStripEXIF(strip bool) Opts {
+	return func(s *Storage) { s.stripEXIF = strip }
+}
+
+// WithStripEXIFStrict 去除EXIF元数据失败(如图片损坏)时中止上传, 否则按原内容存储
+func WithStripEXIFStrict(strict bool) Opts {
+	return func(s *Storage) { s.stripEXIFStrict = strict }
+}
+
+var (
+	jpegMagic      = []byte{0xFF, 0xD8}
+	tiffMagicLE    = []byte{'I', 'I', 0x2A, 0x00}
+	tiffMagicBE    = []byte{'M', 'M', 0x00, 0x2A}
+	exifAPP1Prefix = []byte("Exif\x00\x00")
+	xmpAPP1Prefix  = []byte("http://ns.adobe.com/xap/1.0/\x00")
+)
+
+// stripEXIFReader 返回去除EXIF元数据后的内容, TIFF内容返回 ErrStripEXIFUnsupported, 其它内容原样返回
+func (s *Storage) stripEXIFReader(src io.Reader) (content io.Reader, err error) {
+	rec := &recorder{}
+	br := bufio.NewReader(io.TeeReader(src, rec))
+	magic, _ := br.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, jpegMagic):
+		content, err = stripJPEGEXIF(br)
+	case bytes.Equal(magic, tiffMagicLE), bytes.Equal(magic, tiffMagicBE):
+		// 按原内容存储会保留GPS等元数据, 因此总是拒绝
+		return nil, fmt.Errorf("%w: tiff", ErrStripEXIFUnsupported)
+	default:
+		content = br
+	}
+	if err == nil || s.stripEXIFStrict {
+		rec.stop()
+		return
+	}
+	// 非严格模式下按原内容存储
+	if s.logger != nil {
+		s.logger.Warn("strip exif failed, storing original content", "error", err)
+	}
+	content, err = io.MultiReader(&rec.buf, src), nil
+	rec.stop()
+	return
+}
+
+// recorder 记录解析过程中已读取的源内容, 用于解析失败时回退
+type recorder struct {
+	buf     bytes.Buffer
+	stopped bool
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	if !r.stopped {
+		r.buf.Write(p)
+	}
+	return len(p), nil
+}
+
+func (r *recorder) stop() {
+	r.stopped = true
+}
+
+// stripJPEGEXIF 解析JPEG图像数据(SOS)之前的段, 丢弃APP1中的EXIF及XMP段
+func stripJPEGEXIF(br *bufio.Reader) (io.Reader, error) {
+	header := &bytes.Buffer{}
+	soi := make([]byte, 2)
+	if _, err := io.ReadFull(br, soi); err != nil {
+		return nil, err
+	}
+	header.Write(soi)
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b != 0xFF {
+			return nil, fmt.Errorf("strip exif: invalid jpeg marker 0x%02x", b)
+		}
+		marker := byte(0xFF)
+		for marker == 0xFF {
+			if marker, err = br.ReadByte(); err != nil {
+				return nil, err
+			}
+		}
+		// 无长度字段的标记
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD9) {
+			header.Write([]byte{0xFF, marker})
+			if marker == 0xD9 {
+				return io.MultiReader(header, br), nil
+			}
+			continue
+		}
+		length := make([]byte, 2)
+		if _, err = io.ReadFull(br, length); err != nil {
+			return nil, err
+		}
+		size := int(binary.BigEndian.Uint16(length))
+		if size < 2 {
+			return nil, fmt.Errorf("strip exif: invalid jpeg segment length %d", size)
+		}
+		payload := make([]byte, size-2)
+		if _, err = io.ReadFull(br, payload); err != nil {
+			return nil, err
+		}
+		if marker == 0xE1 && (bytes.HasPrefix(payload, exifAPP1Prefix) || bytes.HasPrefix(payload, xmpAPP1Prefix)) {
+			continue
+		}
+		header.Write([]byte{0xFF, marker})
+		header.Write(length)
+		header.Write(payload)
+		// SOS 之后为图像数据, 原样输出
+		if marker == 0xDA {
+			return io.MultiReader(header, br), nil
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package fileupload
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/jpeg"
+	"testing"
+)
+
+// gpsMarker 测试用EXIF段中的GPS数据
+const gpsMarker = "GPS 37.7749N 122.4194W"
+
+// jpegWithEXIF 生成在SOI之后带有EXIF段(含GPS数据)的JPEG图片
+func jpegWithEXIF(t *testing.T) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	if err := jpeg.Encode(buf, image.NewGray(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatal(err)
+	}
+	payload := append(append([]byte{}, exifAPP1Prefix...), []byte("II*\x00"+gpsMarker)...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+	encoded := buf.Bytes()
+	return append(append(append([]byte{}, encoded[:2]...), segment...), encoded[2:]...)
+}
+
+func TestStripEXIF(t *testing.T) {
+	s, _ := newTestStorage(t, WithStripEXIF(true))
+	content := jpegWithEXIF(t)
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes(content), "photo.jpg", int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := readFile(t, result.PathAbs)
+	if bytes.Contains([]byte(stored), []byte(gpsMarker)) {
+		t.Error("stored file still contains the GPS data")
+	}
+	if _, err = jpeg.Decode(bytes.NewReader([]byte(stored))); err != nil {
+		t.Errorf("stored file is not a valid jpeg: %v", err)
+	}
+	hash, size, err := s.HashReader(bytes.NewReader([]byte(stored)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Hash != hash || result.Size != size {
+		t.Errorf("result hash/size = %s/%d, want the stripped content %s/%d", result.Hash, result.Size, hash, size)
+	}
+}
+
+func TestStripEXIFDisabled(t *testing.T) {
+	s, _ := newTestStorage(t)
+	content := jpegWithEXIF(t)
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes(content), "photo.jpg", int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains([]byte(readFile(t, result.PathAbs)), []byte(gpsMarker)) {
+		t.Error("metadata removed although stripping is disabled")
+	}
+}
+
+func TestStripEXIFRejectsTIFF(t *testing.T) {
+	s, _ := newTestStorage(t, WithStripEXIF(true))
+	content := []byte("II*\x00" + gpsMarker)
+	_, err := s.CopyMultipartFile(&FileStorage{}, openBytes(content), "scan.tiff", int64(len(content)))
+	if !errors.Is(err, ErrStripEXIFUnsupported) {
+		t.Fatalf("err = %v, want ErrStripEXIFUnsupported", err)
+	}
+}
@@ -0,0 +1,127 @@
+package fileupload
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// WithDefaultExtension 原始文件名没有后缀(如 "README")时使用的文件后缀, 如 ".bin"
+func WithDefaultExtension(ext string) Opts {
+	return func(s *Storage) {
+		if ext != "" && !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		s.defaultExtension = ext
+	}
+}
+
+// WithExtensionFromContentType 原始文件名没有后缀时根据检测到的内容类型确定后缀(如 image/png 使用 ".png"), 无法确定时使用 WithDefaultExtension
+func WithExtensionFromContentType(enable bool) Opts {
+	return func(s *Storage) { s.extensionFromContentType = enable }
+}
+
+// WithRequireExtension 拒绝存储最终仍没有后缀的文件, 返回 ErrMissingExtension
+func WithRequireExtension(require bool) Opts {
+	return func(s *Storage) { s.requireExtension = require }
+}
+
+// WithPreserveExtensionCase 保留原始文件名后缀的大小写, 默认统一转换为小写(如 "PHOTO.JPG" 存储为 "哈希值.jpg")
+func WithPreserveExtensionCase(preserve bool) Opts {
+	return func(s *Storage) { s.preserveExtensionCase = preserve }
+}
+
+// WithMimeExtensions 内容类型(如 "image/svg+xml")对应的文件后缀(如 ".svg"), 覆盖或补充默认的对应关系
+// 用于base64 data URI声明的类型及 WithExtensionFromContentType
+func WithMimeExtensions(extensions map[string]string) Opts {
+	return func(s *Storage) {
+		if s.mimeExtensions == nil {
+			s.mimeExtensions = make(map[string]string, len(extensions))
+		}
+		for mediaType, ext := range extensions {
+			if ext != "" && !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			s.mimeExtensions[strings.ToLower(mediaType)] = ext
+		}
+	}
+}
+
+// contentTypeExtensions 内容类型(http.DetectContentType 的检测结果及常见的声明类型)对应的文件后缀
+var contentTypeExtensions = map[string]string{
+	"image/jpeg":               ".jpg",
+	"image/jpg":                ".jpg",
+	"image/pjpeg":              ".jpg",
+	"image/svg+xml":            ".svg",
+	"image/tiff":               ".tiff",
+	"image/avif":               ".avif",
+	"image/heic":               ".heic",
+	"image/heif":               ".heif",
+	"image/vnd.microsoft.icon": ".ico",
+	"image/png":                ".png",
+	"image/gif":                ".gif",
+	"image/webp":               ".webp",
+	"image/bmp":                ".bmp",
+	"image/x-icon":             ".ico",
+	"application/pdf":          ".pdf",
+	"application/zip":          ".zip",
+	"application/x-gzip":       ".gz",
+	"application/wasm":         ".wasm",
+	"audio/mpeg":               ".mp3",
+	"audio/wave":               ".wav",
+	"audio/aiff":               ".aiff",
+	"audio/midi":               ".mid",
+	"application/ogg":          ".ogg",
+	"video/mp4":                ".mp4",
+	"video/webm":               ".webm",
+	"video/avi":                ".avi",
+	"font/woff":                ".woff",
+	"font/woff2":               ".woff2",
+	"font/ttf":                 ".ttf",
+	"text/plain":               ".txt",
+	"text/html":                ".html",
+	"text/xml":                 ".xml",
+}
+
+// mimeExtension 内容类型对应的文件后缀, 没有对应关系时使用子类型(去除 + 之后的后缀, 如 "image/x-foo+xml" 使用 ".x-foo")
+func (s *Storage) mimeExtension(mediaType string) string {
+	mediaType = strings.ToLower(mediaType)
+	if ext, ok := s.mimeExtensions[mediaType]; ok {
+		return ext
+	}
+	if ext, ok := contentTypeExtensions[mediaType]; ok {
+		return ext
+	}
+	_, subtype, _ := strings.Cut(mediaType, "/")
+	subtype, _, _ = strings.Cut(subtype, "+")
+	if subtype == "" {
+		return ""
+	}
+	return "." + subtype
+}
+
+// resolveExtension 统一文件后缀的大小写, 原始文件名没有后缀时确定文件后缀
+func (s *Storage) resolveExtension(result *FileStorageResult) error {
+	if !s.preserveExtensionCase {
+		result.FileExt = strings.ToLower(result.FileExt)
+	}
+	if result.FileExt != "" {
+		return nil
+	}
+	if s.extensionFromContentType {
+		if mediaType, _, err := mime.ParseMediaType(result.FinalContentType); err == nil {
+			if ext, ok := s.mimeExtensions[mediaType]; ok {
+				result.FileExt = ext
+			} else {
+				result.FileExt = contentTypeExtensions[mediaType]
+			}
+		}
+	}
+	if result.FileExt == "" {
+		result.FileExt = s.defaultExtension
+	}
+	if result.FileExt == "" && s.requireExtension {
+		return fmt.Errorf("%w: %s", ErrMissingExtension, result.OriginName)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package fileupload
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestDefaultExtension(t *testing.T) {
+	content := []byte("# readme\n")
+	for _, tc := range []struct {
+		name string
+		opts []Opts
+		ext  string
+	}{
+		{"none", nil, ""},
+		{"default", []Opts{WithDefaultExtension("bin")}, ".bin"},
+		{"content type", []Opts{WithDefaultExtension(".bin"), WithExtensionFromContentType(true)}, ".txt"},
+	} {
+		s, _ := newTestStorage(t, tc.opts...)
+		result, err := s.CopyMultipartFile(&FileStorage{}, openBytes(content), "README", int64(len(content)))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if result.FileExt != tc.ext || filepath.Ext(result.PathAbs) != tc.ext {
+			t.Errorf("%s: FileExt = %q, PathAbs = %s, want %q", tc.name, result.FileExt, result.PathAbs, tc.ext)
+		}
+	}
+}
+
+func TestRequireExtension(t *testing.T) {
+	s, _ := newTestStorage(t, WithRequireExtension(true))
+	_, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("x")), "README", 1)
+	if !errors.Is(err, ErrMissingExtension) {
+		t.Fatalf("err = %v, want ErrMissingExtension", err)
+	}
+	s, _ = newTestStorage(t, WithRequireExtension(true), WithDefaultExtension(".md"))
+	if _, err = s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("x")), "README", 1); err != nil {
+		t.Errorf("default extension did not satisfy the requirement: %v", err)
+	}
+}
+
+func TestMimeExtensions(t *testing.T) {
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`)
+	jpeg := []byte{0xFF, 0xD8, 0xFF, 0xE0}
+	for _, tc := range []struct {
+		opts        []Opts
+		contentType string
+		content     []byte
+		ext         string
+	}{
+		{nil, "image/svg+xml", svg, ".svg"},
+		{nil, "image/jpeg", jpeg, ".jpg"},
+		{nil, "image/x-icon", []byte{0, 0, 1, 0}, ".ico"},
+		{nil, "image/x-custom+xml", svg, ".x-custom"},
+		{[]Opts{WithMimeExtensions(map[string]string{"IMAGE/JPEG": "jpeg"})}, "image/jpeg", jpeg, ".jpeg"},
+	} {
+		s, _ := newTestStorage(t, tc.opts...)
+		results, err := s.Base64Copy(&FileStorage{}, [][]byte{dataURI(tc.contentType, tc.content)})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.contentType, err)
+		}
+		if results[0].FileExt != tc.ext || filepath.Ext(results[0].Name) != tc.ext {
+			t.Errorf("%s: FileExt = %q, Name = %s, want %q", tc.contentType, results[0].FileExt, results[0].Name, tc.ext)
+		}
+	}
+}
+
+func TestExtensionCase(t *testing.T) {
+	s, _ := newTestStorage(t)
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("png")), "IMAGE.PNG", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.FileExt != ".png" || result.Name != result.Hash+".png" || filepath.Base(result.PathAbs) != result.Name {
+		t.Errorf("FileExt = %s, Name = %s, want %s.png", result.FileExt, result.Name, result.Hash)
+	}
+	if result.OriginName != "IMAGE.PNG" {
+		t.Errorf("OriginName = %s, want the original case", result.OriginName)
+	}
+
+	s, _ = newTestStorage(t, WithPreserveExtensionCase(true))
+	if result, err = s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("png")), "IMAGE.PNG", 3); err != nil {
+		t.Fatal(err)
+	}
+	if result.Name != result.Hash+".PNG" {
+		t.Errorf("preserved Name = %s, want %s.PNG", result.Name, result.Hash)
+	}
+}
@@ -0,0 +1,52 @@
+package fileupload
+
+import (
+	"encoding/hex"
+	"hash"
+	"io"
+)
+
+// WithExtraHashes 存储时在计算文件哈希值的同一次读取中额外计算的摘要, 键为摘要名称(如 "md5"), 值为摘要构造函数(如 md5.New)
+// 摘要以小写十六进制编码记录于存储结果的 Hashes, 不影响文件命名及内容复用
+func WithExtraHashes(hashes map[string]func() hash.Hash) Opts {
+	return func(s *Storage) { s.extraHashes = hashes }
+}
+
+// extraDigests 额外计算的摘要
+type extraDigests map[string]hash.Hash
+
+// newExtraDigests 创建额外计算的摘要, 未配置 WithExtraHashes 或不需要填充哈希值(见 WithResultFields)时返回nil
+func (s *Storage) newExtraDigests() extraDigests {
+	if len(s.extraHashes) == 0 || !s.wants(ResultHash) {
+		return nil
+	}
+	digests := make(extraDigests, len(s.extraHashes))
+	for name, fn := range s.extraHashes {
+		digests[name] = fn()
+	}
+	return digests
+}
+
+// tee 读取 r 的同时计算全部摘要
+func (d extraDigests) tee(r io.Reader) io.Reader {
+	if len(d) == 0 {
+		return r
+	}
+	writers := make([]io.Writer, 0, len(d))
+	for _, h := range d {
+		writers = append(writers, h)
+	}
+	return io.TeeReader(r, io.MultiWriter(writers...))
+}
+
+// sums 全部摘要的十六进制编码
+func (d extraDigests) sums() map[string]string {
+	if len(d) == 0 {
+		return nil
+	}
+	sums := make(map[string]string, len(d))
+	for name, h := range d {
+		sums[name] = hex.EncodeToString(h.Sum(nil))
+	}
+	return sums
+}
@@ -0,0 +1,32 @@
+package fileupload
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"hash"
+	"testing"
+)
+
+func TestExtraHashes(t *testing.T) {
+	s, _ := newTestStorage(t, WithExtraHashes(map[string]func() hash.Hash{"md5": md5.New, "sha256": sha256.New}))
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("abc")), "abc.txt", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const sha = "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	if result.Hashes["md5"] != "900150983cd24fb0d6963f7d28e17f72" || result.Hashes["sha256"] != sha {
+		t.Errorf("Hashes = %v", result.Hashes)
+	}
+	if result.Hash != sha || result.Name != sha+".txt" {
+		t.Errorf("primary hash = %s, Name = %s, want naming by sha256", result.Hash, result.Name)
+	}
+
+	// 复用已存储的相同内容时同样记录
+	again, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("abc")), "again.txt", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Created || again.Hashes["md5"] != result.Hashes["md5"] {
+		t.Errorf("deduplicated Hashes = %v (created %v)", again.Hashes, again.Created)
+	}
+}
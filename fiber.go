@@ -0,0 +1,48 @@
+//go:build fiber
+
+package fileupload
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Fiber 文件上传fiber, 需使用构建标签 fiber 编译(go build -tags fiber), 语义与 HTTP 一致
+// 表单由本库按 WithMaxRequestBodySize, WithFormMaxMemory, WithFormTempDirectory 解析(不使用fiber的表单解析), 存储完成后清理表单临时文件
+// 请求体大小同时受fiber的 BodyLimit 限制; 开启fiber的 StreamRequestBody 时流式读取请求体
+func (s *Storage) Fiber(c *fiber.Ctx, param *FileStorage, name *MultipartFileName) (succeeded []*FileStorageResult, err error) {
+	if name == nil || name.empty() {
+		return
+	}
+	r, err := fiberRequest(c)
+	if err != nil {
+		return
+	}
+	form, err := s.parseForm(r, param)
+	if err != nil {
+		return
+	}
+	defer s.removeForm(form)
+	return s.formCopy(c.UserContext(), form, param, name)
+}
+
+// fiberRequest 以fiber请求的方法, 内容类型及请求体构造 http.Request, 用于按本库的配置解析表单
+func fiberRequest(c *fiber.Ctx) (*http.Request, error) {
+	var body io.Reader = bytes.NewReader(c.Body())
+	stream := c.Context().RequestBodyStream()
+	if stream != nil {
+		body = stream
+	}
+	r, err := http.NewRequestWithContext(c.UserContext(), c.Method(), c.OriginalURL(), body)
+	if err != nil {
+		return nil, err
+	}
+	if stream != nil {
+		r.ContentLength = int64(c.Request().Header.ContentLength())
+	}
+	r.Header.Set("Content-Type", string(c.Request().Header.ContentType()))
+	return r, nil
+}
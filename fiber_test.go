@@ -0,0 +1,101 @@
+//go:build fiber
+
+package fileupload
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// fiberUpload 通过fiber处理上传请求, 返回存储结果及错误
+func fiberUpload(t *testing.T, s *Storage, r *http.Request, name *MultipartFileName) ([]*FileStorageResult, error) {
+	t.Helper()
+	var (
+		results []*FileStorageResult
+		err     error
+	)
+	app := fiber.New()
+	app.Post("/upload", func(c *fiber.Ctx) error {
+		results, err = s.Fiber(c, &FileStorage{}, name)
+		// 请求结束后fiber回收上下文, 存储结果需在处理函数中复制
+		encoded, _ := json.Marshal(results)
+		results = nil
+		_ = json.Unmarshal(encoded, &results)
+		return nil
+	})
+	resp, rer := app.Test(r, -1)
+	if rer != nil {
+		t.Fatal(rer)
+	}
+	_ = resp.Body.Close()
+	return results, err
+}
+
+func TestFiberMultipleFields(t *testing.T) {
+	s, _ := newTestStorage(t)
+	r := multipartRequest(t, []testFile{
+		{field: "avatar", filename: "me.txt", content: "avatar"},
+		{field: "files", filename: "a.txt", content: "a"},
+		{field: "files", filename: "b.txt", content: "b"},
+	})
+	results, err := fiberUpload(t, s, r, &MultipartFileName{Single: "avatar", Multiple: "files"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]string{{"avatar", "me.txt"}, {"files", "a.txt"}, {"files", "b.txt"}}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+	for i, v := range want {
+		if results[i].Field != v[0] || results[i].OriginName != v[1] {
+			t.Errorf("result %d = %s %s, want %s %s", i, results[i].Field, results[i].OriginName, v[0], v[1])
+		}
+	}
+}
+
+func TestFiberMissingSingleField(t *testing.T) {
+	s, _ := newTestStorage(t)
+	files := []testFile{{field: "files", filename: "a.txt", content: "a"}}
+	_, err := fiberUpload(t, s, multipartRequest(t, files), &MultipartFileName{Single: "avatar", Multiple: "files"})
+	if !errors.Is(err, http.ErrMissingFile) {
+		t.Errorf("required field: err = %v, want http.ErrMissingFile", err)
+	}
+	results, err := fiberUpload(t, s, multipartRequest(t, files), &MultipartFileName{Single: "avatar", SingleOptional: true, Multiple: "files"})
+	if err != nil {
+		t.Fatalf("optional field: %v", err)
+	}
+	if len(results) != 1 || results[0].Field != "files" {
+		t.Errorf("results = %+v, want only the multiple field", results)
+	}
+}
+
+func TestFiberFormLimits(t *testing.T) {
+	files := []testFile{{field: "files", filename: "a.txt", content: strings.Repeat("a", 4096)}}
+	name := &MultipartFileName{Multiple: "files"}
+
+	s, dir := newTestStorage(t, WithMaxRequestBodySize(1024))
+	if _, err := fiberUpload(t, s, multipartRequest(t, files), name); !errors.Is(err, ErrRequestTooLarge) {
+		t.Errorf("err = %v, want ErrRequestTooLarge", err)
+	}
+	if got := countFiles(t, dir); got != 0 {
+		t.Errorf("%d files stored from an oversized body", got)
+	}
+
+	// 超出表单内存上限的文件写入表单临时目录, 存储完成后删除
+	tempDir := t.TempDir()
+	observer := &tempDirObserver{dir: tempDir}
+	s, _ = newTestStorage(t, WithFormTempDirectory(tempDir), WithFormMaxMemory(1024), WithObserver(observer))
+	results, err := fiberUpload(t, s, multipartRequest(t, files), name)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("results = %v, %v", results, err)
+	}
+	if observer.spooled != 1 {
+		t.Errorf("%d temp files while storing, want the file spooled to the form temp directory", observer.spooled)
+	}
+	assertEmptyDir(t, tempDir)
+}
@@ -0,0 +1,57 @@
+package fileupload
+
+// ResultField 存储结果字段掩码
+type ResultField uint32
+
+const (
+	ResultPathAbs       ResultField = 1 << iota // 文件存储绝对路径
+	ResultPathRlt                               // 文件存储相对路径
+	ResultHash                                  // 文件哈希值
+	ResultRawOriginName                         // 原始文件名(客户端提交的原值)
+	ResultDimensions                            // 图片宽高
+	ResultThumbnail                             // 缩略图
+	ResultContentType                           // 内容类型(DetectedContentType, FinalContentType, ContentType)
+
+	ResultAll ResultField = 1<<iota - 1 // 全部字段
+)
+
+// WithResultFields 仅计算并填充指定的存储结果字段, 未指定的字段保持零值; 默认填充全部字段
+// 例如不需要 ResultDimensions 及 ResultThumbnail 时可跳过图片解码, 不需要 ResultContentType 时跳过源内容的类型检测
+// 存储过程本身依赖的值(如用于文件命名的哈希值, 用于资源分类的内容类型)仍会计算, 仅在返回前清空
+func WithResultFields(mask ResultField) Opts {
+	return func(s *Storage) { s.resultFields = mask }
+}
+
+// wants 是否需要填充指定的存储结果字段
+func (s *Storage) wants(field ResultField) bool {
+	return s.resultFields == 0 || s.resultFields&field != 0
+}
+
+// trimResult 清空未要求填充的存储结果字段
+func (s *Storage) trimResult(result *FileStorageResult) {
+	if s.resultFields == 0 {
+		return
+	}
+	for _, v := range result.Links {
+		s.trimResult(v)
+	}
+	if !s.wants(ResultPathAbs) {
+		result.PathAbs = ""
+	}
+	if !s.wants(ResultPathRlt) {
+		result.PathRlt = ""
+	}
+	if !s.wants(ResultHash) {
+		result.Hash = ""
+		result.HashEncoding = ""
+		result.Hashes = nil
+	}
+	if !s.wants(ResultRawOriginName) {
+		result.RawOriginName = ""
+	}
+	if !s.wants(ResultContentType) {
+		result.DetectedContentType = ""
+		result.FinalContentType = ""
+		result.ContentType = ""
+	}
+}
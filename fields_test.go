@@ -0,0 +1,60 @@
+package fileupload
+
+import (
+	"testing"
+)
+
+func TestResultFields(t *testing.T) {
+	s, _ := newTestStorage(t, WithImageProcessing(16, 16), WithResultFields(ResultPathRlt))
+	content := pngBytes(t, 64, 32)
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes(content), "photo.png", int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.PathRlt == "" || result.Name == "" || result.PathUri == "" {
+		t.Errorf("requested or always populated fields are empty: %+v", result)
+	}
+	if result.PathAbs != "" || result.Hash != "" || result.HashEncoding != "" || result.RawOriginName != "" {
+		t.Errorf("skipped path/hash fields are populated: %+v", result)
+	}
+	if result.Width != 0 || result.Height != 0 || result.ThumbnailUri != "" {
+		t.Errorf("skipped image fields are populated: %+v", result)
+	}
+	if result.DetectedContentType != "" || result.FinalContentType != "" || result.ContentType != "" {
+		t.Errorf("skipped content type fields are populated: %+v", result)
+	}
+}
+
+func TestResultFieldsDefault(t *testing.T) {
+	s, _ := newTestStorage(t, WithImageProcessing(16, 16))
+	content := pngBytes(t, 64, 32)
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes(content), "photo.png", int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.PathAbs == "" || result.Hash == "" || result.Width != 64 || result.ThumbnailUri == "" || result.ContentType != "image/png" {
+		t.Errorf("default result is missing fields: %+v", result)
+	}
+}
+
+func BenchmarkResultFields(b *testing.B) {
+	content := pngBytes(b, 512, 512)
+	for _, bc := range []struct {
+		name string
+		mask ResultField
+	}{
+		{"all", ResultAll},
+		{"paths", ResultPathRlt},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			s := NewStorage(WithStorageDirectory(b.TempDir()), WithImageProcessing(128, 128), WithResultFields(bc.mask))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := s.CopyMultipartFile(&FileStorage{}, openBytes(content), "photo.png", int64(len(content))); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package fileupload
+
+import (
+	"fmt"
+	"os"
+)
+
+// storedFileMode 新建存储文件的默认权限(创建时受 umask 影响), 与 os.Create 一致
+const storedFileMode os.FileMode = 0666
+
+// WithFileMode 存储文件的权限(如 0644), 在临时文件重命名至目标位置前设置, 不受 umask 影响; 默认为 0666 去除 umask 后的权限
+// 文件系统(见 WithFileSystem)需支持修改文件权限, 否则存储失败
+func WithFileMode(mode os.FileMode) Opts {
+	return func(s *Storage) { s.fileMode = mode.Perm() }
+}
+
+// chmodTemp 将写入完成的临时文件设置为配置的存储文件权限, 未配置时保持创建时的权限
+func (u *upload) chmodTemp(name string) error {
+	if u.fileMode == 0 {
+		return nil
+	}
+	fs, ok := u.fs.(chmoder)
+	if !ok {
+		return fmt.Errorf("file mode: file system does not support chmod")
+	}
+	return fs.Chmod(name, u.fileMode)
+}
@@ -0,0 +1,61 @@
+package fileupload
+
+import (
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxFilenameLength 默认清理后原始文件名及存储文件名的最大字节数
+const maxFilenameLength = 255
+
+// FilenameSanitizer 清理客户端提交的原始文件名(已去除路径部分), 返回值作为存储结果的 OriginName
+type FilenameSanitizer func(name string) string
+
+// WithFilenameSanitizer 自定义原始文件名清理, 默认使用 DefaultFilenameSanitizer; 客户端提交的原值保留在 RawOriginName
+func WithFilenameSanitizer(sanitizer FilenameSanitizer) Opts {
+	return func(s *Storage) { s.filenameSanitizer = sanitizer }
+}
+
+// DefaultFilenameSanitizer 默认原始文件名清理: NFC规范化, 去除控制字符(如换行, NUL)及双向文本控制字符,
+// 去除首尾空白, 超出255字节时保留后缀截断
+func DefaultFilenameSanitizer(name string) string {
+	name = norm.NFC.String(strings.ToValidUTF8(name, ""))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || unicode.Is(unicode.Bidi_Control, r) {
+			return -1
+		}
+		return r
+	}, name)
+	return truncateFilename(strings.TrimSpace(name), maxFilenameLength)
+}
+
+// truncateFilename 将文件名截断至不超过 limit 字节, 尽量保留后缀且不截断多字节字符
+func truncateFilename(name string, limit int) string {
+	if len(name) <= limit {
+		return name
+	}
+	ext := path.Ext(name)
+	if len(ext) > limit/2 {
+		ext = ""
+	}
+	base := strings.TrimSuffix(name, ext)[:limit-len(ext)]
+	for len(base) > 0 && !utf8.ValidString(base) {
+		base = base[:len(base)-1]
+	}
+	return base + ext
+}
+
+// sanitizeFilename 清理客户端提交的文件名, 去除路径部分
+func (s *Storage) sanitizeFilename(name string) string {
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	if s.filenameSanitizer != nil {
+		return s.filenameSanitizer(name)
+	}
+	return DefaultFilenameSanitizer(name)
+}
@@ -0,0 +1,41 @@
+package fileupload
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestDefaultFilenameSanitizer(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want string
+	}{
+		{"report\r\nSet-Cookie: x.pdf", "reportSet-Cookie: x.pdf"},
+		{"holiday 🏖️.jpg", "holiday 🏖️.jpg"},
+		{"nul\x00byte.txt", "nulbyte.txt"},
+		{"  padded.txt \t", "padded.txt"},
+		{"e\u0301.txt", "\u00e9.txt"},
+		{"evil\u202egnp.exe", "evilgnp.exe"},
+	} {
+		if got := DefaultFilenameSanitizer(tc.in); got != tc.want {
+			t.Errorf("DefaultFilenameSanitizer(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+	long := DefaultFilenameSanitizer(strings.Repeat("😀", 100) + ".png")
+	if len(long) > maxFilenameLength || !strings.HasSuffix(long, ".png") || !utf8.ValidString(long) {
+		t.Errorf("long name = %q (%d bytes), want a valid name of at most %d bytes keeping .png", long, len(long), maxFilenameLength)
+	}
+}
+
+func TestFilenameSanitizerOption(t *testing.T) {
+	s, _ := newTestStorage(t, WithFilenameSanitizer(strings.ToUpper))
+	raw := "dir/crlf\r\n🙂.txt"
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("x")), raw, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.OriginName != "CRLF\r\n🙂.TXT" || result.RawOriginName != raw {
+		t.Errorf("OriginName = %q, RawOriginName = %q", result.OriginName, result.RawOriginName)
+	}
+}
@@ -2,17 +2,18 @@ package fileupload
 
 import (
 	"bytes"
-	"crypto/sha256"
-	"encoding/base64"
-	"encoding/hex"
+	"compress/gzip"
+	"context"
 	"fmt"
+	"hash"
 	"io"
 	"mime/multipart"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
-	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -21,6 +22,115 @@ import (
 type Storage struct {
 	storageDirectory string // 存储目录
 	uriAccessPrefix  string // 资源访问前缀
+	noLeadingSlash   bool   // 资源访问路径不以 / 开头
+
+	uriBuilder func(result *FileStorageResult) (string, error) // 自定义资源访问路径
+
+	dimensionLimits *dimensionLimits   // 图片宽高限制
+	imageProcessing *ImageProcessing   // 图片处理参数
+	derivatives     chan struct{}      // 衍生文件生成任务配额
+	maxImagePixels  int64              // 完整解码图片的像素数上限
+	perceptualHash  bool               // 计算图片感知哈希
+	categorizer     Categorizer        // 资源分类
+	compression     *CompressionPolicy // 压缩存储策略
+	encryption      *encryption        // 加密存储配置
+	scanner         Scanner            // 内容扫描(如病毒扫描)
+	sidecarMetadata bool               // 写入存储结果的元数据文件
+
+	onStored func(result *FileStorageResult) // 文件存储成功回调
+	onError  func(err error, origin string)  // 文件存储失败回调
+
+	chunkDirectory string     // 分片上传的分片暂存目录
+	maxChunkSize   int64      // 单个分片的大小上限
+	maxChunks      int        // 单次分片上传的分片数上限
+	tempDirectory  string     // 临时文件目录
+	fs             FileSystem // 文件系统
+
+	mirrors    []FileSystem // 镜像文件系统
+	mirrorMode MirrorMode   // 镜像写入方式
+
+	mutex           sync.Mutex     // 保护生命周期状态
+	lifecycle       lifecycle      // 生命周期状态
+	existingPolicy  ExistingPolicy // 同名文件已存在时的处理策略
+	verifyExisting  bool           // 同名文件已存在时比较文件内容
+	stripEXIF       bool           // 去除JPEG图片EXIF元数据
+	stripEXIFStrict bool           // 去除EXIF元数据失败时中止上传
+	converter       Converter      // 存储前转换上传内容的格式
+
+	readOnlyAfterWrite bool        // 存储完成后去除文件的写权限
+	sync               bool        // 关闭写入的文件前刷新至存储介质
+	fileMode           os.FileMode // 存储文件的权限, 为0时使用默认权限
+
+	decompressOnUpload  bool  // 存储前解压gzip压缩的上传内容
+	maxDecompressedSize int64 // 解压后的大小上限
+
+	concurrency int        // 批量存储时的最大并发数
+	locker      pathLocker // 文件路径锁
+
+	resultFields ResultField      // 需要填充的存储结果字段
+	location     *time.Location   // 日期子目录使用的时区
+	clock        func() time.Time // 获取当前时间
+
+	nameShardingDepth int          // 按原始文件名首字符分片的目录层数
+	contentIndex      ContentIndex // 内容索引
+	dedupVerify       bool         // 内容索引命中时在文件锁内再次确认文件存在
+	dedupScope        DedupScope   // 相同内容的复用范围
+	refCounter        RefCounter   // 已存储文件的引用计数
+	scanned           scannedIndex // 扫描存储目录建立的内存索引
+	requestDedup      RequestDedup // 单次请求内重复内容的处理方式
+
+	minFreeSpace   int64 // 写入后需保留的最小可用空间
+	checkFreeSpace bool  // 写入前检查可用空间
+
+	idGenerator func() int64 // 存储结果 Uid 生成函数
+	ids         idSequence   // 默认 Uid 生成器
+
+	hashEncoding  HashEncoding // 文件哈希值的编码方式
+	declaredTypes []string     // 允许上传的表单文件声明类型
+	maxTotalSize  int64        // 单次请求内全部上传文件的总大小上限
+	formMaxMemory int64        // 解析表单时内存中保存的最大字节数
+	keepFormTemp  string       // 复制保留表单上传文件的目录
+	formTempDir   string       // 表单上传文件超出内存上限时写入的目录
+	formParts     sync.Map     // 本库解析的表单上传文件内容(见 WithFormTempDirectory)
+	atomicBatch   bool         // 批量存储失败时删除本次已新写入的文件
+
+	maxRequestBodySize int64 // 请求体大小上限
+
+	base64Parser Base64Parser // base64(data URI)头部解析
+
+	extraHashes map[string]func() hash.Hash // 额外计算的摘要
+
+	preserveCreatedAt bool              // 复用已存在的相同内容时存储时间取已存在文件的修改时间
+	filenameSanitizer FilenameSanitizer // 原始文件名清理
+	maxFileSize       int64             // 单个文件的大小上限
+	observer          Observer          // 上传过程观察者
+	buffers           copyBuffers       // 复制内容使用的缓冲区池
+	logger            Logger            // 结构化日志
+
+	separateByCategory      bool                  // 以资源分类作为存储子目录的第一级目录
+	subDirectoryTemplate    *subDirectoryTemplate // 存储子目录模板
+	subDirectoryTemplateErr error                 // 存储子目录模板解析错误
+
+	defaultExtension         string            // 原始文件名没有后缀时使用的文件后缀
+	extensionFromContentType bool              // 原始文件名没有后缀时根据内容类型确定后缀
+	requireExtension         bool              // 拒绝存储没有后缀的文件
+	preserveExtensionCase    bool              // 保留原始文件名后缀的大小写
+	mimeExtensions           map[string]string // 自定义内容类型对应的文件后缀
+
+	linkMode LinkMode                               // 链接至其它子目录的方式
+	nameFunc func(result *FileStorageResult) string // 自定义存储文件名
+
+	maxFilenameLength    int  // 存储文件名的最大字节数
+	filenameLengthStrict bool // 存储文件名超出最大长度时返回错误
+
+	zipMaxEntries int   // 解压zip文件的文件数上限
+	zipMaxSize    int64 // 解压zip文件的解压后总大小上限
+
+	quota func(subDirectory string) int64 // 子目录配额(字节)
+	usage quotaUsage                      // 子目录已使用的字节数
+
+	usageCache    usageCache    // 子目录用量统计结果的缓存
+	usageCacheTTL time.Duration // 子目录用量统计结果的缓存时间
 }
 
 type Opts func(s *Storage)
@@ -30,11 +140,27 @@ func WithStorageDirectory(directory string) Opts {
 	return func(s *Storage) { s.storageDirectory = directory }
 }
 
-// WithUriAccessPrefix uri资源访问前缀
+// WithUriAccessPrefix uri资源访问前缀, 可以是路径(如 /static)或完整URL(如 https://cdn.example.com/static)
 func WithUriAccessPrefix(prefix string) Opts {
 	return func(s *Storage) { s.uriAccessPrefix = prefix }
 }
 
+// ExistingPolicy 目标位置已存在同名文件时的处理策略, 新内容总是先写入临时文件, 替换时原子地重命名至目标位置
+type ExistingPolicy int
+
+const (
+	ExistingError     ExistingPolicy = iota // 大小一致时视为相同内容并保留已存在的文件, 大小不一致时返回 ErrHashCollision
+	ExistingOverwrite                       // 无论大小是否一致, 均以新内容替换已存在的文件
+	ExistingKeep                            // 无论大小是否一致, 均保留已存在的文件, 存储结果的 Size 为已存在文件的大小
+	ExistingRename                          // 大小一致时视为相同内容并保留已存在的文件, 大小不一致时存储为带序号的文件名(如 hash-1.png)
+)
+
+// WithExistingPolicy 目标位置已存在同名文件时的处理策略, 默认 ExistingError; 目标位置为目录时总是返回错误
+// 需要同时比较文件内容时见 WithVerifyExisting
+func WithExistingPolicy(policy ExistingPolicy) Opts {
+	return func(s *Storage) { s.existingPolicy = policy }
+}
+
 func NewStorage(
 	opts ...Opts,
 ) *Storage {
@@ -47,244 +173,697 @@ func NewStorage(
 
 // FileStorage 文件存储参数
 type FileStorage struct {
-	StorageDirectory    string // 文件存储目录
-	UriAccessPrefix     string // 资源访问前缀
-	StorageSubDirectory string // 文件保存子目录
+	StorageDirectory    string   // 文件存储目录
+	UriAccessPrefix     string   // 资源访问前缀, 为空时使用 WithUriAccessPrefix 的设置
+	NoUriAccessPrefix   bool     // 不使用任何资源访问前缀(忽略 UriAccessPrefix 及 WithUriAccessPrefix)
+	StorageSubDirectory string   // 文件保存子目录
+	DryRun              bool     // 仅计算哈希值及存储路径并检查内容是否已存在, 不写入任何文件及目录
+	LinkSubDirectories  []string // 同时链接至的其它子目录(见 WithLinkMode), 返回多个存储结果的接口中每个位置为一个独立的存储结果, 紧随原存储结果之后; 其它接口记录于 FileStorageResult.Links
+	FormMaxMemory       int64    // 解析表单时内存中保存的最大字节数, 为0时使用 WithFormMaxMemory 的设置
+
+	// 客户端声明的文件哈希值(sha256, 编码方式见 WithHashEncoding)及大小, 与接收到的上传内容(base64为解码后的内容, 未经解压及去除EXIF元数据)不一致时返回 ErrIntegrityMismatch 且不写入文件
+	// 为空(0)时不校验; 批量存储时对每个文件校验, 因此仅适用于单文件上传
+	ExpectedHash string
+	ExpectedSize int64
 }
 
 // FileStorageResult 文件存储结果
 type FileStorageResult struct {
-	Uid        int64  `json:"uid,omitempty"`      // 文件唯一id
-	Size       int64  `json:"size"`               // 文件大小
-	Bucket     string `json:"bucket,omitempty"`   // 文件存储桶
-	Category   string `json:"category,omitempty"` // 资源分类
-	Name       string `json:"name"`               // 文件名
-	Hash       string `json:"hash,omitempty"`     // 文件哈希值(sha256)
-	FileExt    string `json:"file_ext"`           // 文件后缀
-	PathAbs    string `json:"path_abs,omitempty"` // 文件存储绝对路径
-	PathRlt    string `json:"path_rlt,omitempty"` // 文件存储相对路径
-	PathUri    string `json:"path_uri"`           // 文件资源访问路径
-	OriginName string `json:"origin_name"`        // 原始文件名
-}
-
-func (s *Storage) multipartCopy(param *FileStorage, file *multipart.FileHeader) (result *FileStorageResult, err error) {
+	Uid           int64  `json:"uid,omitempty"`             // 文件唯一id
+	Size          int64  `json:"size"`                      // 文件大小
+	Bucket        string `json:"bucket,omitempty"`          // 文件存储桶
+	Category      string `json:"category,omitempty"`        // 资源分类
+	Name          string `json:"name"`                      // 文件名
+	Hash          string `json:"hash,omitempty"`            // 文件哈希值(sha256), 编码方式见 HashEncoding
+	HashEncoding  string `json:"hash_encoding,omitempty"`   // 文件哈希值的编码方式
+	FileExt       string `json:"file_ext"`                  // 文件后缀
+	PathAbs       string `json:"path_abs,omitempty"`        // 文件存储绝对路径
+	PathRlt       string `json:"path_rlt,omitempty"`        // 文件存储相对路径(相对于存储目录), 统一使用 / 分隔且不以 / 开头
+	PathUri       string `json:"path_uri"`                  // 文件资源访问路径
+	OriginName    string `json:"origin_name"`               // 原始文件名(已清理, 见 WithFilenameSanitizer)
+	RawOriginName string `json:"raw_origin_name,omitempty"` // 原始文件名(客户端提交的原值)
+	Field         string `json:"field,omitempty"`           // 表单字段名
+	Width         int    `json:"width,omitempty"`           // 图片宽度
+	Height        int    `json:"height,omitempty"`          // 图片高度
+	ThumbnailUri  string `json:"thumbnail_uri,omitempty"`   // 缩略图资源访问路径
+
+	PerceptualHash string `json:"perceptual_hash,omitempty"` // 图片感知哈希(见 WithPerceptualHash)
+
+	DetectedContentType string `json:"detected_content_type,omitempty"` // 上传内容的类型
+	FinalContentType    string `json:"final_content_type,omitempty"`    // 存储内容经全部处理(如解压, 格式转换)后的实际类型, 不考虑存储时的压缩及加密(WithCompression, WithEncryption)
+	ContentType         string `json:"content_type,omitempty"`          // 内容类型, 内容检测无法识别时使用声明的类型或文件后缀对应的类型
+
+	Hashes map[string]string `json:"hashes,omitempty"` // 额外计算的摘要(见 WithExtraHashes), 键为摘要名称
+
+	Compressed   bool  `json:"compressed,omitempty"`    // 文件以gzip压缩存储
+	OriginalSize int64 `json:"original_size,omitempty"` // 压缩前的文件大小
+	Encrypted    bool  `json:"encrypted,omitempty"`     // 文件以AES-GCM加密存储
+
+	Exists  bool `json:"exists,omitempty"`  // 相同内容是否已存储(FileStorage.DryRun 时有效)
+	Created bool `json:"created,omitempty"` // 本次存储是否新写入了文件, 复用已存储的相同内容时为 false
+
+	CreatedAt time.Time `json:"created_at"` // 存储时间, 见 WithPreserveCreatedAt
+
+	Links []*FileStorageResult `json:"links,omitempty"` // 链接至其它子目录的存储结果(见 FileStorage.LinkSubDirectories), 仅用于返回单个存储结果的接口(如 CopyMultipartFile)
+
+	written    *writtenFile // 本次新写入的文件, 不受 WithResultFields 影响
+	dirs       []string     // 本次存储过程中新建的目录(由深至浅)
+	referenced string       // 已增加引用数的引用计数键(见 WithRefCounter)
+}
+
+func (s *Storage) multipartCopy(param *FileStorage, file *multipart.FileHeader, b *batch) (result *FileStorageResult, err error) {
+	return s.fileHeaderCopy(param, file, func() (io.ReadSeekCloser, error) { return s.OpenFormFile(file) }, b)
+}
+
+// fileHeaderCopy 根据表单文件信息检查后存储 open 打开的内容
+func (s *Storage) fileHeaderCopy(param *FileStorage, file *multipart.FileHeader, open func() (io.ReadSeekCloser, error), b *batch) (result *FileStorageResult, err error) {
 	result = &FileStorageResult{
-		Size:       file.Size,
-		OriginName: file.Filename,
+		Size:          file.Size,
+		OriginName:    s.sanitizeFilename(file.Filename),
+		RawOriginName: file.Filename,
 	}
+	s.started(param)
 
-	src, err := file.Open()
-	if err != nil {
+	if err = s.checkDeclaredType(file); err != nil {
+		s.notify(result, err)
+		return
+	}
+	if err = s.checkFileSize(file.Size); err != nil {
+		s.notify(result, err)
 		return
 	}
-	defer func() { _ = src.Close() }()
 
-	result.Hash, err = s.sha256Reader(src)
+	src, err := open()
 	if err != nil {
+		s.notify(result, err)
 		return
 	}
+	defer func() { _ = src.Close() }()
 
-	// 下次从文件起始处读取文件内容
-	if _, err = src.Seek(0, 0); err != nil {
-		return
+	err = s.storeOpened(&upload{
+		param:        param,
+		result:       result,
+		src:          src,
+		batch:        b,
+		declaredSize: file.Size,
+	}, isGzipPart(file))
+	return
+}
+
+// storeOpened 存储已打开的源内容, gzipped 表示源内容为gzip压缩数据(开启 WithDecompressOnUpload 时存储前解压)
+func (s *Storage) storeOpened(u *upload, gzipped bool) error {
+	name := u.result.OriginName
+	if s.decompressOnUpload && gzipped {
+		u.gzipped = true
+		if ext := path.Ext(name); strings.EqualFold(ext, ".gz") {
+			name = strings.TrimSuffix(name, ext)
+		}
 	}
+	u.result.FileExt = path.Ext(name)
+	return s.store(u)
+}
 
-	result.FileExt = path.Ext(file.Filename)
-	// filename
-	result.Name = result.Hash + result.FileExt
+// upload 单个文件的存储过程
+type upload struct {
+	param         *FileStorage       // 文件存储参数
+	result        *FileStorageResult // 文件存储结果
+	src           io.ReadSeeker      // 源内容
+	gzipped       bool               // 源内容为gzip压缩数据, 存储前解压
+	converted     bool               // 上传内容已按 Converter 转换格式
+	batch         *batch             // 所属请求的存储状态
+	fs            FileSystem         // 文件系统
+	tempDirectory string             // 临时文件目录, 为空时写入目标位置所在目录
+	declaredType  string             // 客户端声明的内容类型(如base64 data URI中的类型)
+	declaredSize  int64              // 声明的源内容大小(如表单文件的 Size), 小于等于0时表示未知
+	created       []string           // 本次存储过程中新建的文件
+	dirs          []string           // 本次存储过程中新建的目录(由深至浅)
+	buffers       *copyBuffers       // 复制内容使用的缓冲区池, 为空时使用 io.Copy
+	unlocks       []func()           // 存储完成后需释放的文件路径锁
+	collided      bool               // 发生哈希冲突, 存储为带序号的文件名
+	sync          bool               // 关闭写入的文件前刷新至存储介质
+	fileMode      os.FileMode        // 存储文件的权限, 为0时使用默认权限
+}
 
-	storageDirectory := s.storageDirectory
-	if param.StorageDirectory != "" {
-		storageDirectory = param.StorageDirectory
+// unlock 释放存储过程中获取的文件路径锁
+func (u *upload) unlock() {
+	for i := len(u.unlocks) - 1; i >= 0; i-- {
+		u.unlocks[i]()
 	}
-	saveDirectory := storageDirectory
+	u.unlocks = nil
+}
 
-	result.PathUri = result.Name
-	if param.StorageSubDirectory != "" {
-		storageDirectory = path.Join(storageDirectory, param.StorageSubDirectory)
-		result.PathUri = path.Join(param.StorageSubDirectory, result.PathUri)
+// context 存储过程的上下文
+func (u *upload) context() context.Context {
+	if u.batch != nil && u.batch.ctx != nil {
+		return u.batch.ctx
 	}
+	return context.Background()
+}
 
-	result.PathRlt = path.Join(storageDirectory, result.Name)
-	if filepath.IsAbs(result.PathRlt) {
-		result.PathAbs = result.PathRlt
-		result.PathRlt = strings.TrimPrefix(result.PathRlt, saveDirectory)
-	} else {
-		result.PathAbs, err = filepath.Abs(result.PathRlt)
+// store 预处理并写入文件内容, 根据预处理(如解压, 去除EXIF元数据)后的内容计算哈希值
+// 内容先写入同目录下的临时文件再重命名至目标位置, 存储失败时清理本次创建的文件及目录
+func (s *Storage) store(u *upload) (err error) {
+	if err = s.begin(); err != nil {
+		return
+	}
+	defer s.end()
+	u.fs, u.tempDirectory, u.buffers, u.sync, u.fileMode = s.filesystem(), s.tempDirectory, &s.buffers, s.sync, s.fileMode
+
+	defer func() {
+		if err == nil {
+			err = s.link(u)
+		}
+		if err == nil {
+			err = s.buildURI(u.result)
+		}
+		// 引用数在持有文件锁期间增加, 避免与并发的 Delete 交错
+		if err == nil && !u.param.DryRun {
+			err = s.reference(u.result)
+		}
+		// 镜像写入失败不影响存储结果
+		if err == nil && !u.param.DryRun {
+			s.mirror(u.result)
+		}
 		if err != nil {
+			u.cleanup()
+		} else {
+			u.result.dirs = u.dirs
+			s.trimResult(u.result)
+		}
+		u.unlock()
+		// 开启 WithAtomicBatch 时存储成功的通知延迟至批量存储完成
+		if !u.param.DryRun && !(err == nil && s.atomicBatch && u.batch.postpone(u.result)) {
+			s.notify(u.result, err)
+		}
+	}()
+
+	param, result := u.param, u.result
+	if !param.DryRun {
+		result.Uid = s.nextID()
+		result.CreatedAt = s.now()
+	}
+	// 上传内容的类型需单独读取源内容头部, 不需要时跳过
+	if s.wants(ResultContentType) {
+		if _, err = u.src.Seek(0, io.SeekStart); err != nil {
+			return
+		}
+		if result.DetectedContentType, err = sniffContentType(u.src); err != nil {
 			return
 		}
 	}
 
-	if _, err = os.Stat(result.PathAbs); err != nil {
-		if os.IsNotExist(err) {
-			if err = os.MkdirAll(storageDirectory, 0755); err != nil {
-				return
-			}
+	content, err := s.openContent(u)
+	if err != nil {
+		return
+	}
+	head := &headBuffer{}
+	content = io.TeeReader(content, head)
+	digests := s.newExtraDigests()
+	content = digests.tee(content)
+	// 哈希值用于文件命名及内容复用, 总是计算
+	result.HashEncoding = s.hashEncoding.String()
+	if result.Hash, result.Size, err = s.sha256Reader(content); err != nil {
+		return
+	}
+	result.Hashes = digests.sums()
+	if err = s.checkFileSize(result.Size); err != nil {
+		return
+	}
+	if err = s.checkIntegrity(u); err != nil {
+		return
+	}
+	result.FinalContentType = head.ContentType()
+	if u.converted {
+		s.convertedExtension(result)
+	}
+	if err = s.checkDimensions(u); err != nil {
+		return
+	}
+	if err = s.resolveExtension(result); err != nil {
+		return
+	}
+	if s.wants(ResultContentType) {
+		result.ContentType = resolveContentType(result.FinalContentType, u.declaredType, result.FileExt)
+	}
+	// 资源分类在路径计算之前确定
+	result.Category = s.categorize(result.FileExt, result.FinalContentType)
+
+	name, custom, err := s.fileName(result)
+	if err != nil {
+		return
+	}
+
+	// 相同内容已存储于其它位置
+	index, err := s.dedupIndex()
+	if err != nil {
+		return
+	}
+	if index != nil && !custom {
+		u.unlocks = append(u.unlocks, s.locker.lock("hash:"+result.Hash))
+		hit := false
+		if hit, err = s.lookupIndex(index, result); err != nil || hit {
+			result.Exists = hit
+			return
 		}
 	}
 
-	uriAccessPrefix := s.uriAccessPrefix
-	if param.UriAccessPrefix != "" {
-		uriAccessPrefix = param.UriAccessPrefix
+	// filename
+	result.Name = name
+	if s.shouldCompress(result) {
+		result.Compressed = true
+		result.OriginalSize = result.Size
+		result.Name += compressedSuffix
 	}
-	if uriAccessPrefix != "" {
-		result.PathUri = path.Join(uriAccessPrefix, result.PathUri)
+	if s.encryption != nil {
+		result.Encrypted = true
+		result.Name += encryptedSuffix
 	}
-	if !strings.HasPrefix(result.PathUri, "/") {
-		result.PathUri = "/" + result.PathUri
+	if result.Name, err = s.limitFilename(name, result.Name[len(name):]); err != nil {
+		return
 	}
-	if os.PathSeparator != '/' {
-		result.PathUri = strings.ReplaceAll(result.PathUri, string(os.PathSeparator), "/")
+
+	if err = s.resolvePath(param, result); err != nil {
+		return
 	}
 
-	if stat, ser := os.Stat(result.PathAbs); ser == nil {
-		if stat.Size() == result.Size && !stat.IsDir() {
-			if err = os.Remove(result.PathAbs); err != nil {
-				return
+	// 仅计算存储结果, 不写入任何文件及目录
+	if param.DryRun {
+		if stat, ser := s.filesystem().Stat(result.PathAbs); ser == nil && !stat.IsDir() {
+			result.Exists = true
+			if s.preserveCreatedAt {
+				result.CreatedAt = stat.ModTime()
 			}
 		}
+		return
 	}
 
-	dst, err := os.Create(result.PathAbs)
+	if err = u.mkdirAll(filepath.Dir(result.PathAbs)); err != nil {
+		return
+	}
+
+	// 相同内容的并发写入互斥
+	u.unlocks = append(u.unlocks, s.locker.lock(result.PathAbs))
+
+	// 本次请求已写入相同内容
+	reused, err := u.batch.reuse(result, result.PathAbs)
+	if err != nil || reused {
+		return
+	}
+
+	if err = s.preflightSpace(filepath.Dir(result.PathAbs), result.Size); err != nil {
+		return
+	}
+
+	commitQuota, err := s.reserveQuota(param, s.baseSubDirectory(param, result), result.Size)
 	if err != nil {
 		return
 	}
-	defer func() { _ = dst.Close() }()
+	written := int64(0)
+	defer func() {
+		// 存储失败时已写入的文件会被清理
+		if err != nil {
+			written = 0
+		}
+		commitQuota(written)
+	}()
+
+	// 下次从文件起始处读取文件内容
+	if content, err = s.openContent(u); err != nil {
+		return
+	}
 
-	if _, err = io.Copy(dst, src); err != nil {
+	tmp, size, err := u.writeTemp(result.PathAbs, func(w io.Writer) error {
+		return s.encodeContent(w, content, result)
+	})
+	if err != nil {
 		return
 	}
 
+	if err = s.scan(u, tmp); err != nil {
+		return
+	}
+
+	existing, replace, err := s.checkExisting(u, tmp, size)
+	if err != nil {
+		return
+	}
+	existed := existing != nil
+	if existed && s.preserveCreatedAt {
+		result.CreatedAt = existing.ModTime()
+	}
+
+	if existed && !replace {
+		// 保留已存在的文件, 丢弃本次写入的临时文件
+		_ = u.fs.Remove(tmp)
+		u.forget(tmp)
+		result.Size = existing.Size()
+	} else {
+		if err = u.commitTemp(tmp, result.PathAbs, existed); err != nil {
+			return
+		}
+		if err = s.makeReadOnly(u, result.PathAbs); err != nil {
+			return
+		}
+		result.Size = size
+	}
+	result.Created = !existed
+	// 替换已存在的文件时近似认为占用空间不变
+	if result.Created {
+		written = size
+		result.written = &writtenFile{pathAbs: result.PathAbs, hash: result.Hash}
+	}
+
+	if err = s.processImage(u); err != nil {
+		return
+	}
+	if err = s.writeSidecar(u); err != nil {
+		return
+	}
+
+	// 哈希冲突时带序号的文件不记录至内容索引
+	if !custom && !u.collided {
+		if err = s.storeIndex(result); err != nil {
+			return
+		}
+	}
+	u.batch.store(result, result.PathAbs)
 	return
 }
 
-// MultipartCopy 文件拷贝
-func (s *Storage) MultipartCopy(param *FileStorage, files ...*multipart.FileHeader) (succeeded []*FileStorageResult, err error) {
-	var tmp *FileStorageResult
-	length := len(files)
-	succeeded = make([]*FileStorageResult, 0, length)
-	for i := 0; i < length; i++ {
-		if files[i] == nil {
-			continue
+// openContent 从头读取源内容并进行预处理(如解压, 格式转换, 去除EXIF元数据), 返回实际需要存储的内容
+func (s *Storage) openContent(u *upload) (content io.Reader, err error) {
+	if _, err = u.src.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+	content = u.sourceReader()
+	if u.gzipped {
+		if content, err = gzip.NewReader(content); err != nil {
+			return
 		}
-		tmp, err = s.multipartCopy(param, files[i])
-		if err != nil {
+		content = &decompressLimitReader{r: &sizeLimitReader{r: content, limit: s.maxFileSize}, limit: s.decompressedLimit()}
+	}
+	if s.converter != nil {
+		if content, err = s.convertReader(u, content); err != nil {
 			return
 		}
-		succeeded = append(succeeded, tmp)
+	}
+	if s.stripEXIF {
+		content, err = s.stripEXIFReader(content)
 	}
 	return
 }
 
-var regexpImageBase64 = regexp.MustCompile(`^data:\s*image/(\w+);base64,(.*)`)
-
-func (s *Storage) base64Copy(param *FileStorage, content []byte) (result *FileStorageResult, err error) {
-	result = &FileStorageResult{}
-	matched := regexpImageBase64.FindAllSubmatch(content, -1)
-	if len(matched) == 0 || len(matched[0]) < 3 {
-		err = fmt.Errorf("illegal image base64 value")
-		return
+// baseSubDirectory 存储子目录(开启 WithSeparateByCategory 时以资源分类为第一级目录), 不含子目录模板及分片目录
+func (s *Storage) baseSubDirectory(param *FileStorage, result *FileStorageResult) string {
+	if s.separateByCategory && result.Category != "" {
+		return path.Join(result.Category, param.StorageSubDirectory)
 	}
-	imageContent, err := base64.StdEncoding.DecodeString(string(matched[0][2]))
+	return param.StorageSubDirectory
+}
+
+// resolvePath 根据文件名计算文件存储路径及资源访问路径
+func (s *Storage) resolvePath(param *FileStorage, result *FileStorageResult) (err error) {
+	subDirectory := s.baseSubDirectory(param, result)
+	templated, err := s.templateSubDirectory(result)
 	if err != nil {
 		return
 	}
-	result.FileExt = "." + string(matched[0][1])
-	result.Hash, err = s.sha256Reader(bytes.NewBuffer(content))
-	if err != nil {
-		return
+	if templated != "" {
+		subDirectory = path.Join(subDirectory, templated)
 	}
-	result.Name = result.Hash + result.FileExt
+	if shard := s.nameShard(result.OriginName); shard != "" {
+		subDirectory = path.Join(subDirectory, shard)
+	}
+	return s.locate(param, subDirectory, result)
+}
 
+// locate 根据子目录及文件名计算文件存储路径及资源访问路径
+func (s *Storage) locate(param *FileStorage, subDirectory string, result *FileStorageResult) (err error) {
 	storageDirectory := s.storageDirectory
 	if param.StorageDirectory != "" {
 		storageDirectory = param.StorageDirectory
 	}
+	// 未设置存储目录时为工作目录, 避免以 / 开头的子目录被当作绝对路径
+	if storageDirectory == "" {
+		storageDirectory = "."
+	}
 	saveDirectory := storageDirectory
 
 	result.PathUri = result.Name
-	if param.StorageSubDirectory != "" {
-		storageDirectory = path.Join(storageDirectory, param.StorageSubDirectory)
-		result.PathUri = path.Join(param.StorageSubDirectory, result.PathUri)
-	}
-	result.PathRlt = path.Join(storageDirectory, result.Name)
-	if filepath.IsAbs(result.PathRlt) {
-		result.PathAbs = result.PathRlt
-		result.PathRlt = strings.TrimPrefix(result.PathRlt, saveDirectory)
-	} else {
-		result.PathAbs, err = filepath.Abs(result.PathRlt)
-		if err != nil {
-			return
-		}
+	if subDirectory != "" {
+		storageDirectory = path.Join(storageDirectory, subDirectory)
+		result.PathUri = path.Join(subDirectory, result.PathUri)
 	}
 
-	if _, err = os.Stat(result.PathAbs); err != nil {
-		if os.IsNotExist(err) {
-			if err = os.MkdirAll(storageDirectory, 0755); err != nil {
-				return
-			}
+	if result.PathAbs, err = filepath.Abs(path.Join(storageDirectory, result.Name)); err != nil {
+		return
+	}
+	// 存储目录为绝对路径或相对路径时 PathRlt 均为相对于存储目录的路径
+	if s.wants(ResultPathRlt) {
+		root, err := filepath.Abs(saveDirectory)
+		if err != nil {
+			return err
 		}
+		result.PathRlt = relativePath(root, result.PathAbs)
 	}
 
 	uriAccessPrefix := s.uriAccessPrefix
 	if param.UriAccessPrefix != "" {
 		uriAccessPrefix = param.UriAccessPrefix
 	}
-	if uriAccessPrefix != "" {
-		result.PathUri = path.Join(uriAccessPrefix, result.PathUri)
-	}
-	if !strings.HasPrefix(result.PathUri, "/") {
-		result.PathUri = "/" + result.PathUri
+	if param.NoUriAccessPrefix {
+		uriAccessPrefix = ""
 	}
 	if os.PathSeparator != '/' {
 		result.PathUri = strings.ReplaceAll(result.PathUri, string(os.PathSeparator), "/")
 	}
+	result.PathUri = s.joinURI(uriAccessPrefix, result.PathUri)
+	return
+}
+
+// WithLeadingSlash 资源访问路径(未使用完整URL作为前缀时)是否以 / 开头, 默认为 true; 为 false 时返回如 sub/hash.jpg 的相对路径
+func WithLeadingSlash(leading bool) Opts {
+	return func(s *Storage) { s.noLeadingSlash = !leading }
+}
+
+// joinURI 拼接资源访问前缀与资源路径, 前缀为完整URL(如 https://cdn.example.com/static)时按URL拼接, 否则返回以 / 开头(见 WithLeadingSlash)的路径
+func (s *Storage) joinURI(prefix string, name string) string {
+	if u, err := url.Parse(prefix); err == nil && u.Scheme != "" && u.Host != "" {
+		return u.JoinPath(name).String()
+	}
+	uri := strings.TrimPrefix(path.Join(prefix, name), "/")
+	if !s.noLeadingSlash {
+		uri = "/" + uri
+	}
+	return uri
+}
 
-	if stat, ser := os.Stat(result.PathAbs); ser == nil {
-		if !stat.IsDir() {
-			if err = os.Remove(result.PathAbs); err != nil {
-				return
-			}
+// siblingURI 与资源 uri 位于同一目录的资源 name 的访问路径
+func siblingURI(uri string, name string) string {
+	if u, err := url.Parse(uri); err == nil && u.Scheme != "" && u.Host != "" {
+		return u.ResolveReference(&url.URL{Path: name}).String()
+	}
+	return path.Join(path.Dir(uri), name)
+}
+
+// relativePath 计算 name 相对于 root 的路径, 统一使用 / 分隔且不以分隔符开头
+func relativePath(root string, name string) string {
+	rel := filepath.Clean(name)
+	if root != "" {
+		if r, err := filepath.Rel(filepath.Clean(root), rel); err == nil && !strings.HasPrefix(r, "..") {
+			rel = r
 		}
 	}
+	return strings.TrimLeft(filepath.ToSlash(rel), "/")
+}
 
-	fil, err := os.Create(result.PathAbs)
+// checkExisting 检查目标位置是否已存在同名文件并按 ExistingPolicy 决定是否替换, tmp 为本次写入的临时文件, size 为其大小
+// 返回已存在文件的信息(不存在时为 nil); ExistingRename 时目标位置可能变更为带序号的文件名
+func (s *Storage) checkExisting(u *upload, tmp string, size int64) (existing os.FileInfo, replace bool, err error) {
+	result := u.result
+	stat, err := s.filesystem().Stat(result.PathAbs)
 	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	if stat.IsDir() {
+		err = fmt.Errorf("%s is a directory", result.PathAbs)
 		return
 	}
-	defer func() { _ = fil.Close() }()
+	existing = stat
+	switch s.existingPolicy {
+	case ExistingOverwrite:
+		replace = true
+	case ExistingKeep:
+	default:
+		same := false
+		if same, err = s.sameExisting(u, stat, tmp, size); err != nil || same {
+			return
+		}
+		if s.existingPolicy != ExistingRename {
+			err = fmt.Errorf("%w: %s", ErrHashCollision, result.Name)
+			return
+		}
+		u.collided = true
+		existing, err = s.renameCollision(u, tmp, size)
+	}
+	return
+}
+
+// MultipartCopy 文件拷贝, 配置 WithConcurrency 时并发处理且结果保持原有顺序
+func (s *Storage) MultipartCopy(param *FileStorage, files ...*multipart.FileHeader) (succeeded []*FileStorageResult, err error) {
+	defer func() { succeeded = flattenLinks(succeeded) }()
+	return s.multipartCopyAll(param, nil, files...)
+}
 
-	if _, err = io.Copy(fil, bytes.NewBuffer(imageContent)); err != nil {
+// CopyMultipartFile 存储已打开的表单文件(如已读取头部检测类型), 不再重新打开; filename 为原始文件名, size 为文件大小(未知时为0)
+// 从头读取文件内容, 存储完成后不关闭 f
+func (s *Storage) CopyMultipartFile(param *FileStorage, f multipart.File, filename string, size int64) (result *FileStorageResult, err error) {
+	result = &FileStorageResult{
+		Size:          size,
+		OriginName:    s.sanitizeFilename(filename),
+		RawOriginName: filename,
+	}
+	s.started(param)
+	if err = s.checkFileSize(size); err != nil {
+		s.notify(result, err)
 		return
 	}
+	err = s.storeOpened(&upload{
+		param:        param,
+		result:       result,
+		src:          f,
+		declaredSize: size,
+	}, strings.EqualFold(path.Ext(filename), ".gz"))
+	return
+}
 
+func (s *Storage) multipartCopyAll(param *FileStorage, b *batch, files ...*multipart.FileHeader) (succeeded []*FileStorageResult, err error) {
+	if b == nil {
+		if err = s.checkTotalSize(files, 0); err != nil {
+			return
+		}
+		b = s.pendingBatch()
+		defer func() { succeeded = s.rollback(b, succeeded, err) }()
+	}
+	length := len(files)
+	results := make([]*FileStorageResult, length)
+	err = s.parallel(length, func(i int) (err error) {
+		if files[i] == nil {
+			return
+		}
+		result, err := s.multipartCopy(param, files[i], b)
+		if err == nil {
+			results[i] = result
+		}
+		return
+	})
+	succeeded = make([]*FileStorageResult, 0, length)
+	for i := 0; i < length; i++ {
+		if results[i] != nil {
+			succeeded = append(succeeded, results[i])
+		}
+	}
+	return
+}
+
+// base64Copy 存储base64(data URI)内容, filename 为空时仅允许图片类型
+func (s *Storage) base64Copy(param *FileStorage, content []byte, filename string, b *batch) (result *FileStorageResult, err error) {
+	stored := false
+	result = &FileStorageResult{}
+	if filename != "" {
+		result.OriginName, result.RawOriginName = s.sanitizeFilename(filename), filename
+	}
+	s.started(param)
+	defer func() {
+		// 存储过程中的错误已在 store 中通知
+		if err != nil && !stored {
+			s.notify(result, err)
+		}
+	}()
+	declaredType, n, ok := s.parseBase64(content)
+	declaredType = strings.ToLower(declaredType)
+	if !ok || n < 0 || n > len(content) || (filename == "" && !strings.HasPrefix(declaredType, "image/")) {
+		err = fmt.Errorf("illegal image base64 value")
+		if filename != "" {
+			err = fmt.Errorf("illegal base64 value: %s", result.OriginName)
+		}
+		return
+	}
+	encoded := content[n:]
+	if i := bytes.IndexByte(encoded, '\n'); i >= 0 {
+		encoded = encoded[:i]
+	}
+	// 解码前按编码长度估算解码后的大小, 超出上限时不再解码
+	src := newBase64Source(encoded, s.maxFileSize)
+	if err = s.checkFileSize(int64(len(src.encoded) * 6 / 8)); err != nil {
+		return
+	}
+	// 优先使用原始文件名的后缀
+	if result.FileExt = path.Ext(result.OriginName); result.FileExt == "" {
+		result.FileExt = s.mimeExtension(declaredType)
+	}
+	stored = true
+	err = s.store(&upload{
+		param:        param,
+		result:       result,
+		src:          src,
+		batch:        b,
+		declaredType: declaredType,
+	})
 	return
 }
 
-// Base64Copy 图片base64存储
+// Base64Copy 图片base64存储, 配置 WithConcurrency 时并发处理且结果保持原有顺序
 func (s *Storage) Base64Copy(param *FileStorage, files [][]byte) (succeeded []*FileStorageResult, err error) {
-	var tmp *FileStorageResult
+	defer func() { succeeded = flattenLinks(succeeded) }()
+	b := s.pendingBatch()
+	defer func() { succeeded = s.rollback(b, succeeded, err) }()
+	return s.base64CopyAll(param, b, files, nil)
+}
+
+// base64CopyAll 存储多个base64内容, names 为nil或与 files 一一对应的原始文件名
+func (s *Storage) base64CopyAll(param *FileStorage, b *batch, files [][]byte, names []string) (succeeded []*FileStorageResult, err error) {
 	length := len(files)
-	for i := 0; i < length; i++ {
+	results := make([]*FileStorageResult, length)
+	err = s.parallel(length, func(i int) (err error) {
 		if files[i] == nil {
-			continue
-		}
-		if tmp, err = s.base64Copy(param, files[i]); err != nil {
 			return
-		} else {
-			succeeded = append(succeeded, tmp)
+		}
+		filename := ""
+		if names != nil {
+			filename = names[i]
+		}
+		result, err := s.base64Copy(param, files[i], filename, b)
+		if err == nil {
+			results[i] = result
+		}
+		return
+	})
+	succeeded = make([]*FileStorageResult, 0, length)
+	for i := 0; i < length; i++ {
+		if results[i] != nil {
+			succeeded = append(succeeded, results[i])
 		}
 	}
 	return
 }
 
-func (s *Storage) sha256Reader(r io.Reader) (string, error) {
-	tmp := sha256.New()
-	if _, err := io.Copy(tmp, r); err != nil {
-		return "", err
+// HashReader 计算内容的哈希值(与存储时的文件哈希值一致, 编码方式见 WithHashEncoding)及字节数, 不存储内容
+// 注意: 开启 WithStripEXIF, WithDecompressOnUpload 时存储的哈希值基于处理后的内容计算; base64存储的哈希值基于解码后的内容计算
+func (s *Storage) HashReader(r io.Reader) (hash string, size int64, err error) {
+	return s.sha256Reader(r)
+}
+
+func (s *Storage) sha256Reader(r io.Reader) (string, int64, error) {
+	w := s.NewHashingWriter()
+	n, err := s.buffers.copy(w, r)
+	if err != nil {
+		return "", n, err
 	}
-	return hex.EncodeToString(tmp.Sum(nil)), nil
+	return w.Sum(), n, nil
 }
 
 // IterateResult 迭代处理存储结果
@@ -296,49 +875,63 @@ func (s *Storage) IterateResult(moves []*FileStorageResult, fn func(move *FileSt
 
 // MultipartFileName 表单字段名称
 type MultipartFileName struct {
-	Single   string // 字段名-单文件
-	Multiple string // 字段名-多文件
+	Single   string   // 字段名-单文件
+	Multiple string   // 字段名-多文件
+	Fields   []string // 字段名-其它多文件字段, 存储结果的 Field 记录文件所属字段
+
+	SingleOptional bool // 单文件字段为可选字段, 缺失时跳过; 默认为必填字段, 缺失时返回 http.ErrMissingFile
+
+	Base64Fields []string // 字段名-图片base64(data URI)文本字段, 每个字段可包含多个值
+}
+
+// empty 未指定任何字段
+func (n *MultipartFileName) empty() bool {
+	return n.Single == "" && len(multipleFields(n)) == 0 && len(n.Base64Fields) == 0
 }
 
-// Echo 文件上传echo
+// Echo 文件上传echo, 同一请求内的重复内容只写入一次(见 WithRequestDedup)
 func (s *Storage) Echo(c echo.Context, param *FileStorage, name *MultipartFileName) (succeeded []*FileStorageResult, err error) {
-	if name == nil {
-		return
-	}
-	// single file
-	if name.Single != "" {
-		var file *multipart.FileHeader
-		file, err = c.FormFile(name.Single)
-		if err != nil {
-			return
-		}
-		var tmp *FileStorageResult
-		tmp, err = s.multipartCopy(param, file)
-		if err != nil {
-			return
-		}
-		succeeded = append(succeeded, tmp)
-	}
-	// multiple files
-	if name.Multiple != "" {
-		var form *multipart.Form
-		form, err = c.MultipartForm()
-		if err != nil {
-			return
-		}
-		defer func() { _ = form.RemoveAll() }()
-		var tmp []*FileStorageResult
-		tmp, err = s.MultipartCopy(param, form.File[name.Multiple]...)
-		if err != nil {
-			return
-		}
-		succeeded = append(succeeded, tmp...)
-	}
-	return
+	return s.HTTP(c.Request(), param, name)
+}
+
+// FormFiles 收集echo请求中单文件及多文件字段的上传文件而不存储, 见 HTTPFormFiles
+func (s *Storage) FormFiles(c echo.Context, name *MultipartFileName) ([]*multipart.FileHeader, error) {
+	return s.HTTPFormFiles(c.Request(), name)
 }
 
 // SubDirectoryDate 子目录附日期
 func (s *Storage) SubDirectoryDate(subDirectory string) string {
+	return s.SubDirectoryDateLayout(subDirectory, "2006/01/02")
+}
+
+// SubDirectoryDateLayout 子目录附按 layout 格式化的当前时间, layout 使用Go参考时间格式, 如 "2006/01" 按年/月分目录
+func (s *Storage) SubDirectoryDateLayout(subDirectory string, layout string) string {
+	return path.Join(subDirectory, s.now().Format(layout))
+}
+
+// WithTimeLocation 日期子目录使用的时区, 如 time.UTC; 默认使用服务器本地时区
+func WithTimeLocation(location *time.Location) Opts {
+	return func(s *Storage) { s.location = location }
+}
+
+// WithPreserveCreatedAt 复用已存在的相同内容时, 存储结果的 CreatedAt 取已存在文件的修改时间, 默认为本次存储的时间
+func WithPreserveCreatedAt(preserve bool) Opts {
+	return func(s *Storage) { s.preserveCreatedAt = preserve }
+}
+
+// WithClock 获取当前时间的函数(如测试时固定时间), 用于日期子目录, 存储时间及默认 Uid; 默认 time.Now
+func WithClock(clock func() time.Time) Opts {
+	return func(s *Storage) { s.clock = clock }
+}
+
+// now 当前时间
+func (s *Storage) now() time.Time {
 	now := time.Now()
-	return path.Join(subDirectory, fmt.Sprintf("%04d", now.Year()), fmt.Sprintf("%02d", now.Month()), fmt.Sprintf("%02d", now.Day()))
+	if s.clock != nil {
+		now = s.clock()
+	}
+	if s.location != nil {
+		now = now.In(s.location)
+	}
+	return now
 }
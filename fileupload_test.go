@@ -0,0 +1,463 @@
+package fileupload
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testFile 测试用的表单文件
+type testFile struct {
+	field       string
+	filename    string
+	content     string
+	contentType string
+}
+
+// newTestStorage 以临时目录作为存储目录创建 Storage
+func newTestStorage(t *testing.T, opts ...Opts) (*Storage, string) {
+	t.Helper()
+	dir := t.TempDir()
+	return NewStorage(append([]Opts{WithStorageDirectory(dir)}, opts...)...), dir
+}
+
+// multipartBody 编码表单, values 为非文件字段(字段名, 值交替)
+func multipartBody(t *testing.T, files []testFile, values ...string) (*bytes.Buffer, string) {
+	t.Helper()
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	for _, f := range files {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name=%q; filename=%q`, f.field, f.filename))
+		contentType := f.contentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		header.Set("Content-Type", contentType)
+		part, err := mw.CreatePart(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = part.Write([]byte(f.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i+1 < len(values); i += 2 {
+		if err := mw.WriteField(values[i], values[i+1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return body, mw.FormDataContentType()
+}
+
+// multipartRequest 创建上传表单的请求
+func multipartRequest(t *testing.T, files []testFile, values ...string) *http.Request {
+	t.Helper()
+	body, contentType := multipartBody(t, files, values...)
+	r := httptest.NewRequest(http.MethodPost, "/upload", body)
+	r.Header.Set("Content-Type", contentType)
+	return r
+}
+
+// formFileHeaders 解析表单并按顺序返回全部上传文件
+func formFileHeaders(t *testing.T, files ...testFile) []*multipart.FileHeader {
+	t.Helper()
+	body, contentType := multipartBody(t, files)
+	r := httptest.NewRequest(http.MethodPost, "/upload", body)
+	r.Header.Set("Content-Type", contentType)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = r.MultipartForm.RemoveAll() })
+	headers := make([]*multipart.FileHeader, 0, len(files))
+	seen := map[string]int{}
+	for _, f := range files {
+		headers = append(headers, r.MultipartForm.File[f.field][seen[f.field]])
+		seen[f.field]++
+	}
+	return headers
+}
+
+// pngBytes 生成 w*h 的PNG图片
+func pngBytes(t testing.TB, w int, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 255 / w), G: uint8(y * 255 / h), B: 128, A: 255})
+		}
+	}
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// dataURI 编码为base64 data URI
+func dataURI(contentType string, content []byte) []byte {
+	return []byte("data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(content))
+}
+
+// openBytes 以内存内容作为已打开的表单文件
+func openBytes(content []byte) multipart.File {
+	return memoryFile{Reader: bytes.NewReader(content)}
+}
+
+// readFile 读取文件内容
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestRawOriginName(t *testing.T) {
+	s, _ := newTestStorage(t)
+	raw := "../../etc/pass\r\nwd.txt"
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("hello")), raw, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RawOriginName != raw {
+		t.Errorf("RawOriginName = %q, want %q", result.RawOriginName, raw)
+	}
+	if result.OriginName != "passwd.txt" {
+		t.Errorf("OriginName = %q, want %q", result.OriginName, "passwd.txt")
+	}
+	if got := readFile(t, result.PathAbs); got != "hello" {
+		t.Errorf("stored content = %q", got)
+	}
+}
+
+func TestExistingPolicy(t *testing.T) {
+	const content, planted = "hello", "a different, longer file"
+	for _, tc := range []struct {
+		name   string
+		policy ExistingPolicy
+	}{
+		{"error", ExistingError},
+		{"overwrite", ExistingOverwrite},
+		{"keep", ExistingKeep},
+		{"rename", ExistingRename},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, _ := newTestStorage(t, WithExistingPolicy(tc.policy))
+			dry, err := s.CopyMultipartFile(&FileStorage{DryRun: true}, openBytes([]byte(content)), "a.txt", 5)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = os.MkdirAll(filepath.Dir(dry.PathAbs), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err = os.WriteFile(dry.PathAbs, []byte(planted), 0644); err != nil {
+				t.Fatal(err)
+			}
+			result, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte(content)), "a.txt", 5)
+			switch tc.policy {
+			case ExistingError:
+				if !errors.Is(err, ErrHashCollision) {
+					t.Fatalf("err = %v, want ErrHashCollision", err)
+				}
+				if got := readFile(t, dry.PathAbs); got != planted {
+					t.Errorf("existing file changed to %q", got)
+				}
+				return
+			case ExistingOverwrite:
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := readFile(t, dry.PathAbs); got != content {
+					t.Errorf("existing file = %q, want overwritten", got)
+				}
+			case ExistingKeep:
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := readFile(t, dry.PathAbs); got != planted {
+					t.Errorf("existing file changed to %q", got)
+				}
+				if result.Size != int64(len(planted)) {
+					t.Errorf("Size = %d, want the existing size %d", result.Size, len(planted))
+				}
+			case ExistingRename:
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.PathAbs == dry.PathAbs || !strings.Contains(result.Name, "-1") {
+					t.Errorf("Name = %s, want a numbered name", result.Name)
+				}
+				if got := readFile(t, result.PathAbs); got != content {
+					t.Errorf("renamed file = %q", got)
+				}
+				if got := readFile(t, dry.PathAbs); got != planted {
+					t.Errorf("existing file changed to %q", got)
+				}
+			}
+		})
+	}
+}
+
+func TestSubDirectoryDateLayout(t *testing.T) {
+	clock := func() time.Time { return time.Date(2024, 3, 9, 23, 30, 0, 0, time.UTC) }
+	s := NewStorage(WithClock(clock), WithTimeLocation(time.UTC))
+	if got := s.SubDirectoryDateLayout("uploads", "2006/01"); got != "uploads/2024/03" {
+		t.Errorf("SubDirectoryDateLayout = %q, want uploads/2024/03", got)
+	}
+	if got := s.SubDirectoryDate("uploads"); got != "uploads/2024/03/09" {
+		t.Errorf("SubDirectoryDate = %q, want uploads/2024/03/09", got)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	dir := t.TempDir()
+	stored, err := NewStorage(WithStorageDirectory(dir)).CopyMultipartFile(&FileStorage{}, openBytes([]byte("existing")), "a.txt", 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := &writeRecordingFS{FileSystem: osFileSystem{}}
+	s := NewStorage(WithStorageDirectory(dir), WithFileSystem(fs))
+	existing, err := s.CopyMultipartFile(&FileStorage{DryRun: true}, openBytes([]byte("existing")), "b.txt", 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !existing.Exists || existing.PathAbs != stored.PathAbs {
+		t.Errorf("dry run of stored content = %+v, want Exists at %s", existing, stored.PathAbs)
+	}
+	fresh, err := s.CopyMultipartFile(&FileStorage{DryRun: true, StorageSubDirectory: "new/dir"}, openBytes([]byte("fresh")), "c.txt", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh.Exists || fresh.Hash == "" || fresh.PathAbs == "" {
+		t.Errorf("dry run of new content = %+v, want a planned path and hash", fresh)
+	}
+	if len(fs.writes) != 0 {
+		t.Errorf("dry run wrote to the file system: %v", fs.writes)
+	}
+	if names := listTree(t, dir); len(names) != 1 {
+		t.Errorf("storage tree = %v, want only the stored file", names)
+	}
+	// 确认记录方式有效: 实际存储会产生写操作
+	if _, err = s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("fresh")), "c.txt", 5); err != nil {
+		t.Fatal(err)
+	}
+	if len(fs.writes) == 0 {
+		t.Error("recording file system missed the writes of a real store")
+	}
+}
+
+func TestRelativePath(t *testing.T) {
+	root := filepath.FromSlash("/srv/storage")
+	for _, tc := range []struct {
+		name string
+		want string
+	}{
+		{filepath.FromSlash("/srv/storage/a/b/c.png"), "a/b/c.png"},
+		{filepath.FromSlash("/srv/storage/c.png"), "c.png"},
+		{filepath.FromSlash("/srv/storage//a/./b/../c.png"), "a/c.png"},
+	} {
+		if got := relativePath(root, tc.name); got != tc.want {
+			t.Errorf("relativePath(%q, %q) = %q, want %q", root, tc.name, got, tc.want)
+		}
+	}
+	if runtime.GOOS == "windows" {
+		if got := relativePath(`C:\srv\storage`, `C:\srv\storage\a\b\c.png`); got != "a/b/c.png" {
+			t.Errorf("windows relativePath = %q, want a/b/c.png", got)
+		}
+	}
+}
+
+func TestPathRltSeparators(t *testing.T) {
+	s, _ := newTestStorage(t)
+	for _, sub := range []string{"a/b", "/a/b/", filepath.Join("a", "b")} {
+		result, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: sub}, openBytes([]byte("x")), "x.txt", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "a/b/" + result.Name; result.PathRlt != want {
+			t.Errorf("sub %q: PathRlt = %q, want %q", sub, result.PathRlt, want)
+		}
+		if want := "/a/b/" + result.Name; result.PathUri != want {
+			t.Errorf("sub %q: PathUri = %q, want %q", sub, result.PathUri, want)
+		}
+	}
+}
+
+func TestCreated(t *testing.T) {
+	s, _ := newTestStorage(t)
+	first, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("same bytes")), "a.txt", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("same bytes")), "b.txt", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first.Created {
+		t.Error("first upload is not flagged as created")
+	}
+	if second.Created || second.PathAbs != first.PathAbs {
+		t.Errorf("second upload = %+v, want the existing file flagged as not created", second)
+	}
+}
+
+func TestCreatedAt(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	s, _ := newTestStorage(t, WithClock(func() time.Time { return now }), WithPreserveCreatedAt(true))
+	first, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("x")), "a.txt", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.CreatedAt.IsZero() || !first.CreatedAt.Equal(now) {
+		t.Errorf("CreatedAt = %v, want %v", first.CreatedAt, now)
+	}
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err = os.Chtimes(first.PathAbs, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("x")), "b.txt", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !second.CreatedAt.Equal(modTime) {
+		t.Errorf("deduplicated CreatedAt = %v, want the existing modtime %v", second.CreatedAt, modTime)
+	}
+}
+
+func TestUriAccessPrefix(t *testing.T) {
+	for _, tc := range []struct {
+		prefix string
+		want   string
+	}{
+		{"", "/sub/"},
+		{"/static", "/static/sub/"},
+		{"static/", "/static/sub/"},
+		{"https://cdn.example.com/static", "https://cdn.example.com/static/sub/"},
+		{"https://cdn.example.com", "https://cdn.example.com/sub/"},
+	} {
+		s, _ := newTestStorage(t, WithUriAccessPrefix(tc.prefix))
+		result, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: "sub"}, openBytes([]byte("x")), "a.jpg", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := tc.want + result.Name; result.PathUri != want {
+			t.Errorf("prefix %q: PathUri = %q, want %q", tc.prefix, result.PathUri, want)
+		}
+	}
+}
+
+func TestLeadingSlash(t *testing.T) {
+	for _, tc := range []struct {
+		opts []Opts
+		want string
+	}{
+		{nil, "/sub/"},
+		{[]Opts{WithLeadingSlash(true)}, "/sub/"},
+		{[]Opts{WithLeadingSlash(false)}, "sub/"},
+		{[]Opts{WithLeadingSlash(false), WithUriAccessPrefix("/static")}, "static/sub/"},
+		{[]Opts{WithLeadingSlash(false), WithUriAccessPrefix("https://cdn.example.com")}, "https://cdn.example.com/sub/"},
+	} {
+		s, _ := newTestStorage(t, tc.opts...)
+		result, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: "sub"}, openBytes([]byte("x")), "a.jpg", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := tc.want + result.Name; result.PathUri != want {
+			t.Errorf("PathUri = %q, want %q", result.PathUri, want)
+		}
+	}
+}
+
+func TestCopyMultipartFileAlreadyOpen(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "upload.png")
+	content := pngBytes(t, 8, 8)
+	if err := os.WriteFile(name, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	// 存储前读取头部检测类型
+	head := make([]byte, 8)
+	if _, err = io.ReadFull(f, head); err != nil {
+		t.Fatal(err)
+	}
+	s, _ := newTestStorage(t)
+	result, err := s.CopyMultipartFile(&FileStorage{}, f, "photo.png", int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, result.PathAbs); got != string(content) {
+		t.Errorf("stored %d bytes, want the whole file of %d bytes", len(got), len(content))
+	}
+	if result.ContentType != "image/png" || result.Size != int64(len(content)) {
+		t.Errorf("result = %s %d", result.ContentType, result.Size)
+	}
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		t.Errorf("file was closed after storing: %v", err)
+	}
+}
+
+func TestClock(t *testing.T) {
+	// 固定在UTC午夜前, 东八区已是次日
+	fixed := time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC)
+	shanghai := time.FixedZone("CST", 8*3600)
+	s, _ := newTestStorage(t, WithClock(func() time.Time { return fixed }), WithTimeLocation(shanghai), WithSubDirectoryTemplate("{yyyy}/{mm}/{dd}"))
+	if got := s.SubDirectoryDate("logs"); got != "logs/2025/01/01" {
+		t.Errorf("SubDirectoryDate = %q, want logs/2025/01/01", got)
+	}
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("x")), "a.txt", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := path.Dir(result.PathRlt); got != "2025/01/01" {
+		t.Errorf("template directory = %s, want 2025/01/01", got)
+	}
+	if !result.CreatedAt.Equal(fixed) {
+		t.Errorf("CreatedAt = %v, want %v", result.CreatedAt, fixed)
+	}
+}
+
+func TestNoUriAccessPrefix(t *testing.T) {
+	s, _ := newTestStorage(t, WithUriAccessPrefix("https://cdn.example.com/static"))
+	for _, tc := range []struct {
+		param *FileStorage
+		want  string
+	}{
+		{&FileStorage{StorageSubDirectory: "internal"}, "https://cdn.example.com/static/internal/"},
+		{&FileStorage{StorageSubDirectory: "internal", UriAccessPrefix: "/private"}, "/private/internal/"},
+		{&FileStorage{StorageSubDirectory: "internal", NoUriAccessPrefix: true}, "/internal/"},
+		{&FileStorage{StorageSubDirectory: "internal", UriAccessPrefix: "/private", NoUriAccessPrefix: true}, "/internal/"},
+	} {
+		result, err := s.CopyMultipartFile(tc.param, openBytes([]byte("x")), "a.txt", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := tc.want + result.Name; result.PathUri != want {
+			t.Errorf("%+v: PathUri = %q, want %q", tc.param, result.PathUri, want)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package fileupload
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+)
+
+// 解析表单的限制, 与 mime/multipart 的 ReadForm 一致
+const (
+	maxFormValueBytes    = 10 << 20 // 表单非文件字段及头部等元数据在内存上限之外额外允许的字节数
+	maxFormParts         = 1000     // 表单分段数上限
+	maxFormHeaders       = 10000    // 全部文件分段的头部字段值总数上限
+	formMapEntryOverhead = 200      // 每个字段计入的映射开销
+	formFileHeaderSize   = 100      // 每个上传文件计入的 multipart.FileHeader 开销
+)
+
+// WithFormTempDirectory 解析表单(HTTP, Echo, EchoStreaming, Fiber)时超出内存上限(见 WithFormMaxMemory)的上传文件写入目录 directory; 为空时由 mime/multipart 写入 os.TempDir()
+// 设置后表单由本库逐个分段读取, HTTPFormFiles 返回的文件需通过 OpenFormFile 打开, 不再存储时通过 RemoveForm 清理
+// 未调用 RemoveForm 时, 临时文件及本库保存的文件内容在请求的上下文结束时清理; 上下文永不结束(如 context.Background())的请求必须调用 RemoveForm, 否则一直占用内存及磁盘
+func WithFormTempDirectory(directory string) Opts {
+	return func(s *Storage) { s.formTempDir = directory }
+}
+
+// formPart 本库解析的表单上传文件内容, memory 为 true 时保存在 content 中, 否则为临时文件 name
+type formPart struct {
+	memory  bool
+	content []byte
+	name    string
+}
+
+// memoryFile 内存中保存的表单上传文件
+type memoryFile struct {
+	*bytes.Reader
+}
+
+func (memoryFile) Close() error { return nil }
+
+// OpenFormFile 打开表单上传文件, 配置 WithFormTempDirectory 时 HTTPFormFiles 返回的文件需通过此方法打开
+func (s *Storage) OpenFormFile(file *multipart.FileHeader) (multipart.File, error) {
+	v, ok := s.formParts.Load(file)
+	if !ok {
+		return file.Open()
+	}
+	part := v.(*formPart)
+	if part.memory {
+		return memoryFile{Reader: bytes.NewReader(part.content)}, nil
+	}
+	return os.Open(part.name)
+}
+
+// RemoveForm 删除表单临时文件(含 WithFormTempDirectory 目录中的临时文件), 用于 HTTPFormFiles 之后不再存储的表单
+func (s *Storage) RemoveForm(form *multipart.Form) error {
+	var err error
+	for _, files := range form.File {
+		for _, file := range files {
+			v, ok := s.formParts.LoadAndDelete(file)
+			if !ok {
+				continue
+			}
+			if part := v.(*formPart); !part.memory {
+				if e := os.Remove(part.name); e != nil && !os.IsNotExist(e) && err == nil {
+					err = e
+				}
+			}
+		}
+	}
+	if e := form.RemoveAll(); e != nil && err == nil {
+		err = e
+	}
+	return err
+}
+
+// readForm 逐个读取 multipart 分段解析表单, 上传文件超出内存上限 maxMemory 的部分写入 WithFormTempDirectory 指定的目录
+// 分段数, 头部字段数及内存中保存的字段值, 头部等元数据的限制与 mime/multipart 一致, 超出时返回 multipart.ErrMessageTooLarge
+// 解析出错时删除已写入的临时文件; 解析后的表单保留在请求中, 请求的上下文结束(如 net/http 处理函数返回)时自动清理
+func (s *Storage) readForm(r *http.Request, maxMemory int64) (form *multipart.Form, err error) {
+	if r.MultipartForm != nil {
+		return r.MultipartForm, nil
+	}
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return
+	}
+	if err = os.MkdirAll(s.formTempDir, 0755); err != nil {
+		return
+	}
+	form = &multipart.Form{Value: map[string][]string{}, File: map[string][]*multipart.FileHeader{}}
+	defer func() {
+		if err != nil {
+			_ = s.RemoveForm(form)
+			form = nil
+		}
+	}()
+	// maxMemoryBytes 内存中保存的全部内容(含字段值及头部等元数据)的剩余额度, maxMemory 为文件内容的剩余额度
+	maxMemoryBytes := maxMemory + maxFormValueBytes
+	maxParts, maxHeaders := maxFormParts, int64(maxFormHeaders)
+	for {
+		var part *multipart.Part
+		part, err = mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			err = nil
+			break
+		}
+		if err != nil {
+			return
+		}
+		if maxParts--; maxParts < 0 {
+			_ = part.Close()
+			err = multipart.ErrMessageTooLarge
+			return
+		}
+		name := part.FormName()
+		if name == "" {
+			_ = part.Close()
+			continue
+		}
+		if maxMemoryBytes -= int64(len(name)) + formMapEntryOverhead; maxMemoryBytes < 0 {
+			_ = part.Close()
+			err = multipart.ErrMessageTooLarge
+			return
+		}
+		if part.FileName() == "" {
+			var value bytes.Buffer
+			n, rer := io.CopyN(&value, part, maxMemoryBytes+1)
+			_ = part.Close()
+			if rer != nil && !errors.Is(rer, io.EOF) {
+				err = rer
+				return
+			}
+			if maxMemoryBytes -= n; maxMemoryBytes < 0 {
+				err = multipart.ErrMessageTooLarge
+				return
+			}
+			form.Value[name] = append(form.Value[name], value.String())
+			continue
+		}
+		maxMemoryBytes -= mimeHeaderSize(part.Header) + formMapEntryOverhead + formFileHeaderSize
+		for _, v := range part.Header {
+			maxHeaders -= int64(len(v))
+		}
+		if maxMemoryBytes < 0 || maxHeaders < 0 {
+			_ = part.Close()
+			err = multipart.ErrMessageTooLarge
+			return
+		}
+		var file *multipart.FileHeader
+		file, err = s.readFormFile(part, &maxMemory, &maxMemoryBytes)
+		_ = part.Close()
+		if err != nil {
+			return
+		}
+		form.File[name] = append(form.File[name], file)
+	}
+	r.MultipartForm = form
+	context.AfterFunc(r.Context(), func() { _ = s.RemoveForm(form) })
+	return
+}
+
+// readFormFile 读取表单上传文件, 文件内容的内存剩余额度 maxMemory 不足时写入临时文件; 保存在内存中时同时扣减全部内容的剩余额度 maxMemoryBytes
+func (s *Storage) readFormFile(part *multipart.Part, maxMemory *int64, maxMemoryBytes *int64) (file *multipart.FileHeader, err error) {
+	file = &multipart.FileHeader{
+		Filename: part.FileName(),
+		Header:   textproto.MIMEHeader(part.Header),
+	}
+	var content bytes.Buffer
+	n, err := io.CopyN(&content, part, *maxMemory+1)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if n <= *maxMemory {
+		*maxMemory -= n
+		*maxMemoryBytes -= n
+		file.Size = n
+		s.formParts.Store(file, &formPart{memory: true, content: content.Bytes()})
+		return file, nil
+	}
+	tmp, err := os.CreateTemp(s.formTempDir, "multipart-")
+	if err != nil {
+		return nil, err
+	}
+	s.formParts.Store(file, &formPart{name: tmp.Name()})
+	size, err := s.buffers.copy(tmp, io.MultiReader(&content, part))
+	if e := tmp.Close(); e != nil && err == nil {
+		err = e
+	}
+	if err != nil {
+		if v, ok := s.formParts.LoadAndDelete(file); ok {
+			_ = os.Remove(v.(*formPart).name)
+		}
+		return nil, fmt.Errorf("write multipart form file: %w", err)
+	}
+	file.Size = size
+	return file, nil
+}
+
+// mimeHeaderSize 头部计入内存额度的大小, 与 mime/multipart 一致
+func mimeHeaderSize(h textproto.MIMEHeader) int64 {
+	size := int64(400)
+	for k, vs := range h {
+		size += int64(len(k)) + formMapEntryOverhead
+		for _, v := range vs {
+			size += int64(len(v))
+		}
+	}
+	return size
+}
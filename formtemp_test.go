@@ -0,0 +1,218 @@
+package fileupload
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// largeForm 超出表单内存上限的单文件表单
+var largeForm = []testFile{{field: "file", filename: "large.txt", content: strings.Repeat("x", 4096)}}
+
+// assertEmptyDir 检查目录为空(不存在视为空)
+func assertEmptyDir(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("%s still contains %d temp files", dir, len(entries))
+	}
+}
+
+// tempDirObserver 开始存储时记录目录中的文件数
+type tempDirObserver struct {
+	NopObserver
+	dir     string
+	spooled int
+}
+
+func (o *tempDirObserver) UploadStarted() {
+	entries, _ := os.ReadDir(o.dir)
+	o.spooled = len(entries)
+}
+
+func TestEchoSingleErrorRemovesFormTemp(t *testing.T) {
+	tempDir := t.TempDir()
+	// 开始存储时检查临时文件已写入目录
+	observer := &tempDirObserver{dir: tempDir}
+	s, _ := newTestStorage(t, WithFormTempDirectory(tempDir), WithFormMaxMemory(1024), WithMaxFileSize(1024), WithObserver(observer))
+	c := echo.New().NewContext(multipartRequest(t, largeForm), httptest.NewRecorder())
+	_, err := s.Echo(c, &FileStorage{}, &MultipartFileName{Single: "file"})
+	if !errors.Is(err, ErrFileTooLarge) {
+		t.Fatalf("err = %v, want ErrFileTooLarge", err)
+	}
+	if observer.spooled != 1 {
+		t.Errorf("%d temp files while storing, want the large file spooled to the temp directory", observer.spooled)
+	}
+	assertEmptyDir(t, tempDir)
+}
+
+func TestFormTempTruncatedBody(t *testing.T) {
+	tempDir := t.TempDir()
+	s, _ := newTestStorage(t, WithFormTempDirectory(tempDir), WithFormMaxMemory(1024))
+	body, contentType := multipartBody(t, append(largeForm, largeForm...))
+	truncated := body.Bytes()[:body.Len()-100]
+	r := httptest.NewRequest(http.MethodPost, "/upload", bytes.NewReader(truncated))
+	r.Header.Set("Content-Type", contentType)
+	if _, err := s.HTTP(r, &FileStorage{}, &MultipartFileName{Multiple: "file"}); err == nil {
+		t.Fatal("truncated form parsed without error")
+	}
+	assertEmptyDir(t, tempDir)
+}
+
+func TestHTTPFormFilesTempDirectory(t *testing.T) {
+	tempDir := t.TempDir()
+	s, _ := newTestStorage(t, WithFormTempDirectory(tempDir), WithFormMaxMemory(1024))
+	r := multipartRequest(t, largeForm)
+	files, err := s.HTTPFormFiles(r, &MultipartFileName{Single: "file"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Size != 4096 {
+		t.Fatalf("files = %+v", files)
+	}
+	f, err := s.OpenFormFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(f)
+	_ = f.Close()
+	if err != nil || string(content) != largeForm[0].content {
+		t.Errorf("form file = %d bytes, %v", len(content), err)
+	}
+	if entries, _ := os.ReadDir(tempDir); len(entries) != 1 {
+		t.Errorf("%d temp files, want the large file spooled", len(entries))
+	}
+	if err = s.RemoveForm(r.MultipartForm); err != nil {
+		t.Fatal(err)
+	}
+	assertEmptyDir(t, tempDir)
+}
+
+// paddedFormRequest 创建包含 parts 个空文件分段的上传请求, 每个分段带有 padding 字节的额外头部
+func paddedFormRequest(t *testing.T, parts int, padding int) *http.Request {
+	t.Helper()
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	for i := 0; i < parts; i++ {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="files"; filename="%d.txt"`, i))
+		if padding > 0 {
+			header.Set("X-Padding", strings.Repeat("p", padding))
+		}
+		if _, err := mw.CreatePart(header); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/upload", body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestFormTempLimits(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		parts   int
+		padding int
+	}{
+		{"part count", 5000, 0},
+		{"header size", 500, 32 << 10},
+	} {
+		// 与 mime/multipart 解析的结果一致
+		for _, tempDir := range []string{"", t.TempDir()} {
+			s, dir := newTestStorage(t, WithFormTempDirectory(tempDir), WithFormMaxMemory(1024))
+			_, err := s.HTTP(paddedFormRequest(t, tc.parts, tc.padding), &FileStorage{}, &MultipartFileName{Multiple: "files"})
+			if !errors.Is(err, multipart.ErrMessageTooLarge) {
+				t.Errorf("%s (temp directory %q): err = %v, want multipart.ErrMessageTooLarge", tc.name, tempDir, err)
+			}
+			if got := countFiles(t, dir); got != 0 {
+				t.Errorf("%s: %d files stored", tc.name, got)
+			}
+			if tempDir != "" {
+				assertEmptyDir(t, tempDir)
+			}
+		}
+	}
+
+	s, _ := newTestStorage(t, WithFormTempDirectory(t.TempDir()))
+	results, err := s.HTTP(paddedFormRequest(t, 100, 0), &FileStorage{}, &MultipartFileName{Multiple: "files"})
+	if err != nil || len(results) != 100 {
+		t.Errorf("within limits = %d results, %v", len(results), err)
+	}
+}
+
+// formPartCount 返回本库保存的表单上传文件数
+func formPartCount(s *Storage) int {
+	n := 0
+	s.formParts.Range(func(_, _ any) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+func TestFormTempRequestContextCleanup(t *testing.T) {
+	tempDir := t.TempDir()
+	s, _ := newTestStorage(t, WithFormTempDirectory(tempDir), WithFormMaxMemory(1024))
+	files := append([]testFile{{field: "file", filename: "small.txt", content: "small"}}, largeForm...)
+
+	// 未结束的上下文: 未调用 RemoveForm 时表单一直保留
+	r := multipartRequest(t, files)
+	if _, err := s.HTTPFormFiles(r, &MultipartFileName{Single: "file"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := formPartCount(s); n != 2 {
+		t.Fatalf("%d form parts, want 2 kept while the request context is alive", n)
+	}
+	if entries, _ := os.ReadDir(tempDir); len(entries) != 1 {
+		t.Fatalf("%d temp files, want 1", len(entries))
+	}
+	if err := s.RemoveForm(r.MultipartForm); err != nil {
+		t.Fatal(err)
+	}
+	if n := formPartCount(s); n != 0 {
+		t.Fatalf("%d form parts after RemoveForm", n)
+	}
+	assertEmptyDir(t, tempDir)
+
+	// 处理函数返回后请求的上下文结束, 未调用 RemoveForm 的表单自动清理
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := s.HTTPFormFiles(r, &MultipartFileName{Single: "file"}); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+	body, contentType := multipartBody(t, files)
+	resp, err := http.Post(server.URL, contentType, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for formPartCount(s) != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := formPartCount(s); n != 0 {
+		t.Fatalf("%d form parts leaked after the request ended", n)
+	}
+	assertEmptyDir(t, tempDir)
+}
@@ -0,0 +1,84 @@
+package fileupload
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// File 文件系统中已打开的文件
+type File interface {
+	io.Reader
+	io.Writer
+	io.Seeker
+	io.Closer
+	Name() string
+}
+
+// FileSystem 存储使用的文件系统, 默认直接操作本地磁盘; 测试时可注入内存文件系统
+type FileSystem interface {
+	Open(name string) (File, error)
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	MkdirAll(path string, perm os.FileMode) error
+	Stat(name string) (os.FileInfo, error)
+	Remove(name string) error
+	Rename(oldPath string, newPath string) error
+}
+
+// WithFileSystem 存储使用的文件系统
+func WithFileSystem(fs FileSystem) Opts {
+	return func(s *Storage) { s.fs = fs }
+}
+
+// filesystem 存储使用的文件系统
+func (s *Storage) filesystem() FileSystem {
+	if s.fs == nil {
+		return osFileSystem{}
+	}
+	return s.fs
+}
+
+// osFileSystem 本地磁盘文件系统
+type osFileSystem struct{}
+
+func (osFileSystem) Open(name string) (File, error) {
+	return os.Open(name)
+}
+
+func (osFileSystem) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	return os.OpenFile(name, flag, perm)
+}
+
+func (osFileSystem) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+func (osFileSystem) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (osFileSystem) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func (osFileSystem) Rename(oldPath string, newPath string) error {
+	return os.Rename(oldPath, newPath)
+}
+
+// createTemp 在目录 directory 下以权限 perm(创建时受 umask 影响)创建名称以 prefix 开头, suffix 结尾的临时文件
+func createTemp(fs FileSystem, directory string, prefix string, suffix string, perm os.FileMode) (file File, err error) {
+	random := make([]byte, 8)
+	for i := 0; i < 10; i++ {
+		if _, err = rand.Read(random); err != nil {
+			return
+		}
+		name := filepath.Join(directory, prefix+hex.EncodeToString(random)+suffix)
+		file, err = fs.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if !os.IsExist(err) {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,294 @@
+package fileupload
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memFS 测试用的内存文件系统
+type memFS struct {
+	mutex sync.Mutex
+	files map[string]*memData
+	dirs  map[string]bool
+}
+
+func newMemFS() *memFS {
+	return &memFS{files: map[string]*memData{}, dirs: map[string]bool{}}
+}
+
+// memData 内存文件的内容
+type memData struct {
+	data    []byte
+	modTime time.Time
+}
+
+func (fs *memFS) Open(name string) (File, error) {
+	return fs.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (fs *memFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	name = filepath.Clean(name)
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	d, ok := fs.files[name]
+	switch {
+	case ok && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+	case !ok && flag&os.O_CREATE == 0:
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	case !ok:
+		if dir := filepath.Dir(name); !fs.dirs[dir] && dir != filepath.Dir(dir) {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		}
+		d = &memData{modTime: time.Now()}
+		fs.files[name] = d
+	}
+	if flag&os.O_TRUNC != 0 {
+		d.data = nil
+	}
+	return &memFile{fs: fs, name: name, d: d}, nil
+}
+
+func (fs *memFS) MkdirAll(path string, perm os.FileMode) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	for dir := filepath.Clean(path); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if _, ok := fs.files[dir]; ok {
+			return &os.PathError{Op: "mkdir", Path: dir, Err: os.ErrExist}
+		}
+		fs.dirs[dir] = true
+	}
+	return nil
+}
+
+func (fs *memFS) Stat(name string) (os.FileInfo, error) {
+	name = filepath.Clean(name)
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	if d, ok := fs.files[name]; ok {
+		return memInfo{name: filepath.Base(name), size: int64(len(d.data)), modTime: d.modTime}, nil
+	}
+	if fs.dirs[name] {
+		return memInfo{name: filepath.Base(name), dir: true}, nil
+	}
+	return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+}
+
+func (fs *memFS) Remove(name string) error {
+	name = filepath.Clean(name)
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	if _, ok := fs.files[name]; ok {
+		delete(fs.files, name)
+		return nil
+	}
+	if fs.dirs[name] {
+		prefix := name + string(filepath.Separator)
+		for other := range fs.files {
+			if strings.HasPrefix(other, prefix) {
+				return &os.PathError{Op: "remove", Path: name, Err: os.ErrExist}
+			}
+		}
+		for other := range fs.dirs {
+			if strings.HasPrefix(other, prefix) {
+				return &os.PathError{Op: "remove", Path: name, Err: os.ErrExist}
+			}
+		}
+		delete(fs.dirs, name)
+		return nil
+	}
+	return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+}
+
+func (fs *memFS) Rename(oldPath string, newPath string) error {
+	oldPath, newPath = filepath.Clean(oldPath), filepath.Clean(newPath)
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	d, ok := fs.files[oldPath]
+	if !ok {
+		return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: os.ErrNotExist}
+	}
+	if !fs.dirs[filepath.Dir(newPath)] {
+		return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: os.ErrNotExist}
+	}
+	delete(fs.files, oldPath)
+	fs.files[newPath] = d
+	return nil
+}
+
+// names 返回全部文件的路径
+func (fs *memFS) names() []string {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	names := make([]string, 0, len(fs.files))
+	for name := range fs.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// content 返回文件内容
+func (fs *memFS) content(name string) (string, bool) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	d, ok := fs.files[filepath.Clean(name)]
+	if !ok {
+		return "", false
+	}
+	return string(d.data), true
+}
+
+// memFile 已打开的内存文件
+type memFile struct {
+	fs     *memFS
+	name   string
+	d      *memData
+	offset int64
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	f.fs.mutex.Lock()
+	defer f.fs.mutex.Unlock()
+	if f.offset >= int64(len(f.d.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.d.data[f.offset:])
+	f.offset += int64(n)
+	return n, nil
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	f.fs.mutex.Lock()
+	defer f.fs.mutex.Unlock()
+	if end := f.offset + int64(len(p)); end > int64(len(f.d.data)) {
+		f.d.data = append(f.d.data, make([]byte, end-int64(len(f.d.data)))...)
+	}
+	n := copy(f.d.data[f.offset:], p)
+	f.offset += int64(n)
+	f.d.modTime = time.Now()
+	return n, nil
+}
+
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	f.fs.mutex.Lock()
+	defer f.fs.mutex.Unlock()
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(len(f.d.data))
+	}
+	if offset < 0 {
+		return 0, os.ErrInvalid
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *memFile) Close() error { return nil }
+
+func (f *memFile) Name() string { return f.name }
+
+// memInfo 内存文件的信息
+type memInfo struct {
+	name    string
+	size    int64
+	dir     bool
+	modTime time.Time
+}
+
+func (i memInfo) Name() string { return i.name }
+
+func (i memInfo) Size() int64 { return i.size }
+
+func (i memInfo) Mode() os.FileMode {
+	if i.dir {
+		return os.ModeDir | 0755
+	}
+	return 0644
+}
+
+func (i memInfo) ModTime() time.Time { return i.modTime }
+
+func (i memInfo) IsDir() bool { return i.dir }
+
+func (i memInfo) Sys() any { return nil }
+
+// slowFS 重命名前等待 delay 的文件系统
+type slowFS struct {
+	FileSystem
+	delay time.Duration
+}
+
+func (fs slowFS) Rename(oldPath string, newPath string) error {
+	time.Sleep(fs.delay)
+	return fs.FileSystem.Rename(oldPath, newPath)
+}
+
+// writeRecordingFS 记录写操作的文件系统
+type writeRecordingFS struct {
+	FileSystem
+	mutex  sync.Mutex
+	writes []string
+}
+
+func (fs *writeRecordingFS) record(op string, name string) {
+	fs.mutex.Lock()
+	fs.writes = append(fs.writes, op+" "+name)
+	fs.mutex.Unlock()
+}
+
+func (fs *writeRecordingFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		fs.record("open", name)
+	}
+	return fs.FileSystem.OpenFile(name, flag, perm)
+}
+
+func (fs *writeRecordingFS) MkdirAll(path string, perm os.FileMode) error {
+	fs.record("mkdir", path)
+	return fs.FileSystem.MkdirAll(path, perm)
+}
+
+func (fs *writeRecordingFS) Remove(name string) error {
+	fs.record("remove", name)
+	return fs.FileSystem.Remove(name)
+}
+
+func (fs *writeRecordingFS) Rename(oldPath string, newPath string) error {
+	fs.record("rename", newPath)
+	return fs.FileSystem.Rename(oldPath, newPath)
+}
+
+func TestInMemoryFileSystem(t *testing.T) {
+	fs := newMemFS()
+	root := filepath.Join(string(filepath.Separator), "virtual", "storage")
+	s := NewStorage(WithStorageDirectory(root), WithFileSystem(fs))
+	result, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: "docs"}, openBytes([]byte("in memory")), "a.txt", 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, ok := fs.content(result.PathAbs); !ok || content != "in memory" {
+		t.Errorf("memory fs content = %q, %v", content, ok)
+	}
+	if names := fs.names(); len(names) != 1 {
+		t.Errorf("memory fs files = %v, want only the stored file", names)
+	}
+	if _, err = os.Stat(root); !os.IsNotExist(err) {
+		t.Errorf("local disk was touched: %v", err)
+	}
+	again, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: "docs"}, openBytes([]byte("in memory")), "b.txt", 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.PathAbs != result.PathAbs || again.Created {
+		t.Errorf("same content = %+v, want the existing file", again)
+	}
+}
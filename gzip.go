@@ -0,0 +1,56 @@
+package fileupload
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"path"
+	"strings"
+)
+
+// defaultMaxDecompressedSize 解压gzip上传内容的默认大小上限
+const defaultMaxDecompressedSize = 1 << 30
+
+// WithDecompressOnUpload 存储前解压gzip压缩的上传内容(Content-Encoding: gzip 或 .gz 后缀文件名)
+// 存储的是解压后的原始内容, 哈希值基于解压后的内容计算, 文件后缀去除 .gz; 解压后的大小上限见 WithMaxDecompressedSize
+func WithDecompressOnUpload(decompress bool) Opts {
+	return func(s *Storage) { s.decompressOnUpload = decompress }
+}
+
+// WithMaxDecompressedSize 解压gzip上传内容后的大小上限(防止gzip炸弹), 为0时使用默认值(1GiB), 小于0时不限制
+// 超出时返回 ErrDecompressedTooLarge; 同时设置 WithMaxFileSize 时解压后的内容还需不超出单个文件的大小上限
+func WithMaxDecompressedSize(bytes int64) Opts {
+	return func(s *Storage) { s.maxDecompressedSize = bytes }
+}
+
+// decompressedLimit 解压后的大小上限, 小于等于0时不限制
+func (s *Storage) decompressedLimit() int64 {
+	if s.maxDecompressedSize == 0 {
+		return defaultMaxDecompressedSize
+	}
+	return s.maxDecompressedSize
+}
+
+// decompressLimitReader 读取的解压内容超出 limit 字节时返回 ErrDecompressedTooLarge, limit 小于等于0时不限制
+type decompressLimitReader struct {
+	r     io.Reader
+	limit int64
+	n     int64
+}
+
+func (l *decompressLimitReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	if l.limit > 0 && l.n > l.limit {
+		return n, fmt.Errorf("%w: more than %d bytes", ErrDecompressedTooLarge, l.limit)
+	}
+	return n, err
+}
+
+// isGzipPart 表单文件是否为gzip压缩数据
+func isGzipPart(file *multipart.FileHeader) bool {
+	if strings.EqualFold(strings.TrimSpace(file.Header.Get("Content-Encoding")), "gzip") {
+		return true
+	}
+	return strings.EqualFold(path.Ext(file.Filename), ".gz")
+}
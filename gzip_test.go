@@ -0,0 +1,89 @@
+package fileupload
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"testing"
+)
+
+func TestDecompressOnUpload(t *testing.T) {
+	const original = "some text that was gzipped by the client\n"
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	if _, err := zw.Write([]byte(original)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	s, _ := newTestStorage(t, WithDecompressOnUpload(true))
+	files := formFileHeaders(t, testFile{field: "file", filename: "notes.txt.gz", content: buf.String()})
+	results, err := s.MultipartCopy(&FileStorage{}, files...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := results[0]
+	if got := readFile(t, result.PathAbs); got != original {
+		t.Errorf("stored content = %q, want the decompressed original", got)
+	}
+	if result.FileExt != ".txt" {
+		t.Errorf("FileExt = %q, want .txt", result.FileExt)
+	}
+	hash, _, err := s.HashReader(bytes.NewReader([]byte(original)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Hash != hash {
+		t.Errorf("Hash = %s, want the hash of the decompressed content %s", result.Hash, hash)
+	}
+}
+
+// gzipBomb 解压后为 size 字节0的gzip数据
+func gzipBomb(t *testing.T, size int) string {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw, err := gzip.NewWriterLevel(buf, gzip.BestCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = zw.Write(make([]byte, size)); err != nil {
+		t.Fatal(err)
+	}
+	if err = zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestDecompressOnUploadLimit(t *testing.T) {
+	bomb := gzipBomb(t, 8<<20)
+	for _, tc := range []struct {
+		name string
+		opts []Opts
+		want error
+	}{
+		{"decompressed size", []Opts{WithMaxDecompressedSize(1 << 20)}, ErrDecompressedTooLarge},
+		{"file size", []Opts{WithMaxFileSize(1 << 20)}, ErrFileTooLarge},
+	} {
+		fs := &writeRecordingFS{FileSystem: osFileSystem{}}
+		s, dir := newTestStorage(t, append(tc.opts, WithDecompressOnUpload(true), WithFileSystem(fs))...)
+		_, err := s.MultipartCopy(&FileStorage{}, formFileHeaders(t, testFile{field: "file", filename: "zeros.bin.gz", content: bomb})...)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%s: err = %v, want %v", tc.name, err, tc.want)
+		}
+		// 计算哈希值时即停止解压, 不写入文件
+		if len(fs.writes) != 0 || len(listTree(t, dir)) != 0 {
+			t.Errorf("%s: writes = %v", tc.name, fs.writes)
+		}
+	}
+
+	s, _ := newTestStorage(t, WithDecompressOnUpload(true), WithMaxDecompressedSize(-1))
+	results, err := s.MultipartCopy(&FileStorage{}, formFileHeaders(t, testFile{field: "file", filename: "zeros.bin.gz", content: bomb})...)
+	if err != nil || results[0].Size != 8<<20 {
+		t.Errorf("unlimited = %v, want the full 8MiB", err)
+	}
+	if limit := NewStorage().decompressedLimit(); limit != defaultMaxDecompressedSize {
+		t.Errorf("default limit = %d, want %d", limit, defaultMaxDecompressedSize)
+	}
+}
@@ -0,0 +1,72 @@
+package fileupload
+
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"math/big"
+	"strings"
+)
+
+// HashEncoding 文件哈希值(文件名)的编码方式, 均可安全用于文件名及URL
+type HashEncoding int
+
+const (
+	HashHex    HashEncoding = iota // 十六进制(64个字符)
+	HashBase32                     // 小写base32且无填充(52个字符)
+	HashBase58                     // base58(约44个字符), 区分大小写, 不适用于大小写不敏感的文件系统
+)
+
+// String 编码方式名称
+func (e HashEncoding) String() string {
+	switch e {
+	case HashBase32:
+		return "base32"
+	case HashBase58:
+		return "base58"
+	default:
+		return "hex"
+	}
+}
+
+// WithHashEncoding 文件哈希值的编码方式, 默认 HashHex
+func WithHashEncoding(enc HashEncoding) Opts {
+	return func(s *Storage) { s.hashEncoding = enc }
+}
+
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// base58Alphabet base58编码字母表(比特币), 不含易混淆的 0 O I l
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// encode 编码哈希摘要
+func (e HashEncoding) encode(sum []byte) string {
+	switch e {
+	case HashBase32:
+		return strings.ToLower(base32NoPadding.EncodeToString(sum))
+	case HashBase58:
+		return base58Encode(sum)
+	default:
+		return hex.EncodeToString(sum)
+	}
+}
+
+// base58Encode base58编码, 前导的0字节编码为 '1'
+func base58Encode(b []byte) string {
+	var encoded []byte
+	n := new(big.Int).SetBytes(b)
+	radix, mod := big.NewInt(58), new(big.Int)
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		encoded = append(encoded, base58Alphabet[mod.Int64()])
+	}
+	for _, v := range b {
+		if v != 0 {
+			break
+		}
+		encoded = append(encoded, base58Alphabet[0])
+	}
+	for i, j := 0, len(encoded)-1; i < j; i, j = i+1, j-1 {
+		encoded[i], encoded[j] = encoded[j], encoded[i]
+	}
+	return string(encoded)
+}
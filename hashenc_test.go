@@ -0,0 +1,78 @@
+package fileupload
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBase58Encode(t *testing.T) {
+	for _, tc := range []struct {
+		in   []byte
+		want string
+	}{
+		{[]byte("Hello World!"), "2NEpo7TZRRrLZSi2U"},
+		{[]byte{0, 0, 1}, "112"},
+		{nil, ""},
+	} {
+		if got := base58Encode(tc.in); got != tc.want {
+			t.Errorf("base58Encode(%v) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestHashEncodingNames(t *testing.T) {
+	store := func(enc HashEncoding) *FileStorageResult {
+		t.Helper()
+		s, _ := newTestStorage(t, WithHashEncoding(enc))
+		result, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("same content")), "a.txt", 12)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	hex, base32, base58 := store(HashHex), store(HashBase32), store(HashBase58)
+	if again := store(HashBase58); again.Hash != base58.Hash {
+		t.Errorf("base58 name is not deterministic: %s != %s", again.Hash, base58.Hash)
+	}
+	if len(hex.Hash) != 64 || len(base32.Hash) != 52 || len(base58.Hash) >= len(base32.Hash) {
+		t.Errorf("hash lengths = %d, %d, %d", len(hex.Hash), len(base32.Hash), len(base58.Hash))
+	}
+	for _, v := range []*FileStorageResult{hex, base32, base58} {
+		if strings.ContainsAny(v.Hash, "/+=") {
+			t.Errorf("%s hash %q is not file and URL safe", v.HashEncoding, v.Hash)
+		}
+		if v.Name != v.Hash+".txt" {
+			t.Errorf("Name = %s, want %s.txt", v.Name, v.Hash)
+		}
+	}
+	if base58.HashEncoding != "base58" || hex.HashEncoding != "hex" {
+		t.Errorf("HashEncoding = %q, %q", base58.HashEncoding, hex.HashEncoding)
+	}
+}
+
+func TestHashReader(t *testing.T) {
+	for _, tc := range []struct {
+		enc  HashEncoding
+		want string
+	}{
+		{HashHex, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{HashBase32, "xj4bnp4pahh6uqkbidpf3lrceoyagyndsylxvhfucd7wd4qacwwq"},
+	} {
+		s := NewStorage(WithHashEncoding(tc.enc))
+		hash, size, err := s.HashReader(strings.NewReader("abc"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hash != tc.want || size != 3 {
+			t.Errorf("%s: HashReader = %s/%d, want %s/3", tc.enc, hash, size, tc.want)
+		}
+	}
+	s, _ := newTestStorage(t)
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("abc")), "abc.txt", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash, _, _ := s.HashReader(strings.NewReader("abc")); hash != result.Hash {
+		t.Errorf("HashReader = %s, stored hash = %s", hash, result.Hash)
+	}
+}
@@ -0,0 +1,38 @@
+package fileupload
+
+import (
+	"crypto/sha256"
+	"hash"
+)
+
+// HashingWriter 计算写入内容的哈希值, 与存储时用于命名的文件哈希值一致(编码方式见 WithHashEncoding)
+// 可与 io.MultiWriter, io.TeeReader 配合在写入自有目标的同时计算哈希值; 不能并发写入
+type HashingWriter struct {
+	hash     hash.Hash
+	encoding HashEncoding
+	size     int64
+}
+
+// NewHashingWriter 创建使用当前存储配置计算哈希值的 HashingWriter
+func (s *Storage) NewHashingWriter() *HashingWriter {
+	return &HashingWriter{
+		hash:     sha256.New(),
+		encoding: s.hashEncoding,
+	}
+}
+
+func (w *HashingWriter) Write(p []byte) (int, error) {
+	n, err := w.hash.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Sum 已写入内容的哈希值
+func (w *HashingWriter) Sum() string {
+	return w.encoding.encode(w.hash.Sum(nil))
+}
+
+// Size 已写入的字节数
+func (w *HashingWriter) Size() int64 {
+	return w.size
+}
@@ -0,0 +1,35 @@
+package fileupload
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestHashingWriter(t *testing.T) {
+	for _, enc := range []HashEncoding{HashHex, HashBase32, HashBase58} {
+		s, _ := newTestStorage(t, WithHashEncoding(enc))
+		buf := &bytes.Buffer{}
+		w := s.NewHashingWriter()
+		mw := io.MultiWriter(buf, w)
+		for _, chunk := range []string{"generated ", "server-side ", "content"} {
+			if _, err := io.WriteString(mw, chunk); err != nil {
+				t.Fatal(err)
+			}
+		}
+		hash, size, err := s.HashReader(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w.Sum() != hash || w.Size() != size {
+			t.Errorf("%s: HashingWriter = %s/%d, HashReader = %s/%d", enc, w.Sum(), w.Size(), hash, size)
+		}
+		result, err := s.CopyMultipartFile(&FileStorage{}, openBytes(buf.Bytes()), "generated.txt", int64(buf.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Name != w.Sum()+".txt" {
+			t.Errorf("%s: stored as %s, want %s.txt", enc, result.Name, w.Sum())
+		}
+	}
+}
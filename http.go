@@ -0,0 +1,281 @@
+package fileupload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// defaultMaxMemory 表单解析时内存中保存的最大字节数(与echo一致), 超出部分写入临时文件
+const defaultMaxMemory = 32 << 20
+
+// WithFormMaxMemory 解析表单时内存中保存的最大字节数, 超出部分写入临时文件; 默认32MB
+// 临时文件默认由 mime/multipart 写入 os.TempDir(), 可通过 WithFormTempDirectory 指定目录; 存储完成或出错后均会删除(见 WithKeepFormTemp)
+func WithFormMaxMemory(n int64) Opts {
+	return func(s *Storage) { s.formMaxMemory = n }
+}
+
+// WithMaxRequestBodySize 请求体大小上限, HTTP, Echo, Fiber 等解析表单时以 http.MaxBytesReader 限制读取的字节数
+// 声明的 Content-Length 或实际读取的字节数超出上限时返回 ErrRequestTooLarge; 小于等于0时不限制
+func WithMaxRequestBodySize(n int64) Opts {
+	return func(s *Storage) { s.maxRequestBodySize = n }
+}
+
+// WithKeepFormTemp 将表单上传文件复制至本地目录 directory 保留(用于排查上传内容损坏等问题), 并通过 WithLogger 记录复制后的路径; 为空时不保留
+// 表单临时文件本身总是在存储完成后删除(net/http 在请求结束时同样会删除)
+func WithKeepFormTemp(directory string) Opts {
+	return func(s *Storage) { s.keepFormTemp = directory }
+}
+
+// formFile 表单上传文件
+type formFile struct {
+	field  string                // 表单字段名
+	header *multipart.FileHeader // 文件
+}
+
+// HTTP 文件上传net/http, 同一请求内的重复内容只写入一次(见 WithRequestDedup)
+func (s *Storage) HTTP(r *http.Request, param *FileStorage, name *MultipartFileName) (succeeded []*FileStorageResult, err error) {
+	if name == nil || name.empty() {
+		return
+	}
+	form, err := s.parseForm(r, param)
+	if err != nil {
+		return
+	}
+	defer s.removeForm(form)
+	return s.formCopy(r.Context(), form, param, name)
+}
+
+// HTTPFormFiles 收集请求中单文件及多文件字段的上传文件而不存储, 用于存储前检查(如文件名, 大小)
+// 解析后的表单保留在请求中, 之后调用 HTTP 存储时复用并清理表单临时文件; 不再存储时需调用 RemoveForm(r.MultipartForm)
+// 配置 WithFormTempDirectory 时返回的文件需通过 OpenFormFile 打开, 未调用 RemoveForm 的表单在请求的上下文结束后清理, 之后不可再打开
+func (s *Storage) HTTPFormFiles(r *http.Request, name *MultipartFileName) (files []*multipart.FileHeader, err error) {
+	if name == nil || name.empty() {
+		return
+	}
+	form, err := s.parseForm(r, nil)
+	if err != nil {
+		return
+	}
+	fields, err := formFiles(form, name)
+	if err != nil {
+		return
+	}
+	files = make([]*multipart.FileHeader, 0, len(fields))
+	for _, v := range fields {
+		files = append(files, v.header)
+	}
+	return
+}
+
+// formCopy 存储已解析表单中的上传文件及base64字段
+func (s *Storage) formCopy(ctx context.Context, form *multipart.Form, param *FileStorage, name *MultipartFileName) (succeeded []*FileStorageResult, err error) {
+	defer func() { succeeded = flattenLinks(succeeded) }()
+	b := newBatch(ctx)
+	if s.requestDedup == RequestDedupMerge {
+		defer func() { succeeded = b.merge(succeeded) }()
+	}
+	defer func() { succeeded = s.rollback(b, succeeded, err) }()
+	if err = s.checkFormSize(form, name); err != nil {
+		return
+	}
+	// single file
+	file, err := singleFormFile(form, name)
+	if err != nil {
+		return
+	}
+	if file != nil {
+		var tmp *FileStorageResult
+		tmp, err = s.multipartCopy(param, file, b)
+		if err != nil {
+			return
+		}
+		tmp.Field = name.Single
+		succeeded = append(succeeded, tmp)
+	}
+	// multiple files
+	for _, field := range multipleFields(name) {
+		var tmp []*FileStorageResult
+		tmp, err = s.multipartCopyAll(param, b, form.File[field]...)
+		for _, v := range tmp {
+			v.Field = field
+		}
+		succeeded = append(succeeded, tmp...)
+		if err != nil {
+			return
+		}
+	}
+	// base64 fields
+	for _, field := range name.Base64Fields {
+		var values [][]byte
+		for _, value := range form.Value[field] {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, []byte(value))
+			}
+		}
+		var tmp []*FileStorageResult
+		tmp, err = s.base64CopyAll(param, b, values, nil)
+		for _, v := range tmp {
+			v.Field = field
+		}
+		succeeded = append(succeeded, tmp...)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// parseForm 解析multipart表单, 调用方负责清理表单临时文件
+func (s *Storage) parseForm(r *http.Request, param *FileStorage) (*multipart.Form, error) {
+	maxMemory := s.formMaxMemory
+	if param != nil && param.FormMaxMemory > 0 {
+		maxMemory = param.FormMaxMemory
+	}
+	if maxMemory <= 0 {
+		maxMemory = defaultMaxMemory
+	}
+	if limit := s.maxRequestBodySize; limit > 0 {
+		if r.ContentLength > limit {
+			return nil, fmt.Errorf("%w: content length %d, limit %d bytes", ErrRequestTooLarge, r.ContentLength, limit)
+		}
+		r.Body = http.MaxBytesReader(nil, r.Body, limit)
+	}
+	var err error
+	if s.formTempDir != "" {
+		_, err = s.readForm(r, maxMemory)
+	} else {
+		err = r.ParseMultipartForm(maxMemory)
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			err = fmt.Errorf("%w: limit %d bytes", ErrRequestTooLarge, tooLarge.Limit)
+		}
+		return nil, err
+	}
+	return r.MultipartForm, nil
+}
+
+// removeForm 删除表单临时文件, 配置 WithKeepFormTemp 时先复制保留表单上传文件
+func (s *Storage) removeForm(form *multipart.Form) {
+	if s.keepFormTemp != "" {
+		s.keepForm(form)
+	}
+	_ = s.RemoveForm(form)
+}
+
+// keepForm 将表单上传文件复制至 WithKeepFormTemp 指定的目录并记录复制后的路径
+func (s *Storage) keepForm(form *multipart.Form) {
+	if err := os.MkdirAll(s.keepFormTemp, 0755); err != nil {
+		if s.logger != nil {
+			s.logger.Warn("keep multipart form files failed", "path", s.keepFormTemp, "error", err)
+		}
+		return
+	}
+	for field, files := range form.File {
+		for _, file := range files {
+			name, err := s.keepFormFile(file)
+			if s.logger == nil {
+				continue
+			}
+			if err != nil {
+				s.logger.Warn("keep multipart form file failed", "field", field, "filename", file.Filename, "error", err)
+				continue
+			}
+			s.logger.Debug("kept multipart form file", "field", field, "filename", file.Filename, "path", name)
+		}
+	}
+}
+
+// keepFormFile 将表单上传文件复制至 WithKeepFormTemp 指定的目录, 返回复制后的路径
+func (s *Storage) keepFormFile(file *multipart.FileHeader) (name string, err error) {
+	src, err := s.OpenFormFile(file)
+	if err != nil {
+		return
+	}
+	defer func() { _ = src.Close() }()
+	dst, err := os.CreateTemp(s.keepFormTemp, "form.*"+path.Ext(s.sanitizeFilename(file.Filename)))
+	if err != nil {
+		return
+	}
+	name = dst.Name()
+	if _, err = s.buffers.copy(dst, src); err != nil {
+		_ = dst.Close()
+		return
+	}
+	err = dst.Close()
+	return
+}
+
+// checkFormSize 写入任何文件之前检查表单内全部上传文件(含base64字段)的总大小
+func (s *Storage) checkFormSize(form *multipart.Form, name *MultipartFileName) (err error) {
+	if s.maxTotalSize <= 0 {
+		return
+	}
+	files, err := formFiles(form, name)
+	if err != nil {
+		return
+	}
+	headers := make([]*multipart.FileHeader, 0, len(files))
+	for _, v := range files {
+		headers = append(headers, v.header)
+	}
+	extra := int64(0)
+	for _, field := range name.Base64Fields {
+		for _, value := range form.Value[field] {
+			extra += int64(len(value))
+		}
+	}
+	return s.checkTotalSize(headers, extra)
+}
+
+// singleFormFile 获取单文件字段的第一个文件, 字段缺失且为可选字段时返回 nil
+func singleFormFile(form *multipart.Form, name *MultipartFileName) (*multipart.FileHeader, error) {
+	if name.Single == "" {
+		return nil, nil
+	}
+	if files := form.File[name.Single]; len(files) > 0 {
+		return files[0], nil
+	}
+	if name.SingleOptional {
+		return nil, nil
+	}
+	return nil, fmt.Errorf("%w: %s", http.ErrMissingFile, name.Single)
+}
+
+// multipleFields 多文件字段名
+func multipleFields(name *MultipartFileName) []string {
+	fields := make([]string, 0, len(name.Fields)+1)
+	if name.Multiple != "" {
+		fields = append(fields, name.Multiple)
+	}
+	for _, field := range name.Fields {
+		if field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// formFiles 收集表单中单文件及多文件字段的上传文件
+func formFiles(form *multipart.Form, name *MultipartFileName) (files []*formFile, err error) {
+	file, err := singleFormFile(form, name)
+	if err != nil {
+		return
+	}
+	if file != nil {
+		files = append(files, &formFile{field: name.Single, header: file})
+	}
+	for _, field := range multipleFields(name) {
+		for _, file := range form.File[field] {
+			files = append(files, &formFile{field: field, header: file})
+		}
+	}
+	return
+}
@@ -0,0 +1,171 @@
+package fileupload
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestHTTPMultipleFields(t *testing.T) {
+	s, _ := newTestStorage(t)
+	r := multipartRequest(t, []testFile{
+		{field: "avatar", filename: "me.txt", content: "avatar"},
+		{field: "documents[]", filename: "doc1.txt", content: "doc1"},
+		{field: "documents[]", filename: "doc2.txt", content: "doc2"},
+		{field: "attachments[]", filename: "att.txt", content: "att"},
+	})
+	name := &MultipartFileName{Single: "avatar", Fields: []string{"documents[]", "attachments[]"}}
+	results, err := s.HTTP(r, &FileStorage{}, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]string{{"avatar", "me.txt"}, {"documents[]", "doc1.txt"}, {"documents[]", "doc2.txt"}, {"attachments[]", "att.txt"}}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+	for i, v := range want {
+		if results[i].Field != v[0] || results[i].OriginName != v[1] {
+			t.Errorf("result %d = %s %s, want %s %s", i, results[i].Field, results[i].OriginName, v[0], v[1])
+		}
+	}
+}
+
+func TestHTTPBase64Fields(t *testing.T) {
+	s, _ := newTestStorage(t)
+	image := pngBytes(t, 4, 4)
+	r := multipartRequest(t, []testFile{{field: "file", filename: "a.txt", content: "file part"}},
+		"inline", string(dataURI("image/png", image)))
+	results, err := s.HTTP(r, &FileStorage{}, &MultipartFileName{Single: "file", Base64Fields: []string{"inline"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if results[0].Field != "file" || readFile(t, results[0].PathAbs) != "file part" {
+		t.Errorf("file part result = %+v", results[0])
+	}
+	if results[1].Field != "inline" || results[1].FileExt != ".png" || readFile(t, results[1].PathAbs) != string(image) {
+		t.Errorf("base64 field result = %+v", results[1])
+	}
+}
+
+func TestHTTPMissingSingleField(t *testing.T) {
+	s, _ := newTestStorage(t)
+	files := []testFile{{field: "files", filename: "a.txt", content: "a"}}
+	_, err := s.HTTP(multipartRequest(t, files), &FileStorage{}, &MultipartFileName{Single: "avatar", Multiple: "files"})
+	if !errors.Is(err, http.ErrMissingFile) {
+		t.Errorf("required field: err = %v, want http.ErrMissingFile", err)
+	}
+	results, err := s.HTTP(multipartRequest(t, files), &FileStorage{}, &MultipartFileName{Single: "avatar", SingleOptional: true, Multiple: "files"})
+	if err != nil {
+		t.Fatalf("optional field: %v", err)
+	}
+	if len(results) != 1 || results[0].Field != "files" {
+		t.Errorf("results = %+v, want only the multiple field", results)
+	}
+	results, err = s.HTTP(multipartRequest(t, files), &FileStorage{}, &MultipartFileName{Multiple: "missing"})
+	if err != nil || len(results) != 0 {
+		t.Errorf("missing multiple field = %v, %v, want no results", results, err)
+	}
+}
+
+func TestHTTPMaxRequestBodySize(t *testing.T) {
+	files := []testFile{{field: "files", filename: "a.txt", content: strings.Repeat("a", 4096)}}
+	name := &MultipartFileName{Multiple: "files"}
+
+	s, dir := newTestStorage(t, WithMaxRequestBodySize(1024))
+	if _, err := s.HTTP(multipartRequest(t, files), &FileStorage{}, name); !errors.Is(err, ErrRequestTooLarge) {
+		t.Errorf("declared length: err = %v, want ErrRequestTooLarge", err)
+	}
+	// 未声明长度(如分块传输)时读取超出上限即停止
+	r := multipartRequest(t, files)
+	r.ContentLength = -1
+	r.Body = io.NopCloser(io.MultiReader(r.Body))
+	if _, err := s.HTTP(r, &FileStorage{}, name); !errors.Is(err, ErrRequestTooLarge) {
+		t.Errorf("streamed body: err = %v, want ErrRequestTooLarge", err)
+	}
+	if got := countFiles(t, dir); got != 0 {
+		t.Errorf("%d files stored from oversized bodies", got)
+	}
+
+	s, _ = newTestStorage(t, WithMaxRequestBodySize(1<<20))
+	if results, err := s.HTTP(multipartRequest(t, files), &FileStorage{}, name); err != nil || len(results) != 1 {
+		t.Errorf("body within the limit = %v, %v", results, err)
+	}
+}
+
+func TestFormFiles(t *testing.T) {
+	s, dir := newTestStorage(t)
+	r := multipartRequest(t, []testFile{
+		{field: "avatar", filename: "me.png", content: "avatar"},
+		{field: "files", filename: "a.txt", content: "a"},
+		{field: "files", filename: "b.txt", content: "bb"},
+		{field: "ignored", filename: "c.txt", content: "c"},
+	})
+	name := &MultipartFileName{Single: "avatar", Multiple: "files"}
+	c := echo.New().NewContext(r, httptest.NewRecorder())
+	files, err := s.FormFiles(c, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("got %d files, want 3", len(files))
+	}
+	if files[0].Filename != "me.png" || files[2].Filename != "b.txt" || files[2].Size != 2 {
+		t.Errorf("files = %s %s %d", files[0].Filename, files[2].Filename, files[2].Size)
+	}
+	if got := countFiles(t, dir); got != 0 {
+		t.Errorf("%d files stored while collecting", got)
+	}
+	// 检查之后存储时复用已解析的表单
+	results, err := s.HTTP(r, &FileStorage{}, name)
+	if err != nil || len(results) != 3 {
+		t.Errorf("store after collecting = %d results, %v", len(results), err)
+	}
+}
+
+func TestKeepFormTemp(t *testing.T) {
+	keep, formTemp := t.TempDir(), t.TempDir()
+	logger := &captureLogger{}
+	s, _ := newTestStorage(t, WithKeepFormTemp(keep), WithLogger(logger), WithFormTempDirectory(formTemp), WithFormMaxMemory(1024))
+	files := []testFile{
+		{field: "files", filename: "large.txt", content: strings.Repeat("x", 4096)},
+		{field: "files", filename: "small.txt", content: "small"},
+	}
+	if _, err := s.HTTP(multipartRequest(t, files), &FileStorage{}, &MultipartFileName{Multiple: "files"}); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(keep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := map[string]bool{}
+	for _, entry := range entries {
+		kept[readFile(t, filepath.Join(keep, entry.Name()))] = true
+	}
+	if len(kept) != 2 || !kept[files[0].content] || !kept[files[1].content] {
+		t.Errorf("kept %d files, want copies of both uploads", len(entries))
+	}
+	if n := strings.Count(strings.Join(logger.entries, "\n"), "debug kept multipart form file"); n != 2 {
+		t.Errorf("logged %d kept files, want 2: %q", n, logger.entries)
+	}
+	assertEmptyDir(t, formTemp)
+
+	// 默认不保留
+	s, _ = newTestStorage(t, WithFormTempDirectory(formTemp), WithFormMaxMemory(1024))
+	if _, err = s.HTTP(multipartRequest(t, files), &FileStorage{}, &MultipartFileName{Multiple: "files"}); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ = os.ReadDir(keep); len(entries) != 2 {
+		t.Errorf("keep directory has %d files, want only the 2 kept earlier", len(entries))
+	}
+	assertEmptyDir(t, formTemp)
+}
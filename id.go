@@ -0,0 +1,41 @@
+package fileupload
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// idTimestampShift 默认id中时间戳(毫秒)左移的位数, 低位为同一毫秒内的序号
+const idTimestampShift = 22
+
+// WithIDGenerator 存储结果 Uid 生成函数, 需保证并发调用时返回的值唯一且非0
+func WithIDGenerator(generate func() int64) Opts {
+	return func(s *Storage) { s.idGenerator = generate }
+}
+
+// idSequence 默认id生成器, 生成按时间单调递增的id
+type idSequence struct {
+	last atomic.Int64
+}
+
+// next 以当前时间 now 生成下一个id, 时钟回拨时在上一个id的基础上递增
+func (q *idSequence) next(now time.Time) int64 {
+	for {
+		last := q.last.Load()
+		id := now.UnixMilli() << idTimestampShift
+		if id <= last {
+			id = last + 1
+		}
+		if q.last.CompareAndSwap(last, id) {
+			return id
+		}
+	}
+}
+
+// nextID 生成存储结果 Uid
+func (s *Storage) nextID() int64 {
+	if s.idGenerator != nil {
+		return s.idGenerator()
+	}
+	return s.ids.next(s.now())
+}
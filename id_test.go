@@ -0,0 +1,55 @@
+package fileupload
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrentUIDs(t *testing.T) {
+	s, _ := newTestStorage(t)
+	const n = 32
+	uids := make([]int64, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// 相同内容复用同一文件, 仍为每次存储分配不同的 Uid
+			result, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("same")), "a.txt", 4)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			uids[i] = result.Uid
+		}(i)
+	}
+	wg.Wait()
+	seen := make(map[int64]bool, n)
+	for _, uid := range uids {
+		if uid == 0 || seen[uid] {
+			t.Fatalf("uids = %v, want distinct non-zero values", uids)
+		}
+		seen[uid] = true
+	}
+}
+
+func TestIDSequenceClockRollback(t *testing.T) {
+	var q idSequence
+	now := time.Now()
+	first := q.next(now)
+	if second := q.next(now.Add(-time.Hour)); second <= first {
+		t.Errorf("id after clock rollback = %d, want > %d", second, first)
+	}
+}
+
+func TestIDGenerator(t *testing.T) {
+	s, _ := newTestStorage(t, WithIDGenerator(func() int64 { return 42 }))
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("x")), "a.txt", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Uid != 42 {
+		t.Errorf("Uid = %d, want 42", result.Uid)
+	}
+}
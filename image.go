@@ -0,0 +1,221 @@
+package fileupload
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"path"
+	"path/filepath"
+
+	_ "image/gif"
+	_ "image/png"
+)
+
+// ImageProcessing 图片处理参数
+type ImageProcessing struct {
+	ThumbnailMaxWidth  int // 缩略图最大宽度
+	ThumbnailMaxHeight int // 缩略图最大高度
+}
+
+// thumbnailDirectory 缩略图所在的子目录(位于原图所在目录), 以 . 开头以免与存储文件(包括自定义文件名)冲突
+const thumbnailDirectory = ".thumbnails"
+
+// defaultMaxImagePixels 完整解码图片的默认像素数上限
+const defaultMaxImagePixels = 40000000
+
+// WithImageProcessing 图片处理(宽高解析及缩略图生成), 缩略图按原图比例缩放至不超过最大宽高
+// 默认仅解码标准库支持的 JPEG, PNG, GIF; WebP 需使用构建标签 webp 编译(go build -tags webp)或自行导入解码器(如 golang.org/x/image/webp)
+// 缩略图存储于原图所在目录的 .thumbnails 子目录, 文件名为 存储文件名+.jpg
+func WithImageProcessing(maxWidth int, maxHeight int) Opts {
+	return func(s *Storage) {
+		s.imageProcessing = &ImageProcessing{
+			ThumbnailMaxWidth:  maxWidth,
+			ThumbnailMaxHeight: maxHeight,
+		}
+	}
+}
+
+// WithMaxImagePixels 完整解码图片(生成缩略图, 计算感知哈希)的像素数(宽x高)上限, 防止解压炸弹耗尽内存; 默认 40000000, 小于0时不限制
+// 超出上限的图片仍记录宽高, 但不生成缩略图及感知哈希
+func WithMaxImagePixels(n int64) Opts {
+	return func(s *Storage) { s.maxImagePixels = n }
+}
+
+// decodable 图片像素数是否在完整解码的上限之内
+func (s *Storage) decodable(config image.Config) bool {
+	limit := s.maxImagePixels
+	if limit == 0 {
+		limit = defaultMaxImagePixels
+	}
+	return limit < 0 || int64(config.Width)*int64(config.Height) <= limit
+}
+
+// thumbnailPath 存储文件 name 的缩略图路径
+func thumbnailPath(name string) string {
+	return filepath.Join(filepath.Dir(name), thumbnailDirectory, filepath.Base(name)+".jpg")
+}
+
+// WithMaxConcurrentDerivatives 同时进行的衍生文件(如缩略图)生成任务数上限, 超出时排队等待
+func WithMaxConcurrentDerivatives(n int) Opts {
+	return func(s *Storage) {
+		s.derivatives = nil
+		if n > 0 {
+			s.derivatives = make(chan struct{}, n)
+		}
+	}
+}
+
+// acquireDerivative 获取衍生文件生成任务配额, 返回释放函数
+func (s *Storage) acquireDerivative() (release func()) {
+	if s.derivatives == nil {
+		return func() {}
+	}
+	s.derivatives <- struct{}{}
+	return func() { <-s.derivatives }
+}
+
+// processImage 解析已存储图片的宽高, 生成缩略图并计算感知哈希, 非图片文件不做处理; 内容损坏无法完整解码的图片仅记录宽高
+func (s *Storage) processImage(u *upload) (err error) {
+	dimensions, thumbnail := s.wants(ResultDimensions), s.wants(ResultThumbnail)
+	if s.imageProcessing == nil {
+		dimensions, thumbnail = false, false
+	}
+	if !dimensions && !thumbnail && !s.perceptualHash {
+		return
+	}
+	result := u.result
+	// 压缩或加密存储的文件解码还原后的内容
+	src, err := s.openFile(result.PathAbs, result)
+	if err != nil {
+		return
+	}
+	defer func() { _ = src.Close() }()
+
+	// 先解析图片头部信息, 像素数超出上限时不完整解码
+	head := &bytes.Buffer{}
+	config, _, err := image.DecodeConfig(io.TeeReader(src, head))
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			err = nil
+		}
+		return
+	}
+	if dimensions {
+		result.Width, result.Height = config.Width, config.Height
+	}
+	// 复用已存储的相同内容时, 已存在的缩略图不再重新生成
+	thumbPath := thumbnailPath(result.PathAbs)
+	if thumbnail && !result.Created {
+		if _, ser := u.fs.Stat(thumbPath); ser == nil {
+			result.ThumbnailUri = thumbnailURI(result.PathUri, thumbPath)
+			thumbnail = false
+		}
+	}
+	if !thumbnail && !s.perceptualHash {
+		return
+	}
+	if !s.decodable(config) {
+		if s.logger != nil {
+			s.logger.Warn("image too large to decode", "origin", result.OriginName, "width", config.Width, "height", config.Height)
+		}
+		return
+	}
+
+	release := s.acquireDerivative()
+	defer release()
+
+	// 头部有效但内容损坏的图片仍正常存储, 不生成缩略图及感知哈希
+	img, _, derr := image.Decode(io.MultiReader(head, src))
+	if derr != nil {
+		if s.logger != nil {
+			s.logger.Warn("image decode failed", "origin", result.OriginName, "error", derr)
+		}
+		return
+	}
+	if s.perceptualHash {
+		result.PerceptualHash = dHash(img)
+	}
+	if !thumbnail {
+		return
+	}
+	bounds := img.Bounds()
+	width, height := thumbnailSize(bounds.Dx(), bounds.Dy(), s.imageProcessing.ThumbnailMaxWidth, s.imageProcessing.ThumbnailMaxHeight)
+	thumb := &bytes.Buffer{}
+	if err = jpeg.Encode(thumb, resizeImage(img, width, height), &jpeg.Options{Quality: 85}); err != nil {
+		return
+	}
+
+	if err = u.mkdirAll(filepath.Dir(thumbPath)); err != nil {
+		return
+	}
+	_, ser := u.fs.Stat(thumbPath)
+	if err = u.writeFile(thumbPath, thumb, ser == nil); err != nil {
+		return
+	}
+	result.ThumbnailUri = thumbnailURI(result.PathUri, thumbPath)
+	return
+}
+
+// thumbnailURI 存储文件资源路径 uri 对应的缩略图 thumbPath 的资源路径
+func thumbnailURI(uri string, thumbPath string) string {
+	return siblingURI(uri, path.Join(thumbnailDirectory, filepath.Base(thumbPath)))
+}
+
+// thumbnailSize 保持宽高比计算缩略图尺寸, 最大宽高小于等于0时表示该方向不限制
+func thumbnailSize(width int, height int, maxWidth int, maxHeight int) (int, int) {
+	w, h := width, height
+	if maxWidth > 0 && w > maxWidth {
+		h = h * maxWidth / w
+		w = maxWidth
+	}
+	if maxHeight > 0 && h > maxHeight {
+		w = w * maxHeight / h
+		h = maxHeight
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	return w, h
+}
+
+// resizeImage 区域均值缩放图片
+func resizeImage(src image.Image, width int, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	bounds := src.Bounds()
+	sw, sh := bounds.Dx(), bounds.Dy()
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*sh/height
+		y1 := bounds.Min.Y + (y+1)*sh/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*sw/width
+			x1 := bounds.Min.X + (x+1)*sw/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(b / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}
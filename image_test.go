@@ -0,0 +1,182 @@
+package fileupload
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// probeDecoder 记录同时进行的完整解码数的测试图片格式
+var probeDecoder struct {
+	mutex  sync.Mutex
+	active int
+	max    int
+}
+
+func init() {
+	image.RegisterFormat("probe", "PROBE", func(r io.Reader) (image.Image, error) {
+		probeDecoder.mutex.Lock()
+		probeDecoder.active++
+		probeDecoder.max = max(probeDecoder.max, probeDecoder.active)
+		probeDecoder.mutex.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		probeDecoder.mutex.Lock()
+		probeDecoder.active--
+		probeDecoder.mutex.Unlock()
+		return image.NewGray(image.Rect(0, 0, 1, 1)), nil
+	}, func(r io.Reader) (image.Config, error) {
+		return image.Config{Width: 1, Height: 1}, nil
+	})
+}
+
+func TestImageProcessing(t *testing.T) {
+	s, _ := newTestStorage(t, WithImageProcessing(100, 100))
+	content := pngBytes(t, 400, 200)
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes(content), "photo.png", int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Width != 400 || result.Height != 200 {
+		t.Errorf("dimensions = %dx%d, want 400x200", result.Width, result.Height)
+	}
+	if result.ThumbnailUri == "" {
+		t.Fatal("ThumbnailUri is empty")
+	}
+	thumb := thumbnailPath(result.PathAbs)
+	if filepath.Base(filepath.Dir(thumb)) != thumbnailDirectory {
+		t.Errorf("thumbnail %s is not in %s", thumb, thumbnailDirectory)
+	}
+	f, err := os.Open(thumb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	config, format, err := image.DecodeConfig(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "jpeg" || config.Width != 100 || config.Height != 50 {
+		t.Errorf("thumbnail = %s %dx%d, want jpeg 100x50", format, config.Width, config.Height)
+	}
+}
+
+func TestImageProcessingPixelLimit(t *testing.T) {
+	s, _ := newTestStorage(t, WithImageProcessing(100, 100), WithMaxImagePixels(100*100))
+	content := pngBytes(t, 400, 200)
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes(content), "photo.png", int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Width != 400 || result.Height != 200 {
+		t.Errorf("dimensions = %dx%d, want 400x200", result.Width, result.Height)
+	}
+	if result.ThumbnailUri != "" {
+		t.Errorf("ThumbnailUri = %q, want none over the pixel limit", result.ThumbnailUri)
+	}
+	if _, err = os.Stat(thumbnailPath(result.PathAbs)); !os.IsNotExist(err) {
+		t.Errorf("thumbnail exists over the pixel limit: %v", err)
+	}
+}
+
+func TestImageProcessingSkipsNonImages(t *testing.T) {
+	s, _ := newTestStorage(t, WithImageProcessing(100, 100))
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("plain text")), "a.txt", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Width != 0 || result.ThumbnailUri != "" {
+		t.Errorf("non-image result has image fields: %+v", result)
+	}
+}
+
+func TestImageProcessingCorruptImage(t *testing.T) {
+	s, _ := newTestStorage(t, WithImageProcessing(100, 100), WithPerceptualHash(true), WithAtomicBatch(true))
+	content := pngBytes(t, 64, 64)
+	// 保留有效的头部, 截断图片数据
+	corrupt := content[:len(content)/2]
+	results, err := s.MultipartCopy(&FileStorage{}, formFileHeaders(t,
+		testFile{field: "images", filename: "ok.png", content: string(content)},
+		testFile{field: "images", filename: "corrupt.png", content: string(corrupt)},
+	)...)
+	if err != nil {
+		t.Fatalf("corrupt image failed the batch: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if results[1].Width != 64 || results[1].ThumbnailUri != "" || results[1].PerceptualHash != "" {
+		t.Errorf("corrupt result = %dx%d thumbnail %q phash %q, want dimensions only",
+			results[1].Width, results[1].Height, results[1].ThumbnailUri, results[1].PerceptualHash)
+	}
+	if got := readFile(t, results[1].PathAbs); got != string(corrupt) {
+		t.Error("corrupt image content differs")
+	}
+	if results[0].ThumbnailUri == "" {
+		t.Error("valid image has no thumbnail")
+	}
+}
+
+func TestImageProcessingDuplicateKeepsThumbnail(t *testing.T) {
+	fs := &writeRecordingFS{FileSystem: osFileSystem{}}
+	s, _ := newTestStorage(t, WithImageProcessing(100, 100), WithFileSystem(fs))
+	content := pngBytes(t, 400, 200)
+	first, err := s.CopyMultipartFile(&FileStorage{}, openBytes(content), "photo.png", int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.mutex.Lock()
+	fs.writes = nil
+	fs.mutex.Unlock()
+	again, err := s.CopyMultipartFile(&FileStorage{}, openBytes(content), "copy.png", int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Created || again.ThumbnailUri != first.ThumbnailUri {
+		t.Errorf("duplicate = created %v, thumbnail %q, want %q", again.Created, again.ThumbnailUri, first.ThumbnailUri)
+	}
+	for _, w := range fs.writes {
+		if strings.Contains(w, thumbnailDirectory) {
+			t.Errorf("duplicate upload rewrote the thumbnail: %s", w)
+		}
+	}
+
+	// 缩略图被删除时重新生成
+	if err = os.Remove(thumbnailPath(first.PathAbs)); err != nil {
+		t.Fatal(err)
+	}
+	if again, err = s.CopyMultipartFile(&FileStorage{}, openBytes(content), "copy.png", int64(len(content))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(thumbnailPath(again.PathAbs)); err != nil || again.ThumbnailUri == "" {
+		t.Errorf("thumbnail not regenerated: %v", err)
+	}
+}
+
+func TestMaxConcurrentDerivatives(t *testing.T) {
+	s, _ := newTestStorage(t, WithImageProcessing(8, 8), WithMaxConcurrentDerivatives(2), WithConcurrency(8))
+	files := make([]testFile, 0, 16)
+	for i := 0; i < cap(files); i++ {
+		files = append(files, testFile{field: "images", filename: fmt.Sprintf("%d.probe", i), content: fmt.Sprintf("PROBE%d", i)})
+	}
+	probeDecoder.mutex.Lock()
+	probeDecoder.max = 0
+	probeDecoder.mutex.Unlock()
+	results, err := s.MultipartCopy(&FileStorage{}, formFileHeaders(t, files...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(files) {
+		t.Fatalf("got %d results, want %d", len(results), len(files))
+	}
+	probeDecoder.mutex.Lock()
+	defer probeDecoder.mutex.Unlock()
+	if probeDecoder.max > 2 || probeDecoder.max == 0 {
+		t.Errorf("max concurrent derivatives = %d, want 1..2", probeDecoder.max)
+	}
+}
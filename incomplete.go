@@ -0,0 +1,30 @@
+package fileupload
+
+import (
+	"fmt"
+	"io"
+)
+
+// incompleteReader 读取结束时检查读取的字节数与声明的大小是否一致, 不一致时返回 ErrIncompleteUpload
+type incompleteReader struct {
+	r        io.Reader
+	declared int64 // 声明的大小
+	n        int64 // 已读取的字节数
+}
+
+func (r *incompleteReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	if err == io.EOF && r.n != r.declared {
+		err = fmt.Errorf("%w: read %d bytes, declared %d bytes", ErrIncompleteUpload, r.n, r.declared)
+	}
+	return n, err
+}
+
+// sourceReader 源内容读取器, 声明了源内容大小时检查是否完整读取
+func (u *upload) sourceReader() io.Reader {
+	if u.declaredSize <= 0 {
+		return u.src
+	}
+	return &incompleteReader{r: u.src, declared: u.declaredSize}
+}
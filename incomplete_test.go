@@ -0,0 +1,27 @@
+package fileupload
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIncompleteUpload(t *testing.T) {
+	s, dir := newTestStorage(t)
+	content := []byte("truncated image")
+	// 读取至 EOF 时少于声明的大小
+	_, err := s.CopyMultipartFile(&FileStorage{}, openBytes(content), "a.png", int64(len(content))+100)
+	if !errors.Is(err, ErrIncompleteUpload) {
+		t.Fatalf("err = %v, want ErrIncompleteUpload", err)
+	}
+	if names := listTree(t, dir); len(names) != 0 {
+		t.Errorf("partial upload left %v", names)
+	}
+	// 大小未知时不检查
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes(content), "a.png", 0)
+	if err != nil {
+		t.Fatalf("unknown size: %v", err)
+	}
+	if result.Size != int64(len(content)) {
+		t.Errorf("Size = %d, want %d", result.Size, len(content))
+	}
+}
@@ -0,0 +1,110 @@
+package fileupload
+
+import (
+	"sync"
+)
+
+// ContentIndex 内容索引, 按哈希值记录已存储文件的位置, 用于跨子目录的重复内容检测
+// 实现需保证并发安全
+type ContentIndex interface {
+	// Load 查询哈希值对应的已存储文件
+	Load(hash string) (result *FileStorageResult, ok bool, err error)
+	// Store 记录哈希值对应的已存储文件
+	Store(hash string, result *FileStorageResult) error
+	// Delete 删除哈希值对应的记录
+	Delete(hash string) error
+}
+
+// WithContentIndex 内容索引, 相同内容已存储于其它位置时直接返回该位置而不再重复写入
+func WithContentIndex(index ContentIndex) Opts {
+	return func(s *Storage) { s.contentIndex = index }
+}
+
+// MemoryIndex 基于内存的内容索引
+type MemoryIndex struct {
+	mutex   sync.RWMutex
+	results map[string]*FileStorageResult
+}
+
+// NewMemoryIndex 创建基于内存的内容索引
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		results: make(map[string]*FileStorageResult),
+	}
+}
+
+func (m *MemoryIndex) Load(hash string) (*FileStorageResult, bool, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	result, ok := m.results[hash]
+	if !ok {
+		return nil, false, nil
+	}
+	tmp := *result
+	return &tmp, true, nil
+}
+
+func (m *MemoryIndex) Store(hash string, result *FileStorageResult) error {
+	tmp := *result
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.results[hash] = &tmp
+	return nil
+}
+
+func (m *MemoryIndex) Delete(hash string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.results, hash)
+	return nil
+}
+
+// lookupIndex 从内容索引中查询相同内容的已存储文件, 命中时将其存储位置填充至 result
+func (s *Storage) lookupIndex(index ContentIndex, result *FileStorageResult) (hit bool, err error) {
+	stored, ok, err := index.Load(result.Hash)
+	if err != nil || !ok {
+		return
+	}
+	if s.dedupVerify {
+		unlock := s.locker.lock(stored.PathAbs)
+		defer unlock()
+	}
+	// 索引记录的文件已不存在时删除该记录
+	stat, ser := s.filesystem().Stat(stored.PathAbs)
+	if ser != nil || stat.IsDir() || stat.Size() != stored.Size {
+		err = index.Delete(result.Hash)
+		return
+	}
+	result.Name = stored.Name
+	result.FileExt = stored.FileExt
+	result.PathAbs = stored.PathAbs
+	result.PathRlt = stored.PathRlt
+	result.PathUri = stored.PathUri
+	copyStored(result, stored)
+	if s.preserveCreatedAt {
+		result.CreatedAt = stat.ModTime()
+	}
+	hit = true
+	return
+}
+
+// copyStored 复制已存储文件的存储属性(不含路径)
+func copyStored(dst *FileStorageResult, src *FileStorageResult) {
+	dst.Size = src.Size
+	dst.Compressed = src.Compressed
+	dst.OriginalSize = src.OriginalSize
+	dst.Encrypted = src.Encrypted
+	dst.Width = src.Width
+	dst.Height = src.Height
+	dst.PerceptualHash = src.PerceptualHash
+	dst.ThumbnailUri = src.ThumbnailUri
+}
+
+// storeIndex 将已存储文件记录至内容索引
+func (s *Storage) storeIndex(result *FileStorageResult) error {
+	index, err := s.recordIndex()
+	if err != nil || index == nil {
+		return err
+	}
+	return index.Store(result.Hash, result)
+}
@@ -0,0 +1,45 @@
+package fileupload
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestContentIndex(t *testing.T) {
+	s, _ := newTestStorage(t, WithContentIndex(NewMemoryIndex()))
+	first, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: "2024/01/01"}, openBytes([]byte("logo")), "logo.png", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: "2024/01/02"}, openBytes([]byte("logo")), "logo.png", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.PathAbs != first.PathAbs || second.Created {
+		t.Errorf("second upload = %s (created %v), want the existing %s", second.PathAbs, second.Created, first.PathAbs)
+	}
+}
+
+func TestMemoryIndexConcurrent(t *testing.T) {
+	index := NewMemoryIndex()
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			hash := fmt.Sprint(i % 4)
+			_ = index.Store(hash, &FileStorageResult{Name: hash})
+			if result, ok, err := index.Load(hash); err != nil || !ok || result.Name != hash {
+				t.Errorf("Load(%s) = %v, %v, %v", hash, result, ok, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := index.Delete("0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := index.Load("0"); ok {
+		t.Error("deleted hash is still indexed")
+	}
+}
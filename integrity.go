@@ -0,0 +1,38 @@
+package fileupload
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// checkIntegrity 校验客户端声明的哈希值及大小(见 FileStorage.ExpectedHash, FileStorage.ExpectedSize), 在写入文件之前进行
+// 校验的是接收到的上传内容(base64为解码后的内容); 存储前解压, 转换格式或去除EXIF元数据时另行计算处理前内容的哈希值及大小
+func (s *Storage) checkIntegrity(u *upload) (err error) {
+	param, result := u.param, u.result
+	if param.ExpectedSize <= 0 && param.ExpectedHash == "" {
+		return
+	}
+	hash, size := result.Hash, result.Size
+	if u.gzipped || s.stripEXIF || u.converted {
+		if _, err = u.src.Seek(0, io.SeekStart); err != nil {
+			return
+		}
+		if hash, size, err = s.sha256Reader(u.sourceReader()); err != nil {
+			return
+		}
+	}
+	if param.ExpectedSize > 0 && param.ExpectedSize != size {
+		return fmt.Errorf("%w: size %d, expected %d", ErrIntegrityMismatch, size, param.ExpectedSize)
+	}
+	if param.ExpectedHash == "" {
+		return
+	}
+	expected := strings.TrimSpace(param.ExpectedHash)
+	// base58 区分大小写
+	matched := expected == hash || (s.hashEncoding != HashBase58 && strings.EqualFold(expected, hash))
+	if !matched {
+		return fmt.Errorf("%w: hash %s, expected %s", ErrIntegrityMismatch, hash, expected)
+	}
+	return
+}
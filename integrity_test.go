@@ -0,0 +1,38 @@
+package fileupload
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExpectedHashAndSize(t *testing.T) {
+	const content = "payload to verify"
+	s, dir := newTestStorage(t)
+	hash, size, err := s.HashReader(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := s.CopyMultipartFile(&FileStorage{ExpectedHash: strings.ToUpper(hash), ExpectedSize: size}, openBytes([]byte(content)), "a.txt", size)
+	if err != nil {
+		t.Fatalf("correct hash: %v", err)
+	}
+	if readFile(t, result.PathAbs) != content {
+		t.Error("stored content differs")
+	}
+
+	before := listTree(t, dir)
+	wrong := strings.Repeat("0", len(hash))
+	for name, param := range map[string]*FileStorage{
+		"wrong hash": {ExpectedHash: wrong, StorageSubDirectory: "verify"},
+		"wrong size": {ExpectedHash: hash, ExpectedSize: size + 1, StorageSubDirectory: "verify"},
+	} {
+		_, err = s.CopyMultipartFile(param, openBytes([]byte(content)), "b.txt", size)
+		if !errors.Is(err, ErrIntegrityMismatch) {
+			t.Errorf("%s: err = %v, want ErrIntegrityMismatch", name, err)
+		}
+	}
+	if after := listTree(t, dir); strings.Join(after, ",") != strings.Join(before, ",") {
+		t.Errorf("mismatched uploads changed the tree: %v -> %v", before, after)
+	}
+}
@@ -0,0 +1,78 @@
+package fileupload
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// lifecycle 存储生命周期状态
+type lifecycle struct {
+	closed   bool           // 已关闭, 不再接受新的存储操作
+	inflight sync.WaitGroup // 进行中的存储操作(含异步镜像写入)
+	done     chan struct{}  // 进行中的操作结束且资源已关闭时关闭
+	err      error          // 关闭资源的错误, done 关闭后有效
+}
+
+// begin 开始一次存储操作, 已关闭时返回 ErrStorageClosed
+func (s *Storage) begin() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.lifecycle.closed {
+		return ErrStorageClosed
+	}
+	s.lifecycle.inflight.Add(1)
+	return nil
+}
+
+// end 结束一次存储操作
+func (s *Storage) end() {
+	s.lifecycle.inflight.Done()
+}
+
+// Close 关闭存储, 不再接受新的存储操作; 等待进行中的存储操作及异步镜像写入完成后, 关闭实现了 io.Closer 的
+// 文件系统(WithFileSystem), 镜像文件系统(WithMirrors), 内容索引(WithContentIndex)及引用计数(WithRefCounter)
+// ctx 结束时停止等待并返回 ctx.Err(), 关闭过程在后台继续; 重复调用时等待同一关闭过程完成并返回相同的结果
+func (s *Storage) Close(ctx context.Context) error {
+	s.mutex.Lock()
+	if !s.lifecycle.closed {
+		s.lifecycle.closed = true
+		s.lifecycle.done = make(chan struct{})
+		go s.shutdown()
+	}
+	done := s.lifecycle.done
+	s.mutex.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-done:
+		return s.lifecycle.err
+	}
+}
+
+// shutdown 等待进行中的操作完成后关闭资源
+func (s *Storage) shutdown() {
+	s.lifecycle.inflight.Wait()
+	s.lifecycle.err = s.closeResources()
+	close(s.lifecycle.done)
+}
+
+// closeResources 依次关闭实现了 io.Closer 的文件系统, 镜像文件系统, 内容索引及引用计数
+func (s *Storage) closeResources() error {
+	resources := []any{s.fs}
+	for _, mirror := range s.mirrors {
+		resources = append(resources, mirror)
+	}
+	resources = append(resources, s.contentIndex, s.refCounter)
+	var errs []error
+	for _, resource := range resources {
+		if closer, ok := resource.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
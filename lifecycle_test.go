@@ -0,0 +1,63 @@
+package fileupload
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// closingIndex 记录是否已关闭的内容索引
+type closingIndex struct {
+	*MemoryIndex
+	closed bool
+}
+
+func (i *closingIndex) Close() error {
+	i.closed = true
+	return nil
+}
+
+func TestCloseDrainsAsyncMirror(t *testing.T) {
+	mirror := newMemFS()
+	index := &closingIndex{MemoryIndex: NewMemoryIndex()}
+	s, _ := newTestStorage(t, WithMirrors(MirrorAsync, slowFS{FileSystem: mirror, delay: 50 * time.Millisecond}), WithContentIndex(index))
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("hello")), "a.txt", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := mirror.content(result.PathAbs); ok {
+		t.Fatal("async mirror finished before the store returned")
+	}
+	if err = s.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if content, ok := mirror.content(result.PathAbs); !ok || content != "hello" {
+		t.Errorf("mirror after Close = %q, %v, want the stored content", content, ok)
+	}
+	if !index.closed {
+		t.Error("content index was not closed")
+	}
+	if _, err = s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("hello")), "a.txt", 5); !errors.Is(err, ErrStorageClosed) {
+		t.Errorf("store after Close: err = %v, want ErrStorageClosed", err)
+	}
+	if err = s.Close(context.Background()); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
+
+func TestCloseContext(t *testing.T) {
+	mirror := newMemFS()
+	s, _ := newTestStorage(t, WithMirrors(MirrorAsync, slowFS{FileSystem: mirror, delay: 200 * time.Millisecond}))
+	if _, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("hello")), "a.txt", 5); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if err := s.Close(context.Background()); err != nil {
+		t.Errorf("waiting Close: %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package fileupload
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// LinkMode 在其它子目录中访问同一文件的方式, 见 FileStorage.LinkSubDirectories
+type LinkMode int
+
+const (
+	LinkHard     LinkMode = iota // 硬链接
+	LinkSymbolic                 // 符号链接(指向文件存储绝对路径)
+)
+
+// WithLinkMode 在其它子目录中访问同一文件的方式, 默认 LinkHard; 无法创建链接(如跨设备)时复制文件
+func WithLinkMode(mode LinkMode) Opts {
+	return func(s *Storage) { s.linkMode = mode }
+}
+
+// linker 支持创建链接的文件系统
+type linker interface {
+	Link(oldName string, newName string) error
+	Symlink(oldName string, newName string) error
+}
+
+func (osFileSystem) Link(oldName string, newName string) error {
+	return os.Link(oldName, newName)
+}
+
+func (osFileSystem) Symlink(oldName string, newName string) error {
+	return os.Symlink(oldName, newName)
+}
+
+// link 将已存储的文件链接至 FileStorage.LinkSubDirectories 中的各个子目录, 每个位置的存储结果暂记录于 result.Links
+func (s *Storage) link(u *upload) (err error) {
+	param, result := u.param, u.result
+	if len(param.LinkSubDirectories) == 0 || param.DryRun {
+		return
+	}
+	for _, subDirectory := range param.LinkSubDirectories {
+		linked := *result
+		linked.Links, linked.ThumbnailUri, linked.Created, linked.written = nil, "", false, nil
+		p := *param
+		p.StorageSubDirectory = subDirectory
+		if err = s.resolvePath(&p, &linked); err != nil {
+			return
+		}
+		// 与文件存储位置相同
+		if linked.PathAbs == result.PathAbs {
+			continue
+		}
+		if linked.Created, err = s.linkFile(u, result.PathAbs, linked.PathAbs); err != nil {
+			return
+		}
+		if linked.Created {
+			linked.written = &writtenFile{pathAbs: linked.PathAbs}
+		}
+		result.Links = append(result.Links, &linked)
+	}
+	return
+}
+
+// linkFile 在 name 处创建指向 target 的链接, 失败时复制文件
+// name 已存在时比较其与 target 的内容, 一致时视为已链接, 不一致时返回 ErrHashCollision
+func (s *Storage) linkFile(u *upload, target string, name string) (created bool, err error) {
+	if err = u.mkdirAll(filepath.Dir(name)); err != nil {
+		return
+	}
+	unlock := s.locker.lock(name)
+	defer unlock()
+	if existing, ser := u.fs.Stat(name); ser == nil {
+		err = s.sameLinked(u, target, existing, name)
+		return
+	} else if !os.IsNotExist(ser) {
+		err = ser
+		return
+	}
+	if l, ok := u.fs.(linker); ok {
+		if s.linkMode == LinkSymbolic {
+			err = l.Symlink(target, name)
+		} else {
+			err = l.Link(target, name)
+		}
+		if err == nil {
+			u.created = append(u.created, name)
+			return true, nil
+		}
+	}
+	src, err := u.fs.Open(target)
+	if err != nil {
+		return
+	}
+	defer func() { _ = src.Close() }()
+	if err = u.writeFile(name, io.Reader(src), false); err != nil {
+		return
+	}
+	return true, nil
+}
+
+// sameLinked 检查已存在的文件 name 与 target 是否为同一文件或内容一致
+func (s *Storage) sameLinked(u *upload, target string, existing os.FileInfo, name string) error {
+	stat, err := u.fs.Stat(target)
+	if err != nil {
+		return err
+	}
+	if os.SameFile(stat, existing) {
+		return nil
+	}
+	if existing.IsDir() || existing.Size() != stat.Size() {
+		return fmt.Errorf("%w: %s", ErrHashCollision, name)
+	}
+	a, err := u.fs.Open(target)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = a.Close() }()
+	b, err := u.fs.Open(name)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = b.Close() }()
+	same, err := equalReader(a, b)
+	if err == nil && !same {
+		err = fmt.Errorf("%w: %s", ErrHashCollision, name)
+	}
+	return err
+}
+
+// flattenLinks 将各存储结果链接至其它子目录的位置(Links)展开为独立的存储结果, 紧随原存储结果之后
+func flattenLinks(results []*FileStorageResult) []*FileStorageResult {
+	n := len(results)
+	for _, v := range results {
+		n += len(v.Links)
+	}
+	if n == len(results) {
+		return results
+	}
+	flattened := make([]*FileStorageResult, 0, n)
+	for _, v := range results {
+		links := v.Links
+		v.Links = nil
+		flattened = append(flattened, v)
+		for _, link := range links {
+			link.Field = v.Field
+			flattened = append(flattened, link)
+		}
+	}
+	return flattened
+}
@@ -0,0 +1,76 @@
+package fileupload
+
+import (
+	"os"
+	"path"
+	"runtime"
+	"testing"
+)
+
+// storeLinked 存储文件并链接至 tenant 子目录, 返回全部位置的存储结果
+func storeLinked(t *testing.T, s *Storage) []*FileStorageResult {
+	t.Helper()
+	files := formFileHeaders(t, testFile{field: "files", filename: "logo.txt", content: "shared asset"})
+	results, err := s.MultipartCopy(&FileStorage{StorageSubDirectory: "public", LinkSubDirectories: []string{"tenant"}}, files...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want one per location", len(results))
+	}
+	if path.Dir(results[0].PathRlt) != "public" || path.Dir(results[1].PathRlt) != "tenant" {
+		t.Errorf("locations = %s, %s", results[0].PathRlt, results[1].PathRlt)
+	}
+	if results[0].Hash != results[1].Hash || !results[1].Created {
+		t.Errorf("linked result = %+v", results[1])
+	}
+	return results
+}
+
+func TestLinkSubDirectoriesHardLink(t *testing.T) {
+	s, _ := newTestStorage(t)
+	results := storeLinked(t, s)
+	a, err := os.Stat(results[0].PathAbs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.Stat(results[1].PathAbs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(a, b) {
+		t.Error("linked files do not share an inode")
+	}
+}
+
+func TestLinkSubDirectoriesSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require privileges on windows")
+	}
+	s, _ := newTestStorage(t, WithLinkMode(LinkSymbolic))
+	results := storeLinked(t, s)
+	stat, err := os.Lstat(results[1].PathAbs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("mode = %v, want a symlink", stat.Mode())
+	}
+	if target, _ := os.Readlink(results[1].PathAbs); target != results[0].PathAbs {
+		t.Errorf("symlink target = %s, want %s", target, results[0].PathAbs)
+	}
+}
+
+func TestLinkSubDirectoriesCopyFallback(t *testing.T) {
+	// 不支持链接的文件系统
+	s, _ := newTestStorage(t, WithFileSystem(struct{ FileSystem }{osFileSystem{}}))
+	results := storeLinked(t, s)
+	a, _ := os.Stat(results[0].PathAbs)
+	b, _ := os.Stat(results[1].PathAbs)
+	if os.SameFile(a, b) {
+		t.Error("linked although the file system does not support links")
+	}
+	if readFile(t, results[1].PathAbs) != "shared asset" {
+		t.Error("copied file content differs")
+	}
+}
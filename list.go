@@ -0,0 +1,123 @@
+package fileupload
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ListOptions List 的参数
+type ListOptions struct {
+	Recursive bool // 递归列出子目录中的文件
+	Hash      bool // 读取文件内容重新计算哈希值(压缩或加密存储的文件按原始内容计算)
+}
+
+// dirReader 支持列出目录内容的文件系统
+type dirReader interface {
+	ReadDir(name string) ([]os.DirEntry, error)
+}
+
+func (osFileSystem) ReadDir(name string) ([]os.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+// List 列出存储目录下子目录 subDirectory 中已存储的文件, 跳过临时文件, 元数据文件及以 . 开头的文件(及目录, 如缩略图目录)
+// 存储结果根据文件名及文件信息重建(设置 WithUriBuilder 时 PathUri 由其生成), 仅包含 Name, FileExt, Size, Path*, Category, ContentType, CreatedAt(修改时间), Compressed, Encrypted 及 Hash(见 ListOptions.Hash)
+func (s *Storage) List(subDirectory string, options *ListOptions) (results []*FileStorageResult, err error) {
+	if options == nil {
+		options = &ListOptions{}
+	}
+	fs := s.filesystem()
+	reader, ok := fs.(dirReader)
+	if !ok {
+		return nil, fmt.Errorf("list: file system does not support reading directories")
+	}
+	storageDirectory := s.storageDirectory
+	if storageDirectory == "" {
+		storageDirectory = "."
+	}
+	root, err := filepath.Abs(storageDirectory)
+	if err != nil {
+		return
+	}
+	directory := filepath.Join(root, filepath.FromSlash(path.Clean("/"+subDirectory)))
+
+	var walk func(directory string) error
+	walk = func(directory string) error {
+		entries, err := reader.ReadDir(directory)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if strings.HasPrefix(name, ".") {
+				continue
+			}
+			if entry.IsDir() {
+				if options.Recursive {
+					if err = walk(filepath.Join(directory, name)); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if strings.HasSuffix(name, tempFileSuffix) || s.isSidecar(name) || !entry.Type().IsRegular() {
+				continue
+			}
+			result, err := s.listedResult(root, filepath.Join(directory, name), entry, options)
+			if err != nil {
+				return err
+			}
+			results = append(results, result)
+		}
+		return nil
+	}
+	err = walk(directory)
+	return
+}
+
+// listedResult 根据已存储的文件重建存储结果
+func (s *Storage) listedResult(root string, name string, entry os.DirEntry, options *ListOptions) (result *FileStorageResult, err error) {
+	info, err := entry.Info()
+	if err != nil {
+		return
+	}
+	result = &FileStorageResult{
+		Name:      entry.Name(),
+		Size:      info.Size(),
+		PathAbs:   name,
+		PathRlt:   relativePath(root, name),
+		CreatedAt: info.ModTime(),
+	}
+	base := result.Name
+	if s.encryption != nil && strings.HasSuffix(base, encryptedSuffix) {
+		result.Encrypted = true
+		base = strings.TrimSuffix(base, encryptedSuffix)
+	}
+	// 压缩存储的文件名为 哈希值+后缀+.gz, 上传的 .gz 文件本身只有一个后缀
+	if s.compression != nil && strings.HasSuffix(base, compressedSuffix) && path.Ext(strings.TrimSuffix(base, compressedSuffix)) != "" {
+		result.Compressed = true
+		base = strings.TrimSuffix(base, compressedSuffix)
+	}
+	result.FileExt = path.Ext(base)
+	result.PathUri = s.joinURI(s.uriAccessPrefix, result.PathRlt)
+	result.ContentType = resolveContentType("", "", result.FileExt)
+	result.Category = s.categorize(result.FileExt, result.ContentType)
+	if options.Hash {
+		rc, err := s.openFile(name, result)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = rc.Close() }()
+		if result.Hash, _, err = s.sha256Reader(rc); err != nil {
+			return nil, err
+		}
+		result.HashEncoding = s.hashEncoding.String()
+	}
+	if err = s.buildURI(result); err != nil {
+		return nil, err
+	}
+	return
+}
@@ -0,0 +1,49 @@
+package fileupload
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestList(t *testing.T) {
+	s, dir := newTestStorage(t)
+	a, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: "docs"}, openBytes([]byte("first")), "a.txt", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: "docs/2024"}, openBytes([]byte("second")), "b.md", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "docs", "partial"+tempFileSuffix), []byte("temp"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := s.List("docs", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].PathAbs != a.PathAbs {
+		t.Fatalf("non-recursive results = %+v, want only %s", results, a.PathAbs)
+	}
+	listed := results[0]
+	if listed.Name != a.Name || listed.FileExt != ".txt" || listed.Size != 5 || listed.PathUri != a.PathUri || listed.Hash != "" {
+		t.Errorf("listed = %+v, want fields of %+v without a hash", listed, a)
+	}
+
+	results, err = s.List("docs", &ListOptions{Recursive: true, Hash: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Size < results[j].Size })
+	if len(results) != 2 {
+		t.Fatalf("recursive results = %d, want 2 without the temp file", len(results))
+	}
+	for i, want := range []*FileStorageResult{a, b} {
+		if results[i].PathAbs != want.PathAbs || results[i].Hash != want.Hash {
+			t.Errorf("result %d = %s %s, want %s %s", i, results[i].PathAbs, results[i].Hash, want.PathAbs, want.Hash)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package fileupload
+
+import (
+	"errors"
+)
+
+// Logger 结构化日志, keysAndValues 为交替的键值对, 如 "path", "/a.png", "bytes", 1024
+// 方法在存储过程中同步调用, 实现需保证并发安全
+type Logger interface {
+	Debug(msg string, keysAndValues ...any)
+	Info(msg string, keysAndValues ...any)
+	Warn(msg string, keysAndValues ...any)
+	Error(msg string, keysAndValues ...any)
+}
+
+// NopLogger 不输出任何日志
+type NopLogger struct{}
+
+func (NopLogger) Debug(string, ...any) {}
+func (NopLogger) Info(string, ...any)  {}
+func (NopLogger) Warn(string, ...any)  {}
+func (NopLogger) Error(string, ...any) {}
+
+// WithLogger 记录存储过程中的决策(如拒绝存储, 复用相同内容, 写入字节数), 默认不记录
+func WithLogger(logger Logger) Opts {
+	return func(s *Storage) {
+		s.logger = logger
+		if _, ok := logger.(NopLogger); ok {
+			s.logger = nil
+		}
+	}
+}
+
+// rejections 拒绝存储的错误及其原因
+var rejections = []struct {
+	err    error
+	reason string
+}{
+	{ErrMissingExtension, "extension"},
+	{ErrImageDimensions, "image dimensions"},
+	{ErrStripEXIFUnsupported, "strip exif"},
+	{ErrDeclaredTypeNotAllowed, "declared type"},
+	{ErrFileTooLarge, "file size"},
+	{ErrDecompressedTooLarge, "decompressed size"},
+	{ErrChunkLimitExceeded, "chunk limit"},
+	{ErrTotalSizeExceeded, "total size"},
+	{ErrRequestTooLarge, "request size"},
+	{ErrQuotaExceeded, "quota"},
+	{ErrInsufficientSpace, "disk space"},
+	{ErrScanRejected, "scanner"},
+	{ErrIntegrityMismatch, "integrity"},
+	{ErrIncompleteUpload, "incomplete"},
+	{ErrInvalidFileName, "file name"},
+	{ErrHashCollision, "hash collision"},
+	{ErrNameCollision, "name collision"},
+	{ErrUnsafeZipEntry, "zip entry"},
+	{ErrZipLimitExceeded, "zip limit"},
+	{ErrStorageClosed, "closed"},
+	{ErrBatchRolledBack, "rolled back"},
+}
+
+// logResult 记录存储结果, 未设置 Logger 时不做任何处理(避免构造键值对)
+func (s *Storage) logResult(result *FileStorageResult, err error) {
+	if s.logger == nil {
+		return
+	}
+	origin := ""
+	if result != nil {
+		origin = result.OriginName
+	}
+	if err != nil {
+		for _, v := range rejections {
+			if errors.Is(err, v.err) {
+				s.logger.Warn("rejected: "+v.reason, "origin", origin, "error", err)
+				return
+			}
+		}
+		s.logger.Error("failed", "origin", origin, "error", err)
+		return
+	}
+	if result.Created {
+		s.logger.Info("wrote bytes", "origin", origin, "path", result.PathRlt, "bytes", result.Size)
+		return
+	}
+	s.logger.Info("deduplicated", "origin", origin, "path", result.PathRlt, "hash", result.Hash)
+}
@@ -0,0 +1,47 @@
+package fileupload
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// captureLogger 记录日志级别及消息的日志, 键值对不成对时在消息后标记
+type captureLogger struct {
+	mutex   sync.Mutex
+	entries []string
+}
+
+func (l *captureLogger) log(level string, msg string, keysAndValues []any) {
+	if len(keysAndValues)%2 != 0 {
+		msg += " (odd key value pairs)"
+	}
+	l.mutex.Lock()
+	l.entries = append(l.entries, level+" "+msg)
+	l.mutex.Unlock()
+}
+
+func (l *captureLogger) Debug(msg string, keysAndValues ...any) { l.log("debug", msg, keysAndValues) }
+func (l *captureLogger) Info(msg string, keysAndValues ...any)  { l.log("info", msg, keysAndValues) }
+func (l *captureLogger) Warn(msg string, keysAndValues ...any)  { l.log("warn", msg, keysAndValues) }
+func (l *captureLogger) Error(msg string, keysAndValues ...any) { l.log("error", msg, keysAndValues) }
+
+func TestLoggerEvents(t *testing.T) {
+	logger := &captureLogger{}
+	s, _ := newTestStorage(t, WithLogger(logger), WithRequireExtension(true))
+	for _, name := range []string{"a.txt", "b.txt", "README"} {
+		_, _ = s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("same")), name, 4)
+	}
+	want := []string{"info wrote bytes", "info deduplicated", "warn rejected: extension"}
+	if fmt.Sprint(logger.entries) != fmt.Sprint(want) {
+		t.Errorf("entries = %q, want %q", logger.entries, want)
+	}
+}
+
+func TestNopLoggerDoesNotAllocate(t *testing.T) {
+	s := NewStorage(WithLogger(NopLogger{}))
+	result := &FileStorageResult{OriginName: "a.txt", PathRlt: "a.txt", Size: 1, Created: true}
+	if allocs := testing.AllocsPerRun(100, func() { s.logResult(result, nil) }); allocs != 0 {
+		t.Errorf("no-op logger allocates %v times per result", allocs)
+	}
+}
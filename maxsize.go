@@ -0,0 +1,35 @@
+package fileupload
+
+import (
+	"fmt"
+	"io"
+)
+
+// WithMaxFileSize 单个文件(解压或解码后)的大小上限, 小于等于0时不限制, 超出时返回 ErrFileTooLarge
+func WithMaxFileSize(bytes int64) Opts {
+	return func(s *Storage) { s.maxFileSize = bytes }
+}
+
+// checkFileSize 检查文件大小是否超出上限
+func (s *Storage) checkFileSize(size int64) error {
+	if s.maxFileSize > 0 && size > s.maxFileSize {
+		return fmt.Errorf("%w: %d bytes, limit %d bytes", ErrFileTooLarge, size, s.maxFileSize)
+	}
+	return nil
+}
+
+// sizeLimitReader 读取超出 limit 字节时返回 ErrFileTooLarge, limit 小于等于0时不限制
+type sizeLimitReader struct {
+	r     io.Reader
+	limit int64
+	n     int64
+}
+
+func (l *sizeLimitReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	if l.limit > 0 && l.n > l.limit {
+		return n, fmt.Errorf("%w: more than %d bytes", ErrFileTooLarge, l.limit)
+	}
+	return n, err
+}
@@ -0,0 +1,86 @@
+package fileupload
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// MirrorMode 镜像写入方式
+type MirrorMode int
+
+const (
+	MirrorSync  MirrorMode = iota // 存储返回前写入全部镜像
+	MirrorAsync                   // 后台写入镜像, Close 时等待写入完成
+)
+
+// WithMirrors 存储成功后将新写入的文件(及其缩略图, 元数据文件)以相同路径复制至镜像文件系统(如备份磁盘或对象存储)
+// 镜像写入失败不影响存储结果, 通过 WithOnError(错误包装 ErrMirrorFailed, MirrorAsync 时在后台goroutine中调用)及 WithLogger 通知; 复用已存储的相同内容时不再写入
+func WithMirrors(mode MirrorMode, mirrors ...FileSystem) Opts {
+	return func(s *Storage) {
+		s.mirrorMode = mode
+		s.mirrors = mirrors
+	}
+}
+
+// mirror 将存储结果新写入的文件复制至镜像文件系统
+func (s *Storage) mirror(result *FileStorageResult) {
+	if len(s.mirrors) == 0 || !result.Created {
+		return
+	}
+	names := []string{result.PathAbs}
+	if result.ThumbnailUri != "" {
+		names = append(names, thumbnailPath(result.PathAbs))
+	}
+	if s.sidecarMetadata {
+		names = append(names, result.PathAbs+sidecarSuffix)
+	}
+	origin := result.OriginName
+	if s.mirrorMode != MirrorAsync {
+		s.mirrorFiles(names, origin)
+		return
+	}
+	// 计入进行中的操作, Close 时等待镜像写入完成
+	s.lifecycle.inflight.Add(1)
+	go func() {
+		defer s.end()
+		s.mirrorFiles(names, origin)
+	}()
+}
+
+// mirrorFiles 依次将文件复制至各镜像文件系统, 失败时通知并继续处理其余镜像
+func (s *Storage) mirrorFiles(names []string, origin string) {
+	for i, mirror := range s.mirrors {
+		for _, name := range names {
+			if err := s.mirrorFile(mirror, name); err != nil {
+				err = fmt.Errorf("%w: mirror %d: %s: %w", ErrMirrorFailed, i, name, err)
+				if s.logger != nil {
+					s.logger.Warn("mirror failed", "origin", origin, "error", err)
+				}
+				if s.onError != nil {
+					s.onError(err, origin)
+				}
+				break
+			}
+		}
+	}
+}
+
+// mirrorFile 将文件 name 复制至镜像文件系统的相同路径
+func (s *Storage) mirrorFile(mirror FileSystem, name string) (err error) {
+	src, err := s.filesystem().Open(name)
+	if err != nil {
+		return
+	}
+	defer func() { _ = src.Close() }()
+	u := &upload{fs: mirror, buffers: &s.buffers, sync: s.sync, fileMode: s.fileMode}
+	defer func() {
+		if err != nil {
+			u.cleanup()
+		}
+	}()
+	if err = u.mkdirAll(filepath.Dir(name)); err != nil {
+		return
+	}
+	_, ser := mirror.Stat(name)
+	return u.writeFile(name, src, ser == nil)
+}
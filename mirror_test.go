@@ -0,0 +1,50 @@
+package fileupload
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMirrorSync(t *testing.T) {
+	backup, broken := newMemFS(), readOnlyFS{FileSystem: newMemFS()}
+	var errs []error
+	s, _ := newTestStorage(t, WithMirrors(MirrorSync, broken, backup),
+		WithOnError(func(err error, origin string) { errs = append(errs, err) }))
+	result, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: "invoices"}, openBytes([]byte("invoice")), "a.pdf", 7)
+	if err != nil {
+		t.Fatalf("mirror failure failed the store: %v", err)
+	}
+	if got := readFile(t, result.PathAbs); got != "invoice" {
+		t.Errorf("primary content = %q", got)
+	}
+	if got, ok := backup.content(result.PathAbs); !ok || got != "invoice" {
+		t.Errorf("mirror content = %q (exists %v), want the stored content at %s", got, ok, result.PathAbs)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], ErrMirrorFailed) {
+		t.Errorf("errors = %v, want one ErrMirrorFailed", errs)
+	}
+
+	// 复用已存储的相同内容时不再写入镜像
+	if _, err = s.CopyMultipartFile(&FileStorage{StorageSubDirectory: "invoices"}, openBytes([]byte("invoice")), "b.pdf", 7); err != nil {
+		t.Fatal(err)
+	}
+	if names := backup.names(); len(names) != 1 || len(errs) != 1 {
+		t.Errorf("mirror = %v, errors = %v after storing the same content", names, errs)
+	}
+}
+
+func TestMirrorAsync(t *testing.T) {
+	backup := newMemFS()
+	s, _ := newTestStorage(t, WithMirrors(MirrorAsync, backup))
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("async")), "a.txt", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := backup.content(result.PathAbs); !ok || got != "async" {
+		t.Errorf("mirror content after Close = %q (exists %v)", got, ok)
+	}
+}
@@ -0,0 +1,148 @@
+package fileupload
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Move 将已存储的文件(及其缩略图, 元数据文件)移动至存储目录下的子目录 subDirectory, 返回更新了存储路径及资源访问路径的存储结果
+// 跨设备无法重命名时复制后删除原文件; 目标位置已存在大小一致的同名文件时视为相同内容并删除原文件, 大小不一致时返回 ErrHashCollision
+// 设置 WithRefCounter 时引用数随文件转移至目标位置; 设置 WithUriBuilder 时 PathUri 由其生成
+// 仅适用于使用 Storage 默认存储目录及资源访问前缀存储的文件
+func (s *Storage) Move(result *FileStorageResult, subDirectory string) (moved *FileStorageResult, err error) {
+	if result == nil || result.Name == "" {
+		return nil, fmt.Errorf("move: empty file name")
+	}
+	if strings.ContainsAny(result.Name, `/\`) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidFileName, result.Name)
+	}
+	for _, segment := range strings.Split(filepath.ToSlash(subDirectory), "/") {
+		if segment == ".." {
+			return nil, fmt.Errorf("move: invalid sub directory %q", subDirectory)
+		}
+	}
+	source, err := s.AbsolutePath(result)
+	if err != nil {
+		return
+	}
+	subDirectory = path.Clean("/" + filepath.ToSlash(subDirectory))[1:]
+	tmp := *result
+	moved = &tmp
+	param := &FileStorage{StorageSubDirectory: subDirectory}
+	if err = s.locate(param, subDirectory, moved); err != nil {
+		return nil, err
+	}
+	// 缩略图, 内容索引及元数据文件使用按资源访问前缀拼接的访问路径, 返回前再由 WithUriBuilder 生成访问路径
+	defer func() {
+		if moved == nil {
+			return
+		}
+		if e := s.buildPathURI(moved); e != nil && err == nil {
+			err = e
+		}
+	}()
+	if moved.PathAbs == source {
+		return
+	}
+
+	for _, name := range sortedPaths(source, moved.PathAbs) {
+		unlock := s.locker.lock(name)
+		defer unlock()
+	}
+	fs := s.filesystem()
+	stat, err := fs.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+	u := &upload{fs: fs, buffers: &s.buffers, sync: s.sync, fileMode: s.fileMode}
+
+	if existing, ser := fs.Stat(moved.PathAbs); ser == nil {
+		if existing.IsDir() || existing.Size() != stat.Size() {
+			return nil, fmt.Errorf("%w: %s", ErrHashCollision, moved.PathAbs)
+		}
+		moved.Created = false
+		if err = fs.Remove(source); err != nil {
+			return nil, err
+		}
+		s.releaseQuota(source, stat.Size())
+		if err = s.moveReferences(source, moved.PathAbs); err != nil {
+			return
+		}
+		if err = s.moveIndex(source, moved); err != nil {
+			return
+		}
+		err = s.moveSidecar(u, source, moved)
+		return
+	}
+
+	commitQuota, err := s.reserveQuota(param, subDirectory, stat.Size())
+	if err != nil {
+		return nil, err
+	}
+	written := int64(0)
+	defer func() { commitQuota(written) }()
+	if err = u.mkdirAll(filepath.Dir(moved.PathAbs)); err != nil {
+		return nil, err
+	}
+	if err = s.moveFile(u, source, moved.PathAbs); err != nil {
+		u.cleanup()
+		return nil, err
+	}
+	written = stat.Size()
+	s.releaseQuota(source, stat.Size())
+	if err = s.moveReferences(source, moved.PathAbs); err != nil {
+		return
+	}
+	if err = s.moveIndex(source, moved); err != nil {
+		return
+	}
+
+	// 缩略图随原图移动, 失败时返回已移动的存储结果及错误
+	if result.ThumbnailUri != "" {
+		thumb, movedThumb := thumbnailPath(source), thumbnailPath(moved.PathAbs)
+		if _, ser := fs.Stat(thumb); ser == nil {
+			if err = u.mkdirAll(filepath.Dir(movedThumb)); err != nil {
+				return
+			}
+			if err = s.moveFile(u, thumb, movedThumb); err != nil {
+				return
+			}
+			moved.ThumbnailUri = thumbnailURI(moved.PathUri, movedThumb)
+		}
+	}
+	err = s.moveSidecar(u, source, moved)
+	return
+}
+
+// moveFile 重命名文件, 跨设备无法重命名时复制至目标位置后删除原文件
+func (s *Storage) moveFile(u *upload, source string, target string) error {
+	if err := u.fs.Rename(source, target); err != nil {
+		if err = u.moveTemp(source, target); err != nil {
+			return err
+		}
+	}
+	return u.commitFile(target)
+}
+
+// moveIndex 内容索引记录的位置为移动前的位置 source 时更新为移动后的位置
+func (s *Storage) moveIndex(source string, moved *FileStorageResult) error {
+	index, err := s.recordIndex()
+	if err != nil || index == nil || moved.Hash == "" {
+		return err
+	}
+	stored, ok, err := index.Load(moved.Hash)
+	if err != nil || !ok || stored.PathAbs != source {
+		return err
+	}
+	return index.Store(moved.Hash, moved)
+}
+
+// sortedPaths 按固定顺序返回两个路径, 避免同时加锁时死锁
+func sortedPaths(a string, b string) []string {
+	if a > b {
+		a, b = b, a
+	}
+	return []string{a, b}
+}
@@ -0,0 +1,69 @@
+package fileupload
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// dirDeviceFS 模拟每个目录位于不同设备, 只能在同一目录内重命名
+type dirDeviceFS struct {
+	osFileSystem
+}
+
+func (fs dirDeviceFS) Rename(oldPath string, newPath string) error {
+	if filepath.Dir(oldPath) != filepath.Dir(newPath) {
+		return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: syscall.EXDEV}
+	}
+	return fs.osFileSystem.Rename(oldPath, newPath)
+}
+
+func TestMove(t *testing.T) {
+	for name, opts := range map[string][]Opts{
+		"rename":       nil,
+		"cross device": {WithFileSystem(dirDeviceFS{})},
+	} {
+		s, dir := newTestStorage(t, opts...)
+		draft, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: "drafts"}, openBytes([]byte("asset")), "a.png", 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		moved, err := s.Move(draft, "published/2024")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if path.Dir(moved.PathRlt) != "published/2024" || moved.PathUri != "/"+moved.PathRlt || moved.Name != draft.Name {
+			t.Errorf("%s: moved = %s %s %s", name, moved.PathRlt, moved.PathUri, moved.Name)
+		}
+		if moved.PathAbs != filepath.Join(dir, "published", "2024", draft.Name) {
+			t.Errorf("%s: PathAbs = %s", name, moved.PathAbs)
+		}
+		if got := readFile(t, moved.PathAbs); got != "asset" {
+			t.Errorf("%s: moved content = %q", name, got)
+		}
+		if _, err = os.Stat(draft.PathAbs); !os.IsNotExist(err) {
+			t.Errorf("%s: source still exists: %v", name, err)
+		}
+		if names := listTree(t, filepath.Join(dir, "published", "2024")); len(names) != 1 {
+			t.Errorf("%s: destination = %v, want only the moved file", name, names)
+		}
+	}
+}
+
+func TestMoveRejectsTraversal(t *testing.T) {
+	s, _ := newTestStorage(t)
+	draft, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: "drafts"}, openBytes([]byte("asset")), "a.png", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, subDirectory := range []string{"../outside", "published/../../outside"} {
+		if moved, err := s.Move(draft, subDirectory); err == nil {
+			t.Errorf("Move to %q = %s", subDirectory, moved.PathAbs)
+		}
+	}
+	if got := readFile(t, draft.PathAbs); got != "asset" {
+		t.Errorf("source changed to %q", got)
+	}
+}
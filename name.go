@@ -0,0 +1,58 @@
+package fileupload
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// WithNameFunc 自定义存储文件名(如 "user-42-avatar.png"), fn 可使用存储结果中的 Hash, FileExt, OriginName, Category 等字段
+// 返回空字符串时使用默认的 哈希值+后缀; 返回的文件名不能包含路径分隔符, 否则返回 ErrInvalidFileName
+// 自定义文件名的文件不参与内容索引(WithContentIndex)的重复内容检测; 同名文件已存在且内容不同时按 WithExistingPolicy 处理
+func WithNameFunc(fn func(result *FileStorageResult) string) Opts {
+	return func(s *Storage) { s.nameFunc = fn }
+}
+
+// WithMaxFilenameLength 存储文件名(含压缩, 加密后缀)的最大字节数, 小于等于0时为255; 超出时保留后缀截断文件名, 见 WithFilenameLengthStrict
+// 默认的 哈希值+后缀 文件名不会超出, 主要影响 WithNameFunc 使用原始文件名等较长名称的情况
+func WithMaxFilenameLength(n int) Opts {
+	return func(s *Storage) { s.maxFilenameLength = n }
+}
+
+// WithFilenameLengthStrict 存储文件名超出最大长度时返回 ErrFilenameTooLong, 不截断
+func WithFilenameLengthStrict(strict bool) Opts {
+	return func(s *Storage) { s.filenameLengthStrict = strict }
+}
+
+// limitFilename 将存储文件名 name 及其后的 suffix(如压缩, 加密后缀)限制在最大长度内
+func (s *Storage) limitFilename(name string, suffix string) (string, error) {
+	limit := s.maxFilenameLength
+	if limit <= 0 {
+		limit = maxFilenameLength
+	}
+	if len(name)+len(suffix) <= limit {
+		return name + suffix, nil
+	}
+	if s.filenameLengthStrict || len(suffix) >= limit {
+		return "", fmt.Errorf("%w: %d bytes, limit %d bytes", ErrFilenameTooLong, len(name)+len(suffix), limit)
+	}
+	return truncateFilename(name, limit-len(suffix)) + suffix, nil
+}
+
+// fileName 计算存储文件名(不含压缩及加密后缀), custom 表示使用了自定义文件名(或 DedupNone 时带 Uid 的文件名)
+func (s *Storage) fileName(result *FileStorageResult) (name string, custom bool, err error) {
+	if s.nameFunc != nil {
+		if name = strings.TrimSpace(s.nameFunc(result)); name != "" {
+			if name == "." || name == ".." || strings.ContainsAny(name, "/\\\x00") {
+				err = fmt.Errorf("%w: %q", ErrInvalidFileName, name)
+				return
+			}
+			return name, true, nil
+		}
+	}
+	// 不复用相同内容时以 Uid 区分, 不参与内容索引
+	if s.dedupScope == DedupNone {
+		return result.Hash + "-" + strconv.FormatInt(result.Uid, 10) + result.FileExt, true, nil
+	}
+	return result.Hash + result.FileExt, false, nil
+}
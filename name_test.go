@@ -0,0 +1,64 @@
+package fileupload
+
+import (
+	"errors"
+	"path"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestNameFuncOriginName(t *testing.T) {
+	s, _ := newTestStorage(t, WithNameFunc(func(result *FileStorageResult) string { return result.OriginName }))
+	result, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: "avatars"}, openBytes([]byte("avatar")), "user-42-avatar.png", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Name != "user-42-avatar.png" || path.Base(result.PathUri) != "user-42-avatar.png" {
+		t.Errorf("Name = %s, PathUri = %s, want the origin name", result.Name, result.PathUri)
+	}
+	hash, _, _ := s.HashReader(strings.NewReader("avatar"))
+	if result.Hash != hash {
+		t.Errorf("Hash = %s, want %s", result.Hash, hash)
+	}
+	if got := readFile(t, result.PathAbs); got != "avatar" {
+		t.Errorf("content = %q", got)
+	}
+}
+
+func TestNameFuncRejectsSeparators(t *testing.T) {
+	for _, name := range []string{"../escape.png", "a/b.png", `a\b.png`, ".."} {
+		s, _ := newTestStorage(t, WithNameFunc(func(*FileStorageResult) string { return name }))
+		_, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("x")), "a.png", 1)
+		if !errors.Is(err, ErrInvalidFileName) {
+			t.Errorf("name %q: err = %v, want ErrInvalidFileName", name, err)
+		}
+	}
+}
+
+func TestMaxFilenameLength(t *testing.T) {
+	long := strings.Repeat("文件", 60) + ".pdf" // 364 字节
+	origin := WithNameFunc(func(result *FileStorageResult) string { return result.OriginName })
+
+	s, _ := newTestStorage(t, origin)
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("x")), long, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Name) > 255 || !utf8.ValidString(result.Name) || path.Ext(result.Name) != ".pdf" {
+		t.Errorf("Name = %q (%d bytes), want a valid name within 255 bytes keeping .pdf", result.Name, len(result.Name))
+	}
+	if got := readFile(t, result.PathAbs); got != "x" {
+		t.Errorf("content = %q", got)
+	}
+
+	s, _ = newTestStorage(t, origin, WithMaxFilenameLength(64))
+	if result, err = s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("x")), long, 1); err != nil || len(result.Name) > 64 {
+		t.Errorf("Name = %q, err = %v, want at most 64 bytes", result.Name, err)
+	}
+
+	s, _ = newTestStorage(t, origin, WithMaxFilenameLength(64), WithFilenameLengthStrict(true))
+	if _, err = s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("x")), long, 1); !errors.Is(err, ErrFilenameTooLong) {
+		t.Errorf("err = %v, want ErrFilenameTooLong", err)
+	}
+}
@@ -0,0 +1,5 @@
+//go:build !race
+
+package fileupload
+
+const raceEnabled = false
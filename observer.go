@@ -0,0 +1,40 @@
+package fileupload
+
+// Observer 上传过程观察者(如统计上传数, 写入字节数, 去重命中数, 失败数), 方法在存储过程中同步调用, 应快速返回
+// 可嵌入 NopObserver 仅实现需要的方法
+type Observer interface {
+	UploadStarted()                         // 开始处理一个上传文件
+	BytesWritten(n int64)                   // 新写入文件 n 字节
+	Stored(result *FileStorageResult)       // 文件存储成功(含复用已存储的相同内容)
+	Deduplicated(result *FileStorageResult) // 复用已存储的相同内容, 未写入新文件
+	Failed(err error)                       // 文件存储失败
+}
+
+// NopObserver 不做任何处理的观察者
+type NopObserver struct{}
+
+func (NopObserver) UploadStarted()                  {}
+func (NopObserver) BytesWritten(int64)              {}
+func (NopObserver) Stored(*FileStorageResult)       {}
+func (NopObserver) Deduplicated(*FileStorageResult) {}
+func (NopObserver) Failed(error)                    {}
+
+// WithObserver 上传过程观察者, 默认 NopObserver
+func WithObserver(observer Observer) Opts {
+	return func(s *Storage) { s.observer = observer }
+}
+
+// observe 上传过程观察者
+func (s *Storage) observe() Observer {
+	if s.observer == nil {
+		return NopObserver{}
+	}
+	return s.observer
+}
+
+// started 开始处理一个上传文件, 仅计算存储结果(DryRun)时不计入
+func (s *Storage) started(param *FileStorage) {
+	if !param.DryRun {
+		s.observe().UploadStarted()
+	}
+}
@@ -0,0 +1,84 @@
+package fileupload
+
+import (
+	"sync"
+	"testing"
+)
+
+// countingObserver 统计各方法调用次数的观察者
+type countingObserver struct {
+	mutex        sync.Mutex
+	started      int
+	bytes        int64
+	stored       int
+	deduplicated int
+	failed       int
+}
+
+func (o *countingObserver) UploadStarted() {
+	o.mutex.Lock()
+	o.started++
+	o.mutex.Unlock()
+}
+
+func (o *countingObserver) BytesWritten(n int64) {
+	o.mutex.Lock()
+	o.bytes += n
+	o.mutex.Unlock()
+}
+
+func (o *countingObserver) Stored(*FileStorageResult) {
+	o.mutex.Lock()
+	o.stored++
+	o.mutex.Unlock()
+}
+
+func (o *countingObserver) Deduplicated(*FileStorageResult) {
+	o.mutex.Lock()
+	o.deduplicated++
+	o.mutex.Unlock()
+}
+
+func (o *countingObserver) Failed(error) {
+	o.mutex.Lock()
+	o.failed++
+	o.mutex.Unlock()
+}
+
+func TestObserverBatch(t *testing.T) {
+	observer := &countingObserver{}
+	s, _ := newTestStorage(t, WithObserver(observer), WithMaxFileSize(8))
+	files := formFileHeaders(t,
+		testFile{field: "files", filename: "a.txt", content: "aaa"},
+		testFile{field: "files", filename: "b.txt", content: "bbbb"},
+		testFile{field: "files", filename: "copy.txt", content: "aaa"},
+	)
+	if _, err := s.MultipartCopy(&FileStorage{}, files...); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("too large file")), "c.txt", 14); err == nil {
+		t.Fatal("expected a size error")
+	}
+	if _, err := s.CopyMultipartFile(&FileStorage{DryRun: true}, openBytes([]byte("dry")), "d.txt", 3); err != nil {
+		t.Fatal(err)
+	}
+	observer.mutex.Lock()
+	defer observer.mutex.Unlock()
+	if observer.started != 4 || observer.stored != 3 || observer.deduplicated != 1 || observer.failed != 1 {
+		t.Errorf("started/stored/deduplicated/failed = %d/%d/%d/%d, want 4/3/1/1",
+			observer.started, observer.stored, observer.deduplicated, observer.failed)
+	}
+	if observer.bytes != 7 {
+		t.Errorf("bytes written = %d, want 7", observer.bytes)
+	}
+}
+
+func TestNopObserverDefault(t *testing.T) {
+	s, _ := newTestStorage(t)
+	if _, ok := s.observe().(NopObserver); !ok {
+		t.Errorf("default observer = %T, want NopObserver", s.observe())
+	}
+	if allocs := testing.AllocsPerRun(100, func() { s.observe().BytesWritten(1) }); allocs != 0 {
+		t.Errorf("no-op observer allocates %v times per call", allocs)
+	}
+}
@@ -0,0 +1,25 @@
+package fileupload
+
+import (
+	"mime/multipart"
+)
+
+// MultipartCopyPartial 文件拷贝, 单个文件失败时不中止其余文件的存储
+// results 与 errs 均与 files 按下标一一对应, 失败(或为nil)的文件对应的 results 为nil; 不受 WithAtomicBatch 影响
+func (s *Storage) MultipartCopyPartial(param *FileStorage, files ...*multipart.FileHeader) (results []*FileStorageResult, errs []error) {
+	length := len(files)
+	results, errs = make([]*FileStorageResult, length), make([]error, length)
+	_ = s.parallel(length, func(i int) error {
+		if files[i] == nil {
+			return nil
+		}
+		result, err := s.multipartCopy(param, files[i], nil)
+		if err != nil {
+			errs[i] = err
+			return nil
+		}
+		results[i] = result
+		return nil
+	})
+	return
+}
@@ -0,0 +1,33 @@
+package fileupload
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMultipartCopyPartial(t *testing.T) {
+	s, dir := newTestStorage(t, WithRequireExtension(true), WithAtomicBatch(true), WithConcurrency(2))
+	files := formFileHeaders(t,
+		testFile{field: "gallery", filename: "1.png", content: "first"},
+		testFile{field: "gallery", filename: "no-extension", content: "second"},
+		testFile{field: "gallery", filename: "3.png", content: "third"},
+	)
+	results, errs := s.MultipartCopyPartial(&FileStorage{}, files...)
+	if len(results) != 3 || len(errs) != 3 {
+		t.Fatalf("got %d results and %d errors, want 3 each", len(results), len(errs))
+	}
+	if errs[0] != nil || errs[2] != nil || !errors.Is(errs[1], ErrMissingExtension) {
+		t.Errorf("errs = %v, want only file 2 to fail with ErrMissingExtension", errs)
+	}
+	if results[1] != nil {
+		t.Errorf("failed file has a result: %+v", results[1])
+	}
+	for i, content := range map[int]string{0: "first", 2: "third"} {
+		if results[i] == nil || readFile(t, results[i].PathAbs) != content {
+			t.Errorf("result %d = %+v", i, results[i])
+		}
+	}
+	if names := listTree(t, dir); len(names) != 2 {
+		t.Errorf("storage = %v, want the two valid files", names)
+	}
+}
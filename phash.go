@@ -0,0 +1,55 @@
+package fileupload
+
+import (
+	"encoding/hex"
+	"fmt"
+	"image"
+	"math/bits"
+)
+
+// WithPerceptualHash 为图片计算感知哈希(dHash, 64位)并记录于存储结果的 PerceptualHash, 用于按汉明距离(见 HammingDistance)查找相似图片
+// 不影响文件命名及相同内容复用; 非图片文件及像素数超出 WithMaxImagePixels 上限的图片不计算
+func WithPerceptualHash(enable bool) Opts {
+	return func(s *Storage) { s.perceptualHash = enable }
+}
+
+// dHash 差异哈希: 缩放为9x8灰度图后比较每行相邻像素的亮度, 以16位十六进制编码
+func dHash(img image.Image) string {
+	small := resizeImage(img, 9, 8)
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if luminance(small, x, y) > luminance(small, x+1, y) {
+				hash |= 1
+			}
+		}
+	}
+	return fmt.Sprintf("%016x", hash)
+}
+
+// luminance 像素的亮度
+func luminance(img *image.RGBA, x int, y int) uint32 {
+	c := img.RGBAAt(x, y)
+	return 299*uint32(c.R) + 587*uint32(c.G) + 114*uint32(c.B)
+}
+
+// HammingDistance 两个感知哈希的汉明距离(不同的位数), 距离越小图片越相似(通常不超过10可视为相似)
+func HammingDistance(a string, b string) (int, error) {
+	if len(a) != len(b) {
+		return 0, fmt.Errorf("hamming distance: length mismatch %d != %d", len(a), len(b))
+	}
+	x, err := hex.DecodeString(a)
+	if err != nil {
+		return 0, err
+	}
+	y, err := hex.DecodeString(b)
+	if err != nil {
+		return 0, err
+	}
+	distance := 0
+	for i := range x {
+		distance += bits.OnesCount8(x[i] ^ y[i])
+	}
+	return distance, nil
+}
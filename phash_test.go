@@ -0,0 +1,83 @@
+package fileupload
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// patternImage 生成带有明暗变化的图片, invert 时明暗相反
+func patternImage(invert bool) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 96, 64))
+	for x := 0; x < 96; x++ {
+		for y := 0; y < 64; y++ {
+			v := uint8((x*x/3 + y*5) % 256)
+			if (x/12+y/16)%2 == 0 {
+				v = 255 - v/2
+			}
+			if invert {
+				v = 255 - v
+			}
+			img.Set(x, y, color.RGBA{R: v, G: v / 2, B: 255 - v, A: 255})
+		}
+	}
+	return img
+}
+
+func TestPerceptualHash(t *testing.T) {
+	s, _ := newTestStorage(t, WithPerceptualHash(true))
+	store := func(name string, encode func(*bytes.Buffer) error) *FileStorageResult {
+		t.Helper()
+		buf := &bytes.Buffer{}
+		if err := encode(buf); err != nil {
+			t.Fatal(err)
+		}
+		result, err := s.CopyMultipartFile(&FileStorage{}, openBytes(buf.Bytes()), name, int64(buf.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.PerceptualHash) != 16 {
+			t.Fatalf("%s: PerceptualHash = %q", name, result.PerceptualHash)
+		}
+		return result
+	}
+	original := store("a.png", func(buf *bytes.Buffer) error { return png.Encode(buf, patternImage(false)) })
+	reencoded := store("a.jpg", func(buf *bytes.Buffer) error {
+		return jpeg.Encode(buf, patternImage(false), &jpeg.Options{Quality: 60})
+	})
+	different := store("b.png", func(buf *bytes.Buffer) error { return png.Encode(buf, patternImage(true)) })
+
+	if original.Hash == reencoded.Hash {
+		t.Fatal("encodings have the same content hash")
+	}
+	if d, err := HammingDistance(original.PerceptualHash, reencoded.PerceptualHash); err != nil || d > 10 {
+		t.Errorf("re-encoded distance = %d, %v, want at most 10", d, err)
+	}
+	if d, err := HammingDistance(original.PerceptualHash, different.PerceptualHash); err != nil || d <= 10 {
+		t.Errorf("different image distance = %d, %v, want more than 10", d, err)
+	}
+	// 不影响文件命名
+	if original.Name != original.Hash+".png" {
+		t.Errorf("Name = %s, want hash naming", original.Name)
+	}
+
+	text, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("plain text")), "a.txt", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text.PerceptualHash != "" {
+		t.Errorf("non-image PerceptualHash = %s", text.PerceptualHash)
+	}
+}
+
+func TestHammingDistance(t *testing.T) {
+	if d, err := HammingDistance("00000000000000ff", "000000000000000f"); err != nil || d != 4 {
+		t.Errorf("distance = %d, %v, want 4", d, err)
+	}
+	if _, err := HammingDistance("00", "0000"); err == nil {
+		t.Error("length mismatch accepted")
+	}
+}
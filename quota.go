@@ -0,0 +1,162 @@
+package fileupload
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// WithQuota 按存储子目录(如租户目录)限制已存储文件的总字节数, limit 返回小于等于0时表示不限制
+// 配额作用于文件实际所在的子目录: 开启 WithSeparateByCategory 时为 资源分类/子目录, 子目录模板及分片目录计入其中
+// 子目录首次写入时统计其中已有文件的大小, 之后随存储及删除增量更新
+func WithQuota(limit func(subDirectory string) (limitBytes int64)) Opts {
+	return func(s *Storage) { s.quota = limit }
+}
+
+// quotaUsage 各子目录已使用的字节数, 以子目录绝对路径为键
+type quotaUsage struct {
+	mutex sync.Mutex
+	used  map[string]int64
+}
+
+// quotaDirectory 配额统计的子目录 subDirectory(相对于存储目录)的绝对路径
+func (s *Storage) quotaDirectory(param *FileStorage, subDirectory string) (string, error) {
+	storageDirectory := s.storageDirectory
+	if param.StorageDirectory != "" {
+		storageDirectory = param.StorageDirectory
+	}
+	if storageDirectory == "" {
+		storageDirectory = "."
+	}
+	return filepath.Abs(filepath.Join(storageDirectory, filepath.FromSlash(path.Clean("/"+subDirectory))))
+}
+
+// reserveQuota 在子目录 subDirectory(相对于存储目录, 为文件实际所在位置的配额子目录)预占 n 字节配额, 超出时返回 ErrQuotaExceeded
+// 返回的 commit 需调用一次, 以实际写入的字节数修正预占量(写入失败时为0)
+func (s *Storage) reserveQuota(param *FileStorage, subDirectory string, n int64) (commit func(written int64), err error) {
+	commit = func(int64) {}
+	if s.quota == nil {
+		return
+	}
+	limit := s.quota(subDirectory)
+	if limit <= 0 {
+		return
+	}
+	directory, err := s.quotaDirectory(param, subDirectory)
+	if err != nil {
+		return
+	}
+	if err = s.initQuota(directory); err != nil {
+		return
+	}
+
+	usage := &s.usage
+	usage.mutex.Lock()
+	defer usage.mutex.Unlock()
+	used := usage.used[directory]
+	if used+n > limit {
+		err = fmt.Errorf("%w: %s used %d bytes, limit %d bytes, incoming %d bytes", ErrQuotaExceeded, subDirectory, used, limit, n)
+		return
+	}
+	usage.used[directory] = used + n
+	commit = func(written int64) {
+		usage.mutex.Lock()
+		usage.used[directory] += written - n
+		usage.mutex.Unlock()
+	}
+	return
+}
+
+// initQuota 首次使用子目录配额时统计其中已有文件的大小, 统计在该子目录的锁内进行, 不阻塞其它子目录的存储
+func (s *Storage) initQuota(directory string) error {
+	usage := &s.usage
+	initialized := func() bool {
+		usage.mutex.Lock()
+		defer usage.mutex.Unlock()
+		_, ok := usage.used[directory]
+		return ok
+	}
+	if initialized() {
+		return nil
+	}
+	unlock := s.locker.lock("quota:" + directory)
+	defer unlock()
+	if initialized() {
+		return nil
+	}
+	used, _, err := s.directoryUsage(directory)
+	if err != nil {
+		return err
+	}
+	usage.mutex.Lock()
+	defer usage.mutex.Unlock()
+	if usage.used == nil {
+		usage.used = make(map[string]int64)
+	}
+	usage.used[directory] = used
+	return nil
+}
+
+// releaseQuota 删除文件后归还其所在子目录的配额
+func (s *Storage) releaseQuota(name string, size int64) {
+	if s.quota == nil || size <= 0 {
+		return
+	}
+	usage := &s.usage
+	usage.mutex.Lock()
+	defer usage.mutex.Unlock()
+	// 子目录可能嵌套, 取最长的匹配
+	match := ""
+	for directory := range usage.used {
+		if strings.HasPrefix(name, directory+string(filepath.Separator)) && len(directory) > len(match) {
+			match = directory
+		}
+	}
+	if match != "" {
+		usage.used[match] -= size
+	}
+}
+
+// directoryUsage 通过存储的文件系统统计目录下全部文件的大小及文件数, 目录不存在时均为0
+func (s *Storage) directoryUsage(directory string) (size int64, count int, err error) {
+	reader, ok := s.filesystem().(dirReader)
+	if !ok {
+		return 0, 0, fmt.Errorf("usage: file system does not support reading directories")
+	}
+	var walk func(directory string) error
+	walk = func(directory string) error {
+		entries, err := reader.ReadDir(directory)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				if err = walk(filepath.Join(directory, entry.Name())); err != nil {
+					return err
+				}
+				continue
+			}
+			if !entry.Type().IsRegular() {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return err
+			}
+			size += info.Size()
+			count++
+		}
+		return nil
+	}
+	err = walk(directory)
+	return
+}
@@ -0,0 +1,60 @@
+package fileupload
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestQuotaBoundary(t *testing.T) {
+	s, dir := newTestStorage(t, WithQuota(func(subDirectory string) int64 {
+		if subDirectory == "tenant-a" {
+			return 10
+		}
+		return 0
+	}))
+	// 已有文件计入用量
+	if err := os.MkdirAll(filepath.Join(dir, "tenant-a"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "tenant-a", "existing.txt"), []byte("1234"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tenant := &FileStorage{StorageSubDirectory: "tenant-a"}
+	store := func(param *FileStorage, content string) (*FileStorageResult, error) {
+		return s.CopyMultipartFile(param, openBytes([]byte(content)), "a.txt", int64(len(content)))
+	}
+	first, err := store(tenant, "123456")
+	if err != nil {
+		t.Fatalf("store up to the quota: %v", err)
+	}
+	if _, err = store(tenant, "x"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("store over the quota: err = %v, want ErrQuotaExceeded", err)
+	}
+	if _, err = store(&FileStorage{StorageSubDirectory: "tenant-b"}, strings.Repeat("x", 100)); err != nil {
+		t.Errorf("unlimited tenant: %v", err)
+	}
+	// 删除后归还配额
+	if err = s.Delete(first); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store(tenant, "abcdef"); err != nil {
+		t.Errorf("store after delete: %v", err)
+	}
+}
+
+func TestQuotaWithCategory(t *testing.T) {
+	var asked []string
+	s, _ := newTestStorage(t, WithSeparateByCategory(true), WithQuota(func(subDirectory string) int64 {
+		asked = append(asked, subDirectory)
+		return 4
+	}))
+	if _, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: "tenant"}, openBytes([]byte("12345")), "a.txt", 5); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("err = %v, want ErrQuotaExceeded", err)
+	}
+	if len(asked) != 1 || asked[0] != "document/tenant" {
+		t.Errorf("quota asked for %v, want [document/tenant]", asked)
+	}
+}
@@ -0,0 +1,6 @@
+//go:build race
+
+package fileupload
+
+// raceEnabled 竞态检测下 sync.Pool 会随机丢弃对象, 分配次数断言不可靠
+const raceEnabled = true
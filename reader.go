@@ -0,0 +1,88 @@
+package fileupload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+)
+
+// CopyMultipartReader 逐个读取 multipart 分段并存储其中的文件, 非文件字段忽略; 不解析完整表单, 适用于超大的上传请求
+// 每个文件先暂存至临时目录(未设置时为存储目录)后按常规流程存储, 同一时间只暂存一个文件; 存储结果的 Field 记录文件所属字段
+// WithMaxFileSize 在暂存时即生效, WithMaxTotalSize 按已读取的文件累计检查
+func (s *Storage) CopyMultipartReader(param *FileStorage, mr *multipart.Reader) (succeeded []*FileStorageResult, err error) {
+	defer func() { succeeded = flattenLinks(succeeded) }()
+	b := newBatch(context.Background())
+	if s.requestDedup == RequestDedupMerge {
+		defer func() { succeeded = b.merge(succeeded) }()
+	}
+	defer func() { succeeded = s.rollback(b, succeeded, err) }()
+	total := int64(0)
+	for {
+		var part *multipart.Part
+		part, err = mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			err = nil
+			return
+		}
+		if err != nil {
+			return
+		}
+		if part.FileName() == "" {
+			_ = part.Close()
+			continue
+		}
+		var result *FileStorageResult
+		result, err = s.partCopy(param, part, b, &total)
+		_ = part.Close()
+		if err != nil {
+			return
+		}
+		result.Field = part.FormName()
+		succeeded = append(succeeded, result)
+	}
+}
+
+// partCopy 暂存 multipart 分段中的文件后存储, total 为本次请求已读取的文件总字节数
+func (s *Storage) partCopy(param *FileStorage, part *multipart.Part, b *batch, total *int64) (result *FileStorageResult, err error) {
+	fs := s.filesystem()
+	directory := s.tempDirectory
+	if directory == "" {
+		directory = s.storageDirectory
+		if param.StorageDirectory != "" {
+			directory = param.StorageDirectory
+		}
+	}
+	if directory == "" {
+		directory = "."
+	}
+	if err = fs.MkdirAll(directory, 0755); err != nil {
+		return
+	}
+	spool, err := createTemp(fs, directory, "part.", tempFileSuffix, 0600)
+	if err != nil {
+		return
+	}
+	name := spool.Name()
+	defer func() { _ = fs.Remove(name) }()
+	size, err := s.buffers.copy(spool, &sizeLimitReader{r: part, limit: s.maxFileSize})
+	if e := spool.Close(); e != nil && err == nil {
+		err = e
+	}
+	if err != nil {
+		result = &FileStorageResult{OriginName: s.sanitizeFilename(part.FileName()), RawOriginName: part.FileName()}
+		s.started(param)
+		s.notify(result, err)
+		return
+	}
+	if *total += size; s.maxTotalSize > 0 && *total > s.maxTotalSize {
+		return nil, fmt.Errorf("%w: more than %d bytes, limit %d bytes", ErrTotalSizeExceeded, *total, s.maxTotalSize)
+	}
+	file := &multipart.FileHeader{
+		Filename: part.FileName(),
+		Header:   part.Header,
+		Size:     size,
+	}
+	return s.fileHeaderCopy(param, file, func() (io.ReadSeekCloser, error) { return fs.Open(name) }, b)
+}
@@ -0,0 +1,61 @@
+package fileupload
+
+import (
+	"errors"
+	"mime"
+	"mime/multipart"
+	"testing"
+)
+
+// multipartReader 创建读取上传表单的 multipart.Reader
+func multipartReader(t *testing.T, files []testFile, values ...string) *multipart.Reader {
+	t.Helper()
+	body, contentType := multipartBody(t, files, values...)
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return multipart.NewReader(body, params["boundary"])
+}
+
+func TestCopyMultipartReader(t *testing.T) {
+	s, dir := newTestStorage(t)
+	mr := multipartReader(t, []testFile{
+		{field: "first", filename: "a.txt", content: "first file"},
+		{field: "second", filename: "b.txt", content: "second file"},
+	}, "title", "ignored text field")
+	results, err := s.CopyMultipartReader(&FileStorage{}, mr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2 file parts", len(results))
+	}
+	for i, want := range []testFile{{field: "first", filename: "a.txt", content: "first file"}, {field: "second", filename: "b.txt", content: "second file"}} {
+		if results[i].Field != want.field || results[i].OriginName != want.filename || readFile(t, results[i].PathAbs) != want.content {
+			t.Errorf("result %d = %s %s", i, results[i].Field, results[i].OriginName)
+		}
+	}
+	if names := listTree(t, dir); len(names) != 2 {
+		t.Errorf("storage = %v, want only the two files", names)
+	}
+}
+
+func TestCopyMultipartReaderMaxFileSize(t *testing.T) {
+	s, dir := newTestStorage(t, WithMaxFileSize(8))
+	mr := multipartReader(t, []testFile{
+		{field: "files", filename: "a.txt", content: "small"},
+		{field: "files", filename: "b.txt", content: "larger than the limit"},
+	})
+	succeeded, err := s.CopyMultipartReader(&FileStorage{}, mr)
+	if !errors.Is(err, ErrFileTooLarge) {
+		t.Fatalf("err = %v, want ErrFileTooLarge", err)
+	}
+	// 超出上限的文件在暂存时即停止读取, 之前存储的文件保留在结果中
+	if len(succeeded) != 1 || succeeded[0].OriginName != "a.txt" {
+		t.Errorf("succeeded = %+v, want only a.txt", succeeded)
+	}
+	if names := listTree(t, dir); len(names) != 1 {
+		t.Errorf("storage = %v, want only a.txt", names)
+	}
+}
@@ -0,0 +1,37 @@
+package fileupload
+
+import (
+	"fmt"
+	"os"
+)
+
+// WithReadOnlyAfterWrite 存储完成后去除文件的写权限, 防止已存储的内容被意外修改; 删除文件取决于所在目录的权限, 不受影响
+// 文件系统(见 WithFileSystem)需支持修改文件权限, 否则存储失败
+func WithReadOnlyAfterWrite(readOnly bool) Opts {
+	return func(s *Storage) { s.readOnlyAfterWrite = readOnly }
+}
+
+// chmoder 支持修改文件权限的文件系统
+type chmoder interface {
+	Chmod(name string, mode os.FileMode) error
+}
+
+func (osFileSystem) Chmod(name string, mode os.FileMode) error {
+	return os.Chmod(name, mode)
+}
+
+// makeReadOnly 在文件原有权限的基础上去除写权限
+func (s *Storage) makeReadOnly(u *upload, name string) error {
+	if !s.readOnlyAfterWrite {
+		return nil
+	}
+	fs, ok := u.fs.(chmoder)
+	if !ok {
+		return fmt.Errorf("read only: file system does not support chmod")
+	}
+	stat, err := u.fs.Stat(name)
+	if err != nil {
+		return err
+	}
+	return fs.Chmod(name, stat.Mode().Perm()&^0222)
+}
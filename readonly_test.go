@@ -0,0 +1,38 @@
+package fileupload
+
+import (
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestReadOnlyAfterWrite(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix permissions")
+	}
+	s, _ := newTestStorage(t, WithReadOnlyAfterWrite(true), WithFileMode(0640))
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("immutable")), "a.txt", 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stat, err := os.Stat(result.PathAbs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := stat.Mode().Perm(); mode != 0440 {
+		t.Errorf("mode = %v, want %v", mode, os.FileMode(0440))
+	}
+	if err = s.Delete(result); err != nil {
+		t.Fatalf("delete read-only file: %v", err)
+	}
+	if _, err = os.Stat(result.PathAbs); !os.IsNotExist(err) {
+		t.Errorf("file still exists: %v", err)
+	}
+}
+
+func TestReadOnlyAfterWriteUnsupported(t *testing.T) {
+	s, _ := newTestStorage(t, WithReadOnlyAfterWrite(true), WithFileSystem(struct{ FileSystem }{osFileSystem{}}))
+	if _, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("x")), "a.txt", 1); err == nil {
+		t.Error("stored read-only on a file system without chmod")
+	}
+}
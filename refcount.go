@@ -0,0 +1,112 @@
+package fileupload
+
+import (
+	"path/filepath"
+	"sync"
+)
+
+// RefCounter 按已存储文件记录引用数, 实现需保证并发安全(可基于数据库实现)
+// 每次存储成功(含复用已存储的相同内容)引用数加1, Delete 时减1, 减至0时才删除文件
+// 计数的键为文件相对于存储目录的路径(与 FileStorageResult.PathRlt 一致), 因此不同子目录下的相同内容分别计数; Move 时引用数随文件转移
+type RefCounter interface {
+	// Incr 引用数加1, 返回加1后的引用数
+	Incr(key string) (int64, error)
+	// Decr 引用数减1, 返回减1后的引用数, 不小于0
+	Decr(key string) (int64, error)
+	// Count 当前引用数
+	Count(key string) (int64, error)
+}
+
+// WithRefCounter 已存储文件的引用计数, 存储时增加引用, Delete 仅在引用数减至0时删除文件
+func WithRefCounter(counter RefCounter) Opts {
+	return func(s *Storage) { s.refCounter = counter }
+}
+
+// MemoryRefCounter 基于内存的引用计数
+type MemoryRefCounter struct {
+	mutex  sync.Mutex
+	counts map[string]int64
+}
+
+// NewMemoryRefCounter 创建基于内存的引用计数
+func NewMemoryRefCounter() *MemoryRefCounter {
+	return &MemoryRefCounter{
+		counts: make(map[string]int64),
+	}
+}
+
+func (m *MemoryRefCounter) Incr(key string) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.counts[key]++
+	return m.counts[key], nil
+}
+
+func (m *MemoryRefCounter) Decr(key string) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	n := m.counts[key] - 1
+	if n <= 0 {
+		delete(m.counts, key)
+		return 0, nil
+	}
+	m.counts[key] = n
+	return n, nil
+}
+
+func (m *MemoryRefCounter) Count(key string) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.counts[key], nil
+}
+
+// counting 是否记录引用数, 不复用相同内容(DedupNone)时每个文件独立, 不计数
+func (s *Storage) counting() bool {
+	return s.refCounter != nil && s.dedupScope != DedupNone
+}
+
+// refKey 已存储文件 name(绝对路径)的引用计数键, 即相对于存储目录的路径; 不在存储目录下时为绝对路径
+func (s *Storage) refKey(name string) string {
+	storageDirectory := s.storageDirectory
+	if storageDirectory == "" {
+		storageDirectory = "."
+	}
+	root, err := filepath.Abs(storageDirectory)
+	if err != nil {
+		return filepath.ToSlash(filepath.Clean(name))
+	}
+	return relativePath(root, name)
+}
+
+// reference 存储成功后增加引用数, 链接至其它子目录的位置(见 FileStorage.LinkSubDirectories)分别计数
+func (s *Storage) reference(result *FileStorageResult) error {
+	if !s.counting() || result.PathAbs == "" {
+		return nil
+	}
+	for _, link := range result.Links {
+		if err := s.reference(link); err != nil {
+			return err
+		}
+	}
+	key := s.refKey(result.PathAbs)
+	if _, err := s.refCounter.Incr(key); err != nil {
+		return err
+	}
+	result.referenced = key
+	return nil
+}
+
+// moveReferences 文件由 source 移动至 target 后转移其引用数, 目标位置已有相同内容的文件时合并引用数
+func (s *Storage) moveReferences(source string, target string) error {
+	if !s.counting() {
+		return nil
+	}
+	from, to := s.refKey(source), s.refKey(target)
+	n, err := s.refCounter.Count(from)
+	for ; err == nil && n > 0; n-- {
+		if _, err = s.refCounter.Incr(to); err == nil {
+			_, err = s.refCounter.Decr(from)
+		}
+	}
+	return err
+}
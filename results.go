@@ -0,0 +1,42 @@
+package fileupload
+
+// TotalSize 存储结果的文件大小合计
+func TotalSize(results []*FileStorageResult) (total int64) {
+	for _, v := range results {
+		if v != nil {
+			total += v.Size
+		}
+	}
+	return
+}
+
+// URIs 存储结果的资源访问路径, 按原有顺序去重
+func URIs(results []*FileStorageResult) []string {
+	uris := make([]string, 0, len(results))
+	exists := make(map[string]struct{}, len(results))
+	for _, v := range results {
+		if v == nil {
+			continue
+		}
+		if _, ok := exists[v.PathUri]; ok {
+			continue
+		}
+		exists[v.PathUri] = struct{}{}
+		uris = append(uris, v.PathUri)
+	}
+	return uris
+}
+
+// ByHash 按文件哈希值索引存储结果, 相同哈希值保留第一个
+func ByHash(results []*FileStorageResult) map[string]*FileStorageResult {
+	hashes := make(map[string]*FileStorageResult, len(results))
+	for _, v := range results {
+		if v == nil {
+			continue
+		}
+		if _, ok := hashes[v.Hash]; !ok {
+			hashes[v.Hash] = v
+		}
+	}
+	return hashes
+}
@@ -0,0 +1,28 @@
+package fileupload
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResultHelpers(t *testing.T) {
+	results := []*FileStorageResult{
+		{Size: 10, Hash: "a", PathUri: "/a.png", Name: "first"},
+		nil,
+		{Size: 20, Hash: "b", PathUri: "/b.png"},
+		{Size: 10, Hash: "a", PathUri: "/a.png", Name: "second"},
+	}
+	if total := TotalSize(results); total != 40 {
+		t.Errorf("TotalSize = %d, want 40", total)
+	}
+	if uris := URIs(results); !reflect.DeepEqual(uris, []string{"/a.png", "/b.png"}) {
+		t.Errorf("URIs = %v, want de-duplicated [/a.png /b.png]", uris)
+	}
+	hashes := ByHash(results)
+	if len(hashes) != 2 || hashes["a"].Name != "first" || hashes["b"].Size != 20 {
+		t.Errorf("ByHash = %v, want the first result per hash", hashes)
+	}
+	if TotalSize(nil) != 0 || len(URIs(nil)) != 0 || len(ByHash(nil)) != 0 {
+		t.Error("helpers on nil results are not empty")
+	}
+}
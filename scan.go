@@ -0,0 +1,32 @@
+package fileupload
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// Scanner 内容扫描(如ClamAV病毒扫描), 返回非nil错误时拒绝存储
+type Scanner func(ctx context.Context, reader io.Reader) error
+
+// WithScanner 内容扫描, 在临时文件重命名至目标位置之前扫描(解密及解压后的)文件内容
+// 扫描拒绝时删除临时文件并返回包装了 ErrScanRejected 及扫描错误的错误
+func WithScanner(scanner Scanner) Opts {
+	return func(s *Storage) { s.scanner = scanner }
+}
+
+// scan 扫描临时文件内容
+func (s *Storage) scan(u *upload, tmpName string) (err error) {
+	if s.scanner == nil {
+		return
+	}
+	reader, err := s.openFile(tmpName, u.result)
+	if err != nil {
+		return
+	}
+	defer func() { _ = reader.Close() }()
+	if err = s.scanner(u.context(), reader); err != nil {
+		err = fmt.Errorf("%w: %w", ErrScanRejected, err)
+	}
+	return
+}
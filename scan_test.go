@@ -0,0 +1,40 @@
+package fileupload
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+// errInfected 测试扫描器的拒绝原因
+var errInfected = errors.New("EICAR test signature")
+
+// fakeScanner 内容包含 EICAR 时拒绝
+func fakeScanner(ctx context.Context, r io.Reader) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if bytes.Contains(b, []byte("EICAR")) {
+		return errInfected
+	}
+	return nil
+}
+
+func TestScanner(t *testing.T) {
+	s, dir := newTestStorage(t, WithScanner(fakeScanner))
+	clean, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("clean")), "clean.txt", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("X5O!EICAR")), "virus.txt", 9)
+	if !errors.Is(err, ErrScanRejected) || !errors.Is(err, errInfected) {
+		t.Fatalf("err = %v, want ErrScanRejected wrapping the scanner error", err)
+	}
+	names := listTree(t, dir)
+	if len(names) != 1 || names[0] != clean.PathRlt {
+		t.Errorf("storage tree = %v, want only %s", names, clean.PathRlt)
+	}
+}
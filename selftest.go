@@ -0,0 +1,83 @@
+package fileupload
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// selfTestDirectory 自检时写入探测文件的子目录, 以 . 开头不会被 List 列出
+const selfTestDirectory = ".selftest"
+
+// selfTestPayload 自检写入的内容
+var selfTestPayload = []byte("fileupload self test")
+
+// SelfTest 在存储目录下写入探测内容(按配置压缩及加密), 读取并校验哈希值后删除, 用于就绪检查时确认存储可用(如挂载失效, 权限变更, 磁盘已满)
+// 不经过内容索引, 配额, 回调等存储流程; 任一步骤失败或 ctx 结束时立即返回错误
+func (s *Storage) SelfTest(ctx context.Context) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	if err = s.begin(); err != nil {
+		return
+	}
+	defer s.end()
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("self test: %w", err)
+		}
+	}()
+
+	storageDirectory := s.storageDirectory
+	if storageDirectory == "" {
+		storageDirectory = "."
+	}
+	directory := filepath.Join(storageDirectory, selfTestDirectory)
+	u := &upload{fs: s.filesystem(), tempDirectory: s.tempDirectory, buffers: &s.buffers, sync: s.sync, fileMode: s.fileMode}
+	// 删除本次创建的探测文件及目录
+	defer u.cleanup()
+	if err = u.mkdirAll(directory); err != nil {
+		return
+	}
+
+	result := &FileStorageResult{Compressed: s.compression != nil, Encrypted: s.encryption != nil}
+	tmp, _, err := u.writeTemp(filepath.Join(directory, "probe"), func(w io.Writer) error {
+		return s.encodeContent(w, bytes.NewReader(selfTestPayload), result)
+	})
+	if err != nil {
+		return
+	}
+	name := strings.TrimSuffix(tmp, tempFileSuffix) + ".bin"
+	if err = u.commitTemp(tmp, name, false); err != nil {
+		return
+	}
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	expected, _, err := s.sha256Reader(bytes.NewReader(selfTestPayload))
+	if err != nil {
+		return
+	}
+	rc, err := s.openFile(name, result)
+	if err != nil {
+		return
+	}
+	hash, size, err := s.sha256Reader(rc)
+	_ = rc.Close()
+	if err != nil {
+		return
+	}
+	if hash != expected || size != int64(len(selfTestPayload)) {
+		return fmt.Errorf("%w: read back %d bytes with hash %s", ErrIntegrityMismatch, size, hash)
+	}
+
+	if err = u.fs.Remove(name); err != nil {
+		return
+	}
+	u.forget(name)
+	return
+}
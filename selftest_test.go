@@ -0,0 +1,29 @@
+package fileupload
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSelfTest(t *testing.T) {
+	s, dir := newTestStorage(t)
+	if err := s.SelfTest(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if names := listTree(t, dir); len(names) != 0 {
+		t.Errorf("SelfTest left %v behind", names)
+	}
+
+	s, _ = newTestStorage(t, WithFileSystem(readOnlyFS{FileSystem: osFileSystem{}}))
+	if err := s.SelfTest(context.Background()); err == nil {
+		t.Error("SelfTest succeeded on a read-only directory")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s, _ = newTestStorage(t)
+	if err := s.SelfTest(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,159 @@
+package fileupload
+
+import (
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// FileSystem 以存储目录为根的 http.FileSystem, 可配合 http.FileServer 及 http.StripPrefix(资源访问前缀)提供已存储文件的访问
+// 拒绝路径穿越(..)及目录访问, 隐藏临时文件(.tmp), 元数据文件(见 WithSidecarMetadata)及以 . 开头的文件(及目录, 如缩略图目录); 文件按存储内容原样返回(压缩或加密存储的文件使用 Open 读取)
+func (s *Storage) FileSystem() http.FileSystem {
+	return &storedFileSystem{storage: s}
+}
+
+// storedFileSystem 已存储文件的 http.FileSystem
+type storedFileSystem struct {
+	storage *Storage
+}
+
+func (f *storedFileSystem) Open(name string) (http.File, error) {
+	if strings.ContainsAny(name, "\\\x00") {
+		return nil, os.ErrPermission
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == ".." {
+			return nil, os.ErrPermission
+		}
+		if strings.HasPrefix(segment, ".") || strings.HasSuffix(segment, tempFileSuffix) {
+			return nil, os.ErrNotExist
+		}
+	}
+	// 元数据文件记录了存储绝对路径及原始文件名, 与 List 一致不对外提供
+	if f.storage.isSidecar(path.Base(name)) {
+		return nil, os.ErrNotExist
+	}
+	storageDirectory := f.storage.storageDirectory
+	if storageDirectory == "" {
+		storageDirectory = "."
+	}
+	fullName := filepath.Join(storageDirectory, filepath.FromSlash(path.Clean("/"+name)))
+
+	fs := f.storage.filesystem()
+	stat, err := fs.Stat(fullName)
+	if err != nil {
+		return nil, err
+	}
+	if stat.IsDir() {
+		return nil, os.ErrPermission
+	}
+	file, err := fs.Open(fullName)
+	if err != nil {
+		return nil, err
+	}
+	if hf, ok := file.(http.File); ok {
+		return hf, nil
+	}
+	return &httpFile{File: file, stat: stat}, nil
+}
+
+// httpFile 将自定义文件系统的文件适配为 http.File
+type httpFile struct {
+	File
+	stat os.FileInfo
+}
+
+func (f *httpFile) Readdir(int) ([]os.FileInfo, error) {
+	return nil, os.ErrPermission
+}
+
+func (f *httpFile) Stat() (os.FileInfo, error) {
+	return f.stat, nil
+}
+
+// ServeFile 返回已存储的文件: 根据存储结果设置 Content-Type, Content-Disposition(使用清理后的原始文件名)及 ETag(文件哈希值)
+// 未压缩及加密存储的文件通过 http.ServeContent 返回, 支持 Range 及条件请求; 压缩或加密存储的文件解压及解密后完整返回
+// 文件不存在或无法读取时返回相应的错误状态码; 仅适用于使用 Storage 默认存储目录及资源访问前缀存储的文件
+func (s *Storage) ServeFile(w http.ResponseWriter, r *http.Request, result *FileStorageResult) {
+	name, err := s.AbsolutePath(result)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	fs := s.filesystem()
+	stat, err := fs.Stat(name)
+	if err == nil && stat.IsDir() {
+		err = os.ErrNotExist
+	}
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+
+	header := w.Header()
+	if contentType := servedContentType(result); contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	header.Set("X-Content-Type-Options", "nosniff")
+	filename := result.OriginName
+	if filename == "" {
+		filename = result.Name
+	}
+	if filename != "" {
+		header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	}
+	if result.Hash != "" {
+		header.Set("Etag", `"`+result.Hash+`"`)
+	}
+
+	if !result.Compressed && !result.Encrypted {
+		file, err := fs.Open(name)
+		if err != nil {
+			serveError(w, err)
+			return
+		}
+		defer func() { _ = file.Close() }()
+		http.ServeContent(w, r, filename, stat.ModTime(), file)
+		return
+	}
+
+	rc, err := s.openFile(name, result)
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+	defer func() { _ = rc.Close() }()
+	if result.Hash != "" && strings.Contains(r.Header.Get("If-None-Match"), `"`+result.Hash+`"`) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		_, _ = s.buffers.copy(w, rc)
+	}
+}
+
+// servedContentType 返回文件时使用的内容类型
+func servedContentType(result *FileStorageResult) string {
+	for _, contentType := range []string{result.ContentType, result.FinalContentType, result.DetectedContentType} {
+		if contentType != "" {
+			return contentType
+		}
+	}
+	return mime.TypeByExtension(result.FileExt)
+}
+
+// serveError 根据错误返回相应的状态码
+func serveError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case os.IsNotExist(err):
+		code = http.StatusNotFound
+	case os.IsPermission(err):
+		code = http.StatusForbidden
+	}
+	http.Error(w, http.StatusText(code), code)
+}
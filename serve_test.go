@@ -0,0 +1,111 @@
+package fileupload
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSystemDeniesTempAndTraversal(t *testing.T) {
+	s, dir := newTestStorage(t)
+	result, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: "docs"}, openBytes([]byte("stored")), "a.txt", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "docs", "partial"+tempFileSuffix), []byte("temp"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(filepath.Dir(dir), "secret.txt"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := s.FileSystem()
+	f, err := fs.Open("/" + result.PathRlt)
+	if err != nil {
+		t.Fatalf("stored file: %v", err)
+	}
+	content, err := io.ReadAll(f)
+	_ = f.Close()
+	if err != nil || string(content) != "stored" {
+		t.Errorf("stored file = %q, %v", content, err)
+	}
+	if _, err = fs.Open("/docs/partial" + tempFileSuffix); !os.IsNotExist(err) {
+		t.Errorf(".tmp file err = %v, want not exist", err)
+	}
+	if _, err = fs.Open("/../secret.txt"); !os.IsPermission(err) {
+		t.Errorf("../ path err = %v, want permission denied", err)
+	}
+	if _, err = fs.Open("/docs"); !os.IsPermission(err) {
+		t.Errorf("directory err = %v, want permission denied", err)
+	}
+
+	server := httptest.NewServer(http.FileServer(fs))
+	defer server.Close()
+	for _, name := range []string{
+		"/" + result.PathRlt,
+		"/docs/partial" + tempFileSuffix,
+		"/docs/partial%2e" + tempFileSuffix[1:],
+		"/docs/%2e%2e/%2e%2e/secret.txt",
+		"/docs/..%2f..%2fsecret.txt",
+	} {
+		resp, err := http.Get(server.URL + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if stored := name == "/"+result.PathRlt; stored != (resp.StatusCode == http.StatusOK) {
+			t.Errorf("GET %s = %d %q", name, resp.StatusCode, body)
+		}
+	}
+}
+
+func TestServeFileRange(t *testing.T) {
+	s, _ := newTestStorage(t)
+	content := []byte("0123456789abcdef")
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes(content), "Report 2024.txt", int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/download", nil)
+	r.Header.Set("Range", "bytes=4-9")
+	w := httptest.NewRecorder()
+	s.ServeFile(w, r, result)
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusPartialContent)
+	}
+	if got := w.Body.String(); got != "456789" {
+		t.Errorf("body = %q, want %q", got, "456789")
+	}
+	header := w.Header()
+	if got := header.Get("Content-Range"); got != "bytes 4-9/16" {
+		t.Errorf("Content-Range = %s", got)
+	}
+	if got := header.Get("Etag"); got != `"`+result.Hash+`"` {
+		t.Errorf("ETag = %s, want the hash", got)
+	}
+	if got := header.Get("Content-Disposition"); got != `attachment; filename="Report 2024.txt"` {
+		t.Errorf("Content-Disposition = %s", got)
+	}
+	if got := header.Get("Content-Type"); got != result.ContentType {
+		t.Errorf("Content-Type = %s, want %s", got, result.ContentType)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/download", nil)
+	r.Header.Set("If-None-Match", `"`+result.Hash+`"`)
+	w = httptest.NewRecorder()
+	s.ServeFile(w, r, result)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("conditional status = %d, want %d", w.Code, http.StatusNotModified)
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeFile(w, httptest.NewRequest(http.MethodGet, "/download", nil), &FileStorageResult{PathRlt: "missing.txt"})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("missing status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,29 @@
+package fileupload
+
+import (
+	"path"
+	"strings"
+	"unicode"
+)
+
+// WithNameSharding 按原始文件名(已清理)的前 depth 个字符逐级分片存储目录, 如 depth 为 2 时 "Report.pdf" 存储于 "r/e/" 下
+// 字母及数字以外的字符(或文件名长度不足)使用 "_" 代替
+func WithNameSharding(depth int) Opts {
+	return func(s *Storage) { s.nameShardingDepth = depth }
+}
+
+// nameShard 根据原始文件名计算分片目录
+func (s *Storage) nameShard(originName string) string {
+	if s.nameShardingDepth <= 0 {
+		return ""
+	}
+	name := []rune(strings.ToLower(strings.TrimSuffix(originName, path.Ext(originName))))
+	shards := make([]string, s.nameShardingDepth)
+	for i := range shards {
+		shards[i] = "_"
+		if i < len(name) && (unicode.IsLetter(name[i]) || unicode.IsDigit(name[i])) {
+			shards[i] = string(name[i])
+		}
+	}
+	return path.Join(shards...)
+}
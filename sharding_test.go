@@ -0,0 +1,28 @@
+package fileupload
+
+import (
+	"path"
+	"testing"
+)
+
+func TestNameSharding(t *testing.T) {
+	s, _ := newTestStorage(t, WithNameSharding(2))
+	store := func(name string, content string) *FileStorageResult {
+		t.Helper()
+		result, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte(content)), name, int64(len(content)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	a, b, c := store("Report.pdf", "a"), store("reading.txt", "b"), store("x.txt", "c")
+	if dir := path.Dir(a.PathRlt); dir != "r/e" {
+		t.Errorf("shard = %s, want r/e", dir)
+	}
+	if path.Dir(a.PathRlt) != path.Dir(b.PathRlt) {
+		t.Errorf("%s and %s do not share a shard", a.PathRlt, b.PathRlt)
+	}
+	if dir := path.Dir(c.PathRlt); dir != "x/_" {
+		t.Errorf("short name shard = %s, want x/_", dir)
+	}
+}
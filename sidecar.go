@@ -0,0 +1,95 @@
+package fileupload
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path"
+	"strings"
+)
+
+// sidecarSuffix 元数据文件后缀, 元数据文件名为 文件名+.json
+const sidecarSuffix = ".json"
+
+// WithSidecarMetadata 在存储文件旁写入记录完整存储结果(如原始文件名, 内容类型, 存储时间)的元数据文件, 见 LoadMetadata
+// 复用已存储的相同内容且元数据文件已存在时不再写入
+func WithSidecarMetadata(sidecar bool) Opts {
+	return func(s *Storage) { s.sidecarMetadata = sidecar }
+}
+
+// writeSidecar 写入存储结果的元数据文件
+func (s *Storage) writeSidecar(u *upload) error {
+	if !s.sidecarMetadata {
+		return nil
+	}
+	name := u.result.PathAbs + sidecarSuffix
+	_, ser := u.fs.Stat(name)
+	if ser == nil && !u.result.Created {
+		return nil
+	}
+	data, err := json.Marshal(u.result)
+	if err != nil {
+		return err
+	}
+	return u.writeFile(name, bytes.NewReader(data), ser == nil)
+}
+
+// LoadMetadata 根据资源访问路径读取存储时写入的元数据文件(见 WithSidecarMetadata), 返回存储时的存储结果
+func (s *Storage) LoadMetadata(uri string) (result *FileStorageResult, err error) {
+	name, err := s.AbsolutePath(&FileStorageResult{PathUri: uri})
+	if err != nil {
+		return
+	}
+	f, err := s.filesystem().Open(name + sidecarSuffix)
+	if err != nil {
+		return
+	}
+	defer func() { _ = f.Close() }()
+	result = &FileStorageResult{}
+	if err = json.NewDecoder(f).Decode(result); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// isSidecar 文件名是否为元数据文件, 上传的 .json 文件本身只有一个后缀
+func (s *Storage) isSidecar(name string) bool {
+	return s.sidecarMetadata && strings.HasSuffix(name, sidecarSuffix) && path.Ext(strings.TrimSuffix(name, sidecarSuffix)) != ""
+}
+
+// removeSidecar 删除文件的元数据文件, 元数据文件不存在时不返回错误
+func (s *Storage) removeSidecar(name string) error {
+	if err := s.filesystem().Remove(name + sidecarSuffix); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// moveSidecar 元数据文件随文件移动, 更新其中的存储路径及资源访问路径; 目标位置已存在元数据文件时保留已存在的文件
+func (s *Storage) moveSidecar(u *upload, source string, moved *FileStorageResult) (err error) {
+	f, err := u.fs.Open(source + sidecarSuffix)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	stored := &FileStorageResult{}
+	err = json.NewDecoder(f).Decode(stored)
+	_ = f.Close()
+	if err != nil {
+		return
+	}
+	name := moved.PathAbs + sidecarSuffix
+	if _, ser := u.fs.Stat(name); ser != nil {
+		stored.PathAbs, stored.PathRlt, stored.PathUri, stored.ThumbnailUri = moved.PathAbs, moved.PathRlt, moved.PathUri, moved.ThumbnailUri
+		var data []byte
+		if data, err = json.Marshal(stored); err != nil {
+			return
+		}
+		if err = u.writeFile(name, bytes.NewReader(data), false); err != nil {
+			return
+		}
+	}
+	return s.removeSidecar(source)
+}
@@ -0,0 +1,52 @@
+package fileupload
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSidecarRoundTrip(t *testing.T) {
+	s, _ := newTestStorage(t, WithSidecarMetadata(true), WithUriAccessPrefix("/static"))
+	content := pngBytes(t, 4, 4)
+	stored, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: "photos"}, openBytes(content), "Holiday Photo.png", int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(stored.PathAbs + sidecarSuffix); err != nil {
+		t.Fatalf("sidecar not written: %v", err)
+	}
+	loaded, err := s.LoadMetadata(stored.PathUri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.OriginName != "Holiday Photo.png" || loaded.ContentType != "image/png" || !loaded.CreatedAt.Equal(stored.CreatedAt) {
+		t.Errorf("loaded = %s %s %v, want %s %s %v", loaded.OriginName, loaded.ContentType, loaded.CreatedAt,
+			stored.OriginName, stored.ContentType, stored.CreatedAt)
+	}
+	if loaded.Hash != stored.Hash || loaded.Size != stored.Size || loaded.PathUri != stored.PathUri {
+		t.Errorf("loaded = %+v, want %+v", loaded, stored)
+	}
+
+	// 复用相同内容时保留已存在的元数据文件
+	again, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: "photos"}, openBytes(content), "copy.png", int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Created {
+		t.Fatal("same content was written again")
+	}
+	if loaded, err = s.LoadMetadata(stored.PathUri); err != nil || loaded.OriginName != "Holiday Photo.png" {
+		t.Errorf("sidecar after dedup = %v, %v, want the first origin name", loaded, err)
+	}
+
+	results, err := s.List("photos", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Errorf("List = %d results, want the sidecar hidden", len(results))
+	}
+	if _, err = s.LoadMetadata("/static/photos/missing.png"); !os.IsNotExist(err) {
+		t.Errorf("missing metadata err = %v, want not exist", err)
+	}
+}
@@ -0,0 +1,92 @@
+package fileupload
+
+import (
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+
+	"github.com/labstack/echo/v4"
+)
+
+// EchoStreaming 文件上传echo, 以 multipart/mixed 响应流式返回存储结果, 每个文件存储完成后立即写出一个分段(链接至其它子目录的位置各写出一个分段, 见 FileStorage.LinkSubDirectories)
+// 响应头写出前的错误(如表单解析失败)直接返回, 由调用方处理; 之后的存储错误以 {"error": "..."} 分段写出并中止后续存储, 响应总是以结束边界结尾
+// 写出的存储结果默认不含服务器路径 PathAbs 及 PathRlt, 需要返回时通过 WithResultFields 显式指定 ResultPathAbs, ResultPathRlt
+func (s *Storage) EchoStreaming(c echo.Context, param *FileStorage, name *MultipartFileName) (err error) {
+	if name == nil {
+		return
+	}
+	form, err := s.parseForm(c.Request(), param)
+	if err != nil {
+		return
+	}
+	defer s.removeForm(form)
+	files, err := formFiles(form, name)
+	if err != nil {
+		return
+	}
+	headers := make([]*multipart.FileHeader, 0, len(files))
+	for _, file := range files {
+		headers = append(headers, file.header)
+	}
+	if err = s.checkTotalSize(headers, 0); err != nil {
+		return
+	}
+
+	b := newBatch(c.Request().Context())
+	response := c.Response()
+	mw := multipart.NewWriter(response)
+	response.Header().Set(echo.HeaderContentType, "multipart/mixed; boundary="+mw.Boundary())
+	response.WriteHeader(http.StatusOK)
+
+	header := textproto.MIMEHeader{}
+	header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	// 写出分段失败(如客户端断开)时仍尝试写出结束边界
+	defer func() {
+		if cer := mw.Close(); cer != nil && err == nil {
+			err = cer
+		}
+	}()
+	for _, file := range files {
+		var result *FileStorageResult
+		var bodies []any
+		result, err = s.multipartCopy(param, file.header, b)
+		if err != nil {
+			bodies = append(bodies, map[string]string{"error": err.Error()})
+		} else if s.requestDedup == RequestDedupMerge && b.duplicated(result) {
+			continue
+		} else {
+			result.Field = file.field
+			// 链接至其它子目录的位置各写出一个分段
+			for _, v := range flattenLinks([]*FileStorageResult{result}) {
+				bodies = append(bodies, s.streamedResult(v))
+			}
+		}
+		for _, body := range bodies {
+			part, wer := mw.CreatePart(header)
+			if wer == nil {
+				wer = json.NewEncoder(part).Encode(body)
+			}
+			if err == nil {
+				err = wer
+			}
+			if err != nil {
+				return
+			}
+		}
+		response.Flush()
+	}
+	return
+}
+
+// streamedResult 写出至客户端的存储结果, 未通过 WithResultFields 显式指定时不含 PathAbs 及 PathRlt
+func (s *Storage) streamedResult(result *FileStorageResult) *FileStorageResult {
+	streamed := *result
+	if s.resultFields&ResultPathAbs == 0 {
+		streamed.PathAbs = ""
+	}
+	if s.resultFields&ResultPathRlt == 0 {
+		streamed.PathRlt = ""
+	}
+	return &streamed
+}
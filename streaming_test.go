@@ -0,0 +1,81 @@
+package fileupload
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// streamedParts 读取 multipart/mixed 响应中的全部分段
+func streamedParts(t *testing.T, rec *httptest.ResponseRecorder) []map[string]any {
+	t.Helper()
+	mediaType, params, err := mime.ParseMediaType(rec.Header().Get(echo.HeaderContentType))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("content type = %q, %v", rec.Header().Get(echo.HeaderContentType), err)
+	}
+	var parts []map[string]any
+	mr := multipart.NewReader(rec.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return parts
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body := map[string]any{}
+		if err = json.NewDecoder(part).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, body)
+	}
+}
+
+func TestEchoStreaming(t *testing.T) {
+	s, _ := newTestStorage(t)
+	r := multipartRequest(t, []testFile{
+		{field: "files", filename: "a.txt", content: "a"},
+		{field: "files", filename: "b.txt", content: "b"},
+		{field: "files", filename: "c.txt", content: "c"},
+	})
+	rec := httptest.NewRecorder()
+	if err := s.EchoStreaming(echo.New().NewContext(r, rec), &FileStorage{}, &MultipartFileName{Multiple: "files"}); err != nil {
+		t.Fatal(err)
+	}
+	parts := streamedParts(t, rec)
+	if len(parts) != 3 {
+		t.Fatalf("got %d parts, want 3", len(parts))
+	}
+	for i, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if parts[i]["origin_name"] != name || parts[i]["field"] != "files" {
+			t.Errorf("part %d = %v, want %s", i, parts[i], name)
+		}
+		if _, ok := parts[i]["path_abs"]; ok {
+			t.Errorf("part %d exposes the server path: %v", i, parts[i])
+		}
+	}
+}
+
+func TestEchoStreamingError(t *testing.T) {
+	s, _ := newTestStorage(t, WithMaxFileSize(1))
+	r := multipartRequest(t, []testFile{
+		{field: "files", filename: "a.txt", content: "a"},
+		{field: "files", filename: "b.txt", content: "too large"},
+		{field: "files", filename: "c.txt", content: "c"},
+	})
+	rec := httptest.NewRecorder()
+	err := s.EchoStreaming(echo.New().NewContext(r, rec), &FileStorage{}, &MultipartFileName{Multiple: "files"})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	parts := streamedParts(t, rec)
+	if len(parts) != 2 || parts[0]["origin_name"] != "a.txt" || parts[1]["error"] == nil {
+		t.Errorf("parts = %v, want one result followed by one error", parts)
+	}
+}
@@ -0,0 +1,61 @@
+package fileupload
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// WithSync 写入的文件在关闭前调用 Sync 刷新至存储介质, 重命名至目标位置后调用文件系统的 Commit(见 Committer), 以吞吐量换取持久性(如断电后不丢失已返回成功的文件)
+// 文件系统(见 WithFileSystem)打开的文件需实现 Sync() error, 否则存储失败
+func WithSync(sync bool) Opts {
+	return func(s *Storage) { s.sync = sync }
+}
+
+// Committer 文件系统可选实现的持久化确认, 开启 WithSync 时在文件重命名至目标位置 name 后调用, 返回错误时存储失败
+// 本地磁盘的实现同步文件所在目录, 使重命名本身持久化; 网络或对象存储可在此确认写入已持久化
+type Committer interface {
+	Commit(name string) error
+}
+
+func (osFileSystem) Commit(name string) error {
+	// Windows 不支持同步目录
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	dir, err := os.Open(filepath.Dir(name))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = dir.Close() }()
+	return dir.Sync()
+}
+
+// commitFile 开启 WithSync 时确认重命名至 name 的文件已持久化, 文件系统未实现 Committer 时不做处理
+func (u *upload) commitFile(name string) error {
+	if !u.sync {
+		return nil
+	}
+	if c, ok := u.fs.(Committer); ok {
+		return c.Commit(name)
+	}
+	return nil
+}
+
+// syncer 支持刷新至存储介质的文件
+type syncer interface {
+	Sync() error
+}
+
+// syncFile 开启 WithSync 时将文件刷新至存储介质
+func (u *upload) syncFile(f File) error {
+	if !u.sync {
+		return nil
+	}
+	s, ok := f.(syncer)
+	if !ok {
+		return fmt.Errorf("sync: file %s does not support sync", f.Name())
+	}
+	return s.Sync()
+}
@@ -0,0 +1,67 @@
+package fileupload
+
+import (
+	"os"
+	"testing"
+)
+
+// syncRecordingFS 记录文件 Sync 及 Commit 调用的文件系统
+type syncRecordingFS struct {
+	FileSystem
+	synced    []string
+	committed []string
+}
+
+func (fs *syncRecordingFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	f, err := fs.FileSystem.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &syncRecordingFile{File: f, fs: fs}, nil
+}
+
+func (fs *syncRecordingFS) Commit(name string) error {
+	fs.committed = append(fs.committed, name)
+	return nil
+}
+
+type syncRecordingFile struct {
+	File
+	fs *syncRecordingFS
+}
+
+func (f *syncRecordingFile) Sync() error {
+	f.fs.synced = append(f.fs.synced, f.Name())
+	return nil
+}
+
+func TestSync(t *testing.T) {
+	fs := &syncRecordingFS{FileSystem: osFileSystem{}}
+	s, _ := newTestStorage(t, WithSync(true), WithFileSystem(fs))
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("ledger")), "ledger.csv", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fs.synced) != 1 {
+		t.Errorf("synced = %v, want the written file", fs.synced)
+	}
+	if len(fs.committed) != 1 || fs.committed[0] != result.PathAbs {
+		t.Errorf("committed = %v, want %s", fs.committed, result.PathAbs)
+	}
+
+	fs = &syncRecordingFS{FileSystem: osFileSystem{}}
+	s, _ = newTestStorage(t, WithFileSystem(fs))
+	if _, err = s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("ledger")), "ledger.csv", 6); err != nil {
+		t.Fatal(err)
+	}
+	if len(fs.synced) != 0 || len(fs.committed) != 0 {
+		t.Errorf("synced %v, committed %v without WithSync", fs.synced, fs.committed)
+	}
+}
+
+func TestSyncUnsupported(t *testing.T) {
+	s := NewStorage(WithStorageDirectory("/uploads"), WithSync(true), WithFileSystem(newMemFS()))
+	if _, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("x")), "a.txt", 1); err == nil {
+		t.Error("stored with sync on files that cannot sync")
+	}
+}
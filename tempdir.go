@@ -0,0 +1,7 @@
+package fileupload
+
+// WithTempDirectory 上传内容先写入临时目录(如本地高速磁盘), 完成后再移动至存储目录
+// 临时目录与存储目录不在同一设备时以复制代替重命名; 未设置时临时文件写入存储目录
+func WithTempDirectory(directory string) Opts {
+	return func(s *Storage) { s.tempDirectory = directory }
+}
@@ -0,0 +1,58 @@
+package fileupload
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// crossDeviceFS 模拟临时目录位于另一设备, 无法从 temp 重命名至其它目录
+type crossDeviceFS struct {
+	osFileSystem
+	temp string
+}
+
+func (fs crossDeviceFS) Rename(oldPath string, newPath string) error {
+	if strings.HasPrefix(oldPath, fs.temp) && !strings.HasPrefix(newPath, fs.temp) {
+		return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: syscall.EXDEV}
+	}
+	return fs.osFileSystem.Rename(oldPath, newPath)
+}
+
+func TestTempDirectoryCrossDevice(t *testing.T) {
+	temp := t.TempDir()
+	s, dir := newTestStorage(t, WithTempDirectory(temp), WithFileSystem(crossDeviceFS{temp: temp}))
+	result, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: "sub"}, openBytes([]byte("moved across devices")), "a.txt", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, result.PathAbs); got != "moved across devices" {
+		t.Errorf("final content = %q", got)
+	}
+	if !strings.HasPrefix(result.PathAbs, dir) {
+		t.Errorf("PathAbs = %s, want under %s", result.PathAbs, dir)
+	}
+	if names := listTree(t, temp); len(names) != 0 {
+		t.Errorf("temp directory = %v, want empty", names)
+	}
+	if names := listTree(t, filepath.Join(dir, "sub")); len(names) != 1 {
+		t.Errorf("storage directory = %v, want only the stored file", names)
+	}
+}
+
+func TestTempDirectorySameDevice(t *testing.T) {
+	temp := t.TempDir()
+	s, _ := newTestStorage(t, WithTempDirectory(temp))
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("renamed")), "a.txt", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, result.PathAbs); got != "renamed" {
+		t.Errorf("final content = %q", got)
+	}
+	if names := listTree(t, temp); len(names) != 0 {
+		t.Errorf("temp directory = %v, want empty", names)
+	}
+}
@@ -0,0 +1,111 @@
+package fileupload
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// subDirectoryTokens 子目录模板支持的变量
+var subDirectoryTokens = map[string]struct{}{"category": {}, "ext": {}, "yyyy": {}, "mm": {}, "dd": {}}
+
+// tokenValue 子目录模板变量的值
+func tokenValue(token string, result *FileStorageResult, now time.Time) string {
+	switch token {
+	case "category":
+		return pathSegment(result.Category)
+	case "ext":
+		return pathSegment(strings.TrimPrefix(result.FileExt, "."))
+	case "yyyy":
+		return now.Format("2006")
+	case "mm":
+		return now.Format("01")
+	case "dd":
+		return now.Format("02")
+	}
+	return ""
+}
+
+// subDirectoryTemplate 已解析的子目录模板, 由文本及变量交替组成
+type subDirectoryTemplate struct {
+	literals []string // 文本, 比变量多一个
+	tokens   []string // 变量名
+}
+
+// WithSubDirectoryTemplate 按模板为每个文件计算存储子目录(位于 FileStorage.StorageSubDirectory 之下), 如 "{category}/{yyyy}/{mm}"
+// 支持的变量: {category} 资源分类, {ext} 文件后缀(不含.), {yyyy} {mm} {dd} 存储日期(见 WithTimeLocation)
+// 模板包含未知变量时 Validate 及每次存储均返回错误
+func WithSubDirectoryTemplate(template string) Opts {
+	return func(s *Storage) {
+		s.subDirectoryTemplate, s.subDirectoryTemplateErr = parseSubDirectoryTemplate(template)
+	}
+}
+
+// parseSubDirectoryTemplate 解析子目录模板
+func parseSubDirectoryTemplate(template string) (*subDirectoryTemplate, error) {
+	t := &subDirectoryTemplate{}
+	rest := template
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("sub directory template %q: unclosed token", template)
+		}
+		token := rest[start+1 : start+end]
+		if _, ok := subDirectoryTokens[token]; !ok {
+			return nil, fmt.Errorf("sub directory template %q: unknown token {%s}", template, token)
+		}
+		t.literals = append(t.literals, rest[:start])
+		t.tokens = append(t.tokens, token)
+		rest = rest[start+end+1:]
+	}
+	if strings.IndexByte(rest, '}') >= 0 {
+		return nil, fmt.Errorf("sub directory template %q: unexpected }", template)
+	}
+	t.literals = append(t.literals, rest)
+	return t, nil
+}
+
+// expand 计算文件的存储子目录
+func (t *subDirectoryTemplate) expand(result *FileStorageResult, now time.Time) string {
+	b := strings.Builder{}
+	for i, token := range t.tokens {
+		b.WriteString(t.literals[i])
+		b.WriteString(tokenValue(token, result, now))
+	}
+	b.WriteString(t.literals[len(t.literals)-1])
+	return path.Clean("/" + b.String())[1:]
+}
+
+// pathSegment 将变量值转换为安全的目录名, 字母, 数字, - 及 _ 以外的字符使用 _ 代替
+func pathSegment(value string) string {
+	if value == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			return unicode.ToLower(r)
+		}
+		return '_'
+	}, value)
+}
+
+// templateSubDirectory 按子目录模板计算文件的存储子目录, 未配置模板时返回空
+func (s *Storage) templateSubDirectory(result *FileStorageResult) (string, error) {
+	if s.subDirectoryTemplateErr != nil {
+		return "", s.subDirectoryTemplateErr
+	}
+	if s.subDirectoryTemplate == nil {
+		return "", nil
+	}
+	now := result.CreatedAt
+	if now.IsZero() {
+		now = s.now()
+	}
+	return s.subDirectoryTemplate.expand(result, now), nil
+}
@@ -0,0 +1,46 @@
+package fileupload
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSubDirectoryTemplate(t *testing.T) {
+	s, dir := newTestStorage(t, WithSubDirectoryTemplate("{category}/{yyyy}/{mm}"), WithTimeLocation(time.UTC))
+	image := pngBytes(t, 2, 2)
+	photo, err := s.CopyMultipartFile(&FileStorage{}, openBytes(image), "photo.png", int64(len(image)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdf := []byte("%PDF-1.4\n%%EOF\n")
+	doc, err := s.CopyMultipartFile(&FileStorage{}, openBytes(pdf), "doc.pdf", int64(len(pdf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := time.Now().UTC().Format("2006/01")
+	for _, tc := range []struct {
+		result   *FileStorageResult
+		category string
+	}{{photo, CategoryImage}, {doc, CategoryDocument}} {
+		want := path.Join(tc.category, date)
+		if got := path.Dir(tc.result.PathRlt); got != want {
+			t.Errorf("%s stored in %s, want %s", tc.result.OriginName, got, want)
+		}
+		if !strings.HasPrefix(tc.result.PathAbs, filepath.Join(dir, tc.category)+string(filepath.Separator)) {
+			t.Errorf("%s PathAbs = %s, want below %s", tc.result.OriginName, tc.result.PathAbs, tc.category)
+		}
+	}
+}
+
+func TestSubDirectoryTemplateUnknownToken(t *testing.T) {
+	s, _ := newTestStorage(t, WithSubDirectoryTemplate("{category}/{week}"))
+	if err := s.Validate(); err == nil || !strings.Contains(err.Error(), "{week}") {
+		t.Errorf("Validate err = %v, want an unknown token error", err)
+	}
+	if _, err := s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("x")), "a.txt", 1); err == nil {
+		t.Error("store succeeded with an invalid template")
+	}
+}
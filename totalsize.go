@@ -0,0 +1,29 @@
+package fileupload
+
+import (
+	"fmt"
+	"mime/multipart"
+)
+
+// WithMaxTotalSize 单次请求(或单次 MultipartCopy 调用)内全部上传文件的总大小上限, 小于等于0时不限制
+// 在写入任何文件之前检查, 超出时返回 ErrTotalSizeExceeded
+func WithMaxTotalSize(bytes int64) Opts {
+	return func(s *Storage) { s.maxTotalSize = bytes }
+}
+
+// checkTotalSize 检查上传文件(及 extra 字节的其它内容)的总大小
+func (s *Storage) checkTotalSize(files []*multipart.FileHeader, extra int64) error {
+	if s.maxTotalSize <= 0 {
+		return nil
+	}
+	total := extra
+	for _, file := range files {
+		if file != nil {
+			total += file.Size
+		}
+	}
+	if total > s.maxTotalSize {
+		return fmt.Errorf("%w: %d bytes, limit %d bytes", ErrTotalSizeExceeded, total, s.maxTotalSize)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package fileupload
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMaxTotalSize(t *testing.T) {
+	s, dir := newTestStorage(t, WithMaxFileSize(10), WithMaxTotalSize(25))
+	files := []testFile{
+		{field: "files", filename: "a.txt", content: strings.Repeat("a", 10)},
+		{field: "files", filename: "b.txt", content: strings.Repeat("b", 10)},
+		{field: "files", filename: "c.txt", content: strings.Repeat("c", 10)},
+	}
+	results, err := s.MultipartCopy(&FileStorage{}, formFileHeaders(t, files...)...)
+	if !errors.Is(err, ErrTotalSizeExceeded) || len(results) != 0 {
+		t.Fatalf("MultipartCopy = %v, %v, want ErrTotalSizeExceeded", results, err)
+	}
+	results, err = s.HTTP(multipartRequest(t, files), &FileStorage{}, &MultipartFileName{Multiple: "files"})
+	if !errors.Is(err, ErrTotalSizeExceeded) || len(results) != 0 {
+		t.Fatalf("HTTP = %v, %v, want ErrTotalSizeExceeded", results, err)
+	}
+	if names := listTree(t, dir); len(names) != 0 {
+		t.Errorf("storage tree = %v, want nothing written", names)
+	}
+	if _, err = s.MultipartCopy(&FileStorage{}, formFileHeaders(t, files[:2]...)...); err != nil {
+		t.Errorf("files under the total limit: %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package fileupload
+
+// WithUriBuilder 自定义存储结果的资源访问路径(如带签名及过期时间的CDN地址), 存储成功后以 fn 的返回值替换 PathUri(及 Links 的 PathUri)
+// fn 调用时 PathUri 为按资源访问前缀拼接的访问路径; 内容索引, 元数据文件及缩略图访问路径仍使用该路径; 返回错误时存储失败
+// Move 及 List 返回的存储结果同样使用 fn 生成 PathUri
+func WithUriBuilder(fn func(result *FileStorageResult) (string, error)) Opts {
+	return func(s *Storage) { s.uriBuilder = fn }
+}
+
+// buildURI 使用自定义函数生成资源访问路径(含 Links)
+func (s *Storage) buildURI(result *FileStorageResult) (err error) {
+	if s.uriBuilder == nil {
+		return
+	}
+	for _, v := range result.Links {
+		if err = s.buildURI(v); err != nil {
+			return
+		}
+	}
+	return s.buildPathURI(result)
+}
+
+// buildPathURI 使用自定义函数生成资源访问路径, 不处理 Links(如 Move 复制的已生成访问路径的 Links)
+func (s *Storage) buildPathURI(result *FileStorageResult) (err error) {
+	if s.uriBuilder == nil {
+		return
+	}
+	uri, err := s.uriBuilder(result)
+	if err != nil {
+		return
+	}
+	result.PathUri = uri
+	return
+}
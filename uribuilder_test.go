@@ -0,0 +1,78 @@
+package fileupload
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestUriBuilder(t *testing.T) {
+	key := []byte("secret")
+	sign := func(result *FileStorageResult) (string, error) {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(result.PathUri + "1700000000"))
+		return "https://cdn.example.com" + result.PathUri + "?expires=1700000000&sig=" + hex.EncodeToString(mac.Sum(nil)), nil
+	}
+	s, _ := newTestStorage(t, WithUriBuilder(sign), WithUriAccessPrefix("/private"))
+	result, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: "docs"}, openBytes([]byte("x")), "a.pdf", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := sign(&FileStorageResult{PathUri: "/private/docs/" + result.Name})
+	if result.PathUri != want {
+		t.Errorf("PathUri = %s, want %s", result.PathUri, want)
+	}
+	if !strings.HasSuffix(result.PathRlt, "docs/"+result.Name) {
+		t.Errorf("PathRlt = %s changed by the builder", result.PathRlt)
+	}
+
+	errSign := errors.New("signing failed")
+	s, _ = newTestStorage(t, WithUriBuilder(func(*FileStorageResult) (string, error) { return "", errSign }))
+	if _, err = s.CopyMultipartFile(&FileStorage{}, openBytes([]byte("x")), "a.pdf", 1); !errors.Is(err, errSign) {
+		t.Errorf("err = %v, want the builder error", err)
+	}
+}
+
+func TestUriBuilderMoveAndList(t *testing.T) {
+	sign := func(result *FileStorageResult) (string, error) {
+		if strings.HasPrefix(result.PathUri, "https://") {
+			return "", errors.New("path uri built twice")
+		}
+		return "https://cdn.example.com" + result.PathUri + "?sig=1", nil
+	}
+	s, _ := newTestStorage(t, WithUriBuilder(sign), WithUriAccessPrefix("/private"), WithLinkMode(LinkHard))
+	draft, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: "drafts", LinkSubDirectories: []string{"shared"}}, openBytes([]byte("asset")), "a.png", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(draft.Links) != 1 {
+		t.Fatalf("got %d links, want 1", len(draft.Links))
+	}
+	moved, err := s.Move(draft, "published")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://cdn.example.com/private/published/" + draft.Name + "?sig=1"; moved.PathUri != want {
+		t.Errorf("moved PathUri = %s, want %s", moved.PathUri, want)
+	}
+	if moved.Links[0].PathUri != draft.Links[0].PathUri {
+		t.Errorf("link PathUri = %s, want %s unchanged", moved.Links[0].PathUri, draft.Links[0].PathUri)
+	}
+
+	results, err := s.List("published", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].PathUri != moved.PathUri {
+		t.Errorf("listed = %+v, want PathUri %s", results, moved.PathUri)
+	}
+
+	errSign := errors.New("signing failed")
+	s.uriBuilder = func(*FileStorageResult) (string, error) { return "", errSign }
+	if _, err = s.List("published", nil); !errors.Is(err, errSign) {
+		t.Errorf("list err = %v, want the builder error", err)
+	}
+}
@@ -0,0 +1,73 @@
+package fileupload
+
+import (
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// defaultUsageCacheTTL 默认子目录用量统计结果的缓存时间
+const defaultUsageCacheTTL = 10 * time.Second
+
+// WithUsageCacheTTL Usage 统计结果的缓存时间, 默认10秒; 小于0时不缓存
+func WithUsageCacheTTL(ttl time.Duration) Opts {
+	return func(s *Storage) { s.usageCacheTTL = ttl }
+}
+
+// usageCache 子目录用量统计结果的缓存, 以子目录绝对路径为键
+type usageCache struct {
+	mutex   sync.Mutex
+	entries map[string]usageEntry
+}
+
+// usageEntry 子目录用量统计结果
+type usageEntry struct {
+	bytes int64
+	count int
+	at    time.Time
+}
+
+// Usage 存储目录下子目录 subDirectory 中已存储文件的总字节数及文件数(含缩略图等衍生文件), 子目录不存在时均为0
+// 统计结果按 WithUsageCacheTTL 缓存; 配置 WithQuota 且该子目录已有配额统计时, 字节数使用配额的增量统计值
+func (s *Storage) Usage(subDirectory string) (bytes int64, count int, err error) {
+	// 子目录不能超出存储目录
+	subDirectory = path.Clean("/" + filepath.ToSlash(subDirectory))[1:]
+	directory, err := s.quotaDirectory(&FileStorage{}, subDirectory)
+	if err != nil {
+		return
+	}
+	ttl := s.usageCacheTTL
+	if ttl == 0 {
+		ttl = defaultUsageCacheTTL
+	}
+
+	cache := &s.usageCache
+	cache.mutex.Lock()
+	entry, ok := cache.entries[directory]
+	cache.mutex.Unlock()
+	if !ok || ttl < 0 || s.now().Sub(entry.at) >= ttl {
+		if entry.bytes, entry.count, err = s.directoryUsage(directory); err != nil {
+			return
+		}
+		entry.at = s.now()
+		if ttl > 0 {
+			cache.mutex.Lock()
+			if cache.entries == nil {
+				cache.entries = make(map[string]usageEntry)
+			}
+			cache.entries[directory] = entry
+			cache.mutex.Unlock()
+		}
+	}
+	bytes, count = entry.bytes, entry.count
+
+	if s.quota != nil {
+		s.usage.mutex.Lock()
+		if used, ok := s.usage.used[directory]; ok {
+			bytes = used
+		}
+		s.usage.mutex.Unlock()
+	}
+	return
+}
@@ -0,0 +1,55 @@
+package fileupload
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUsage(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	s, dir := newTestStorage(t, WithClock(func() time.Time { return now }))
+	for _, f := range []struct{ sub, content string }{
+		{"tenant-a", "abc"},
+		{"tenant-a/2024", "hello"},
+		{"tenant-b", "1234567"},
+	} {
+		if _, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: f.sub}, openBytes([]byte(f.content)), "a.txt", int64(len(f.content))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(sub string, wantBytes int64, wantCount int) {
+		t.Helper()
+		bytes, count, err := s.Usage(sub)
+		if err != nil || bytes != wantBytes || count != wantCount {
+			t.Errorf("Usage(%q) = %d bytes, %d files, %v, want %d bytes, %d files", sub, bytes, count, err, wantBytes, wantCount)
+		}
+	}
+	check("tenant-a", 8, 2)
+	check("tenant-b", 7, 1)
+	check("", 15, 3)
+	check("missing", 0, 0)
+	// 不能超出存储目录
+	check("../../tenant-b", 7, 1)
+
+	// 缓存时间内返回缓存的结果
+	if err := os.WriteFile(filepath.Join(dir, "tenant-b", "extra.txt"), []byte("xyz"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	check("tenant-b", 7, 1)
+	now = now.Add(defaultUsageCacheTTL)
+	check("tenant-b", 10, 2)
+}
+
+func TestUsageQuotaCounter(t *testing.T) {
+	s, _ := newTestStorage(t, WithUsageCacheTTL(-1), WithQuota(func(string) int64 { return 1 << 20 }))
+	for _, content := range []string{"abc", "hello"} {
+		if _, err := s.CopyMultipartFile(&FileStorage{StorageSubDirectory: "tenant"}, openBytes([]byte(content)), "a.txt", int64(len(content))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if bytes, count, err := s.Usage("tenant"); err != nil || bytes != 8 || count != 2 {
+		t.Errorf("Usage = %d bytes, %d files, %v, want 8 bytes, 2 files", bytes, count, err)
+	}
+}
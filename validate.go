@@ -0,0 +1,60 @@
+package fileupload
+
+import (
+	"fmt"
+	"os"
+)
+
+// Validate 检查配置(如子目录模板)是否有效, 存储目录(及已配置的临时目录, 分片暂存目录)是否存在或可以创建, 并写入探测文件确认可写
+// 建议在服务启动时调用, 以尽早发现配置错误
+func (s *Storage) Validate() error {
+	if s.subDirectoryTemplateErr != nil {
+		return s.subDirectoryTemplateErr
+	}
+	fs := s.filesystem()
+	storageDirectory := s.storageDirectory
+	if storageDirectory == "" {
+		storageDirectory = "."
+	}
+	if err := validateDirectory(fs, storageDirectory); err != nil {
+		return fmt.Errorf("storage directory: %w", err)
+	}
+	if s.tempDirectory != "" {
+		if err := validateDirectory(fs, s.tempDirectory); err != nil {
+			return fmt.Errorf("temp directory: %w", err)
+		}
+	}
+	// 分片暂存于本地目录, 不经过存储的文件系统
+	if s.chunkDirectory != "" {
+		if err := validateDirectory(osFileSystem{}, s.chunkDirectory); err != nil {
+			return fmt.Errorf("chunk directory: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateDirectory 创建目录并写入探测文件, 完成后删除探测文件
+func validateDirectory(fs FileSystem, directory string) error {
+	if err := fs.MkdirAll(directory, 0755); err != nil {
+		return err
+	}
+	stat, err := fs.Stat(directory)
+	if err != nil {
+		return err
+	}
+	if !stat.IsDir() {
+		return fmt.Errorf("%s is not a directory", directory)
+	}
+	probe, err := createTemp(fs, directory, ".probe.", tempFileSuffix, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = probe.Write([]byte("probe"))
+	if cer := probe.Close(); err == nil {
+		err = cer
+	}
+	if rer := fs.Remove(probe.Name()); err == nil && rer != nil && !os.IsNotExist(rer) {
+		err = rer
+	}
+	return err
+}
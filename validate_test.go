@@ -0,0 +1,57 @@
+package fileupload
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// readOnlyFS 拒绝全部写操作的文件系统, 模拟只读挂载
+type readOnlyFS struct {
+	FileSystem
+}
+
+func (fs readOnlyFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		return nil, &os.PathError{Op: "open", Path: name, Err: syscall.EROFS}
+	}
+	return fs.FileSystem.OpenFile(name, flag, perm)
+}
+
+func TestValidateReadOnlyDirectory(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStorage(WithStorageDirectory(dir), WithFileSystem(readOnlyFS{FileSystem: osFileSystem{}}))
+	if err := s.Validate(); err == nil {
+		t.Fatal("Validate succeeded on a read-only directory")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Validate left %d entries behind", len(entries))
+	}
+}
+
+func TestValidateMissingParent(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "missing", "parent", "uploads")
+	if err := NewStorage(WithStorageDirectory(dir)).Validate(); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("storage directory was not created: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("probe file was not removed: %v", entries)
+	}
+
+	file := filepath.Join(t.TempDir(), "file")
+	if err = os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = NewStorage(WithStorageDirectory(filepath.Join(file, "uploads"))).Validate(); err == nil {
+		t.Error("Validate succeeded below a regular file")
+	}
+}
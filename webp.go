@@ -0,0 +1,8 @@
+//go:build webp
+
+package fileupload
+
+// 使用构建标签 webp 编译时注册 WebP 解码器, 用于图片宽高解析, 缩略图生成及感知哈希(见 WithImageProcessing)
+import (
+	_ "golang.org/x/image/webp"
+)
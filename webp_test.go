@@ -0,0 +1,26 @@
+//go:build webp
+
+package fileupload
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestWebPDimensions(t *testing.T) {
+	webp, err := base64.StdEncoding.DecodeString(minimalWebP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _ := newTestStorage(t, WithImageProcessing(8, 8))
+	result, err := s.CopyMultipartFile(&FileStorage{}, openBytes(webp), "pixel.webp", int64(len(webp)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Width != 1 || result.Height != 1 {
+		t.Errorf("dimensions = %dx%d, want 1x1", result.Width, result.Height)
+	}
+	if result.ThumbnailUri == "" {
+		t.Error("no thumbnail for a decodable webp")
+	}
+}
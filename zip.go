@@ -0,0 +1,168 @@
+package fileupload
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"path"
+	"strings"
+)
+
+// 解压zip文件的默认限制
+const (
+	defaultZipMaxEntries = 1000
+	defaultZipMaxSize    = 1 << 30
+)
+
+// WithZipLimits ExtractZip 的文件数及解压后总大小上限(防止zip炸弹), 小于等于0时使用默认值(1000个文件, 1GiB)
+func WithZipLimits(maxEntries int, maxTotalSize int64) Opts {
+	return func(s *Storage) {
+		s.zipMaxEntries = maxEntries
+		s.zipMaxSize = maxTotalSize
+	}
+}
+
+// ExtractZip 解压上传的zip文件, 其中每个文件(跳过目录)分别按常规流程存储, 配置 WithConcurrency 时并发处理且结果保持原有顺序
+// 通过 OpenFormFile 读取上传文件, 支持 WithFormTempDirectory 解析的表单
+// 存储前检查全部条目: 包含路径穿越(..)或绝对路径的条目返回 ErrUnsafeZipEntry, 超出 WithZipLimits 时返回 ErrZipLimitExceeded, 此时不存储任何文件
+func (s *Storage) ExtractZip(param *FileStorage, file *multipart.FileHeader) (succeeded []*FileStorageResult, err error) {
+	src, err := s.OpenFormFile(file)
+	if err != nil {
+		return
+	}
+	defer func() { _ = src.Close() }()
+	zr, err := zip.NewReader(src, file.Size)
+	if err != nil {
+		return
+	}
+	entries, err := s.zipEntries(zr)
+	if err != nil {
+		return
+	}
+	defer func() { succeeded = flattenLinks(succeeded) }()
+	b := s.pendingBatch()
+	defer func() { succeeded = s.rollback(b, succeeded, err) }()
+
+	length := len(entries)
+	results := make([]*FileStorageResult, length)
+	err = s.parallel(length, func(i int) (err error) {
+		result, err := s.zipEntryCopy(param, entries[i], b)
+		if err == nil {
+			results[i] = result
+		}
+		return
+	})
+	succeeded = make([]*FileStorageResult, 0, length)
+	for i := 0; i < length; i++ {
+		if results[i] != nil {
+			succeeded = append(succeeded, results[i])
+		}
+	}
+	return
+}
+
+// zipEntries 检查并返回需要存储的zip条目
+func (s *Storage) zipEntries(zr *zip.Reader) (entries []*zip.File, err error) {
+	maxEntries, maxSize := s.zipMaxEntries, s.zipMaxSize
+	if maxEntries <= 0 {
+		maxEntries = defaultZipMaxEntries
+	}
+	if maxSize <= 0 {
+		maxSize = defaultZipMaxSize
+	}
+	total := uint64(0)
+	for _, f := range zr.File {
+		name := strings.ReplaceAll(f.Name, `\`, "/")
+		// 绝对路径或Windows盘符路径(如 C:/)
+		if path.IsAbs(name) || (len(name) >= 2 && name[1] == ':') {
+			return nil, fmt.Errorf("%w: %s", ErrUnsafeZipEntry, f.Name)
+		}
+		for _, segment := range strings.Split(name, "/") {
+			if segment == ".." {
+				return nil, fmt.Errorf("%w: %s", ErrUnsafeZipEntry, f.Name)
+			}
+		}
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		entries = append(entries, f)
+		total += f.UncompressedSize64
+		if len(entries) > maxEntries {
+			return nil, fmt.Errorf("%w: more than %d files", ErrZipLimitExceeded, maxEntries)
+		}
+		if total > uint64(maxSize) {
+			return nil, fmt.Errorf("%w: more than %d bytes", ErrZipLimitExceeded, maxSize)
+		}
+	}
+	return
+}
+
+// zipEntryCopy 存储zip中的一个文件, b 为本次解压的存储状态
+func (s *Storage) zipEntryCopy(param *FileStorage, f *zip.File, b *batch) (result *FileStorageResult, err error) {
+	result = &FileStorageResult{
+		OriginName:    s.sanitizeFilename(f.Name),
+		RawOriginName: f.Name,
+	}
+	s.started(param)
+	result.FileExt = path.Ext(result.OriginName)
+	src := &zipEntrySource{file: f}
+	defer func() { _ = src.Close() }()
+	err = s.store(&upload{
+		param:  param,
+		result: result,
+		src:    src,
+		batch:  b,
+	})
+	return
+}
+
+// zipEntrySource 流式解压的zip条目, 仅支持回到起始位置(重新打开条目)
+// 解压后的大小超出条目声明的大小时返回 ErrZipLimitExceeded
+type zipEntrySource struct {
+	file *zip.File
+	rc   io.ReadCloser
+	r    io.Reader
+}
+
+func (z *zipEntrySource) Read(p []byte) (int, error) {
+	if z.rc == nil {
+		rc, err := z.file.Open()
+		if err != nil {
+			return 0, err
+		}
+		z.rc = rc
+		z.r = &zipLimitReader{r: rc, remain: int64(z.file.UncompressedSize64)}
+	}
+	return z.r.Read(p)
+}
+
+func (z *zipEntrySource) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekStart {
+		return 0, fmt.Errorf("zip entry source: only seeking to the start is supported")
+	}
+	return 0, z.Close()
+}
+
+func (z *zipEntrySource) Close() (err error) {
+	if z.rc != nil {
+		err = z.rc.Close()
+		z.rc, z.r = nil, nil
+	}
+	return
+}
+
+// zipLimitReader 限制读取的字节数不超过条目声明的解压后大小
+type zipLimitReader struct {
+	r      io.Reader
+	remain int64
+}
+
+func (l *zipLimitReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.remain -= int64(n)
+	if l.remain < 0 {
+		return n, fmt.Errorf("%w: entry larger than declared", ErrZipLimitExceeded)
+	}
+	return n, err
+}
@@ -0,0 +1,109 @@
+package fileupload
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// zipBytes 生成包含 entries 的zip文件, 以 / 结尾的名称为目录
+func zipBytes(t *testing.T, entries ...testFile) string {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for _, entry := range entries {
+		w, err := zw.Create(entry.filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = w.Write([]byte(entry.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestExtractZip(t *testing.T) {
+	s, dir := newTestStorage(t)
+	archive := zipBytes(t,
+		testFile{filename: "docs/"},
+		testFile{filename: "docs/a.txt", content: "first document"},
+		testFile{filename: "b.md", content: "# second"},
+	)
+	files := formFileHeaders(t, testFile{field: "archive", filename: "bundle.zip", content: archive})
+	results, err := s.ExtractZip(&FileStorage{}, files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2 (directories skipped)", len(results))
+	}
+	if results[0].OriginName != "a.txt" || results[1].OriginName != "b.md" {
+		t.Errorf("origin names = %s, %s", results[0].OriginName, results[1].OriginName)
+	}
+	if readFile(t, results[0].PathAbs) != "first document" || readFile(t, results[1].PathAbs) != "# second" {
+		t.Error("extracted content differs")
+	}
+
+	malicious := zipBytes(t,
+		testFile{filename: "ok.txt", content: "fine"},
+		testFile{filename: "../escape.txt", content: "zip slip"},
+	)
+	before := listTree(t, dir)
+	files = formFileHeaders(t, testFile{field: "archive", filename: "evil.zip", content: malicious})
+	if _, err = s.ExtractZip(&FileStorage{}, files[0]); !errors.Is(err, ErrUnsafeZipEntry) {
+		t.Fatalf("err = %v, want ErrUnsafeZipEntry", err)
+	}
+	if after := listTree(t, dir); len(after) != len(before) {
+		t.Errorf("unsafe zip stored files: %v", after)
+	}
+}
+
+func TestExtractZipLimits(t *testing.T) {
+	archive := zipBytes(t,
+		testFile{filename: "a.txt", content: "aaaa"},
+		testFile{filename: "b.txt", content: "bbbb"},
+	)
+	files := formFileHeaders(t, testFile{field: "archive", filename: "bundle.zip", content: archive})
+	for name, opt := range map[string]Opts{
+		"entries": WithZipLimits(1, 0),
+		"size":    WithZipLimits(0, 6),
+	} {
+		s, _ := newTestStorage(t, opt)
+		if _, err := s.ExtractZip(&FileStorage{}, files[0]); !errors.Is(err, ErrZipLimitExceeded) {
+			t.Errorf("%s: err = %v, want ErrZipLimitExceeded", name, err)
+		}
+	}
+}
+
+func TestExtractZipFormTempDirectory(t *testing.T) {
+	archive := zipBytes(t,
+		testFile{filename: "a.txt", content: strings.Repeat("a", 4096)},
+		testFile{filename: "b.txt", content: "second"},
+	)
+	for _, maxMemory := range []int64{1 << 20, 64} {
+		tempDir := t.TempDir()
+		s, _ := newTestStorage(t, WithFormTempDirectory(tempDir), WithFormMaxMemory(maxMemory))
+		r := multipartRequest(t, []testFile{{field: "archive", filename: "bundle.zip", content: archive}})
+		files, err := s.HTTPFormFiles(r, &MultipartFileName{Single: "archive"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		results, err := s.ExtractZip(&FileStorage{}, files[0])
+		if err != nil {
+			t.Fatalf("max memory %d: %v", maxMemory, err)
+		}
+		if len(results) != 2 || readFile(t, results[0].PathAbs) != strings.Repeat("a", 4096) || readFile(t, results[1].PathAbs) != "second" {
+			t.Errorf("max memory %d: extracted %+v", maxMemory, results)
+		}
+		if err = s.RemoveForm(r.MultipartForm); err != nil {
+			t.Fatal(err)
+		}
+		assertEmptyDir(t, tempDir)
+	}
+}